@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/deis/deis/pkg/prettyprint"
@@ -12,7 +15,12 @@ import (
 	"github.com/deis/deis/client/controller/api"
 	"github.com/deis/deis/client/controller/client"
 	"github.com/deis/deis/client/controller/models/apps"
+	"github.com/deis/deis/client/controller/models/builds"
+	"github.com/deis/deis/client/controller/models/certs"
 	"github.com/deis/deis/client/controller/models/config"
+	"github.com/deis/deis/client/controller/models/domains"
+	"github.com/deis/deis/client/controller/models/ps"
+	"github.com/deis/deis/client/controller/models/releases"
 	"github.com/deis/deis/client/pkg/git"
 	"github.com/deis/deis/client/pkg/webbrowser"
 )
@@ -86,43 +94,266 @@ func AppsList(results int) error {
 	return nil
 }
 
+// appSummary is everything apps:info reports about an app.
+type appSummary struct {
+	App       api.App                   `json:"app"`
+	Release   *api.Release              `json:"release,omitempty"`
+	Build     *api.Build                `json:"build,omitempty"`
+	Processes []api.Process             `json:"processes"`
+	States    map[string]map[string]int `json:"process_states"`
+	Domains   []domainSummary           `json:"domains"`
+	Memory    map[string]interface{}    `json:"memory"`
+	CPU       map[string]interface{}    `json:"cpu"`
+	Tags      map[string]interface{}    `json:"tags"`
+}
+
+// domainSummary is a domain along with the cert that covers it, if any.
+type domainSummary struct {
+	Domain string `json:"domain"`
+	Cert   string `json:"cert,omitempty"`
+}
+
 // AppInfo prints info about app.
-func AppInfo(appID string) error {
+func AppInfo(appID string, jsonOutput bool) error {
 	c, appID, err := load(appID)
 
 	if err != nil {
 		return err
 	}
 
-	app, err := apps.Get(c, appID)
+	summary, err := summarizeApp(c, appID)
 
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("=== %s Application\n", app.ID)
-	fmt.Println("updated: ", app.Updated)
-	fmt.Println("uuid:    ", app.UUID)
-	fmt.Println("created: ", app.Created)
-	fmt.Println("url:     ", app.URL)
-	fmt.Println("owner:   ", app.Owner)
-	fmt.Println("id:      ", app.ID)
+	if jsonOutput {
+		out, err := json.MarshalIndent(summary, "", "  ")
 
-	fmt.Println()
-	// print the app processes
-	if err = PsList(app.ID, defaultLimit); err != nil {
-		return err
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(out))
+		return nil
 	}
 
-	fmt.Println()
-	// print the app domains
-	if err = DomainsList(app.ID, defaultLimit); err != nil {
+	printAppSummary(summary)
+
+	return nil
+}
+
+// summarizeApp fetches all of the resources of an app concurrently, so that the
+// total latency is that of the slowest request rather than the sum of them all.
+func summarizeApp(c *client.Client, appID string) (appSummary, error) {
+	var (
+		summary    appSummary
+		domainList []api.Domain
+		certList   []api.Cert
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		errs       []error
+	)
+
+	fetch := func(f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	fetch(func() (err error) {
+		summary.App, err = apps.Get(c, appID)
+		return err
+	})
+	fetch(func() (err error) {
+		summary.Processes, _, err = ps.List(c, appID, c.ResponseLimit)
+		return err
+	})
+	fetch(func() (err error) {
+		domainList, _, err = domains.List(c, appID, c.ResponseLimit)
 		return err
+	})
+	fetch(func() (err error) {
+		certList, _, err = certs.List(c, c.ResponseLimit)
+		return err
+	})
+	fetch(func() error {
+		// Releases are returned newest first.
+		list, _, err := releases.List(c, appID, 1)
+		if err == nil && len(list) > 0 {
+			summary.Release = &list[0]
+		}
+		return err
+	})
+	fetch(func() error {
+		// Builds are returned newest first.
+		list, _, err := builds.List(c, appID, 1)
+		if err == nil && len(list) > 0 {
+			summary.Build = &list[0]
+		}
+		return err
+	})
+	fetch(func() error {
+		conf, err := config.List(c, appID)
+		summary.Memory, summary.CPU, summary.Tags = conf.Memory, conf.CPU, conf.Tags
+		return err
+	})
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return appSummary{}, errs[0]
+	}
+
+	summary.States = countStates(summary.Processes)
+	summary.Domains = matchCerts(domainList, certList)
+
+	return summary, nil
+}
+
+// countStates tallies the processes of each type by their state.
+func countStates(processes []api.Process) map[string]map[string]int {
+	states := make(map[string]map[string]int)
+
+	for psType, procs := range ps.ByType(processes) {
+		states[psType] = make(map[string]int)
+
+		for _, proc := range procs {
+			states[psType][proc.State]++
+		}
+	}
+
+	return states
+}
+
+// matchCerts pairs each domain with the cert whose common name covers it.
+// Wildcard certs cover exactly one additional subdomain label.
+func matchCerts(domainList []api.Domain, certList []api.Cert) []domainSummary {
+	summaries := make([]domainSummary, 0, len(domainList))
+
+	for _, domain := range domainList {
+		summary := domainSummary{Domain: domain.Domain}
+
+		for _, cert := range certList {
+			if cert.Name == domain.Domain {
+				summary.Cert = cert.Name
+				break
+			}
+
+			if strings.HasPrefix(cert.Name, "*.") {
+				parts := strings.SplitN(domain.Domain, ".", 2)
+				if len(parts) == 2 && parts[1] == cert.Name[2:] {
+					summary.Cert = cert.Name
+				}
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+func printAppSummary(s appSummary) {
+	fmt.Printf("=== %s Application\n", s.App.ID)
+	fmt.Println("updated: ", s.App.Updated)
+	fmt.Println("uuid:    ", s.App.UUID)
+	fmt.Println("created: ", s.App.Created)
+	fmt.Println("url:     ", s.App.URL)
+	fmt.Println("owner:   ", s.App.Owner)
+	fmt.Println("id:      ", s.App.ID)
+
+	if s.Release != nil {
+		fmt.Printf("release:  v%d by %s (%s)\n", s.Release.Version, s.Release.Owner, s.Release.Created)
+	}
+
+	if s.Build != nil {
+		if s.Build.Image != "" {
+			fmt.Println("image:   ", s.Build.Image)
+		}
+		if s.Build.Sha != "" {
+			fmt.Println("sha:     ", s.Build.Sha)
+		}
+	}
+
+	fmt.Printf("\n=== %s Processes\n", s.App.ID)
+
+	psMap := ps.ByType(s.Processes)
+	psTypes := make([]string, 0, len(psMap))
+	for psType := range psMap {
+		psTypes = append(psTypes, psType)
+	}
+	sort.Strings(psTypes)
+
+	for _, psType := range psTypes {
+		fmt.Printf("--- %s: %s\n", psType, formatStates(s.States[psType]))
+
+		for _, proc := range psMap[psType] {
+			fmt.Printf("%s.%d %s (%s)\n", proc.Type, proc.Num, proc.State, proc.Release)
+		}
+	}
+
+	fmt.Printf("\n=== %s Domains\n", s.App.ID)
+
+	for _, domain := range s.Domains {
+		if domain.Cert != "" {
+			fmt.Printf("%s (cert: %s)\n", domain.Domain, domain.Cert)
+		} else {
+			fmt.Println(domain.Domain)
+		}
+	}
+
+	fmt.Printf("\n=== %s Limits\n", s.App.ID)
+	fmt.Println("memory:  ", formatLimits(s.Memory))
+	fmt.Println("cpu:     ", formatLimits(s.CPU))
+
+	if len(s.Tags) > 0 {
+		fmt.Println("tags:    ", formatLimits(s.Tags))
 	}
 
 	fmt.Println()
+}
 
-	return nil
+// formatStates renders state counts as "2 up, 1 crashed", sorted by state.
+func formatStates(states map[string]int) string {
+	keys := make([]string, 0, len(states))
+	for state := range states {
+		keys = append(keys, state)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, state := range keys {
+		parts = append(parts, fmt.Sprintf("%d %s", states[state], state))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// formatLimits renders a limits map as "web=512M worker=1G", sorted by key.
+func formatLimits(limits map[string]interface{}) string {
+	if len(limits) == 0 {
+		return "Unlimited"
+	}
+
+	keys := make([]string, 0, len(limits))
+	for key := range limits {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", key, limits[key]))
+	}
+
+	return strings.Join(parts, " ")
 }
 
 // AppOpen opens an app in the default webbrowser.
@@ -1,6 +1,11 @@
 package cmd
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/deis/deis/client/controller/api"
+)
 
 func TestPrintLogLinesBadLine(t *testing.T) {
 	t.Parallel()
@@ -16,3 +21,66 @@ func TestPrintLogLinesBadLine(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestMatchCerts(t *testing.T) {
+	t.Parallel()
+
+	domainList := []api.Domain{
+		{Domain: "example.com"},
+		{Domain: "www.example.com"},
+		{Domain: "a.b.example.com"},
+		{Domain: "example.org"},
+	}
+	certList := []api.Cert{{Name: "*.example.com"}, {Name: "example.com"}}
+
+	expected := []domainSummary{
+		{Domain: "example.com", Cert: "example.com"},
+		{Domain: "www.example.com", Cert: "*.example.com"},
+		{Domain: "a.b.example.com"},
+		{Domain: "example.org"},
+	}
+
+	if actual := matchCerts(domainList, certList); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+}
+
+func TestCountStates(t *testing.T) {
+	t.Parallel()
+
+	processes := []api.Process{
+		{Type: "web", Num: 1, State: "up"},
+		{Type: "web", Num: 2, State: "up"},
+		{Type: "web", Num: 3, State: "crashed"},
+		{Type: "worker", Num: 1, State: "down"},
+	}
+
+	expected := map[string]map[string]int{
+		"web":    {"up": 2, "crashed": 1},
+		"worker": {"down": 1},
+	}
+
+	states := countStates(processes)
+
+	if !reflect.DeepEqual(expected, states) {
+		t.Errorf("Expected %v, Got %v", expected, states)
+	}
+
+	if actual := formatStates(states["web"]); actual != "1 crashed, 2 up" {
+		t.Errorf("Expected '1 crashed, 2 up', Got '%s'", actual)
+	}
+}
+
+func TestFormatLimits(t *testing.T) {
+	t.Parallel()
+
+	if actual := formatLimits(nil); actual != "Unlimited" {
+		t.Errorf("Expected 'Unlimited', Got '%s'", actual)
+	}
+
+	limits := map[string]interface{}{"worker": "1G", "web": "512M"}
+
+	if actual := formatLimits(limits); actual != "web=512M worker=1G" {
+		t.Errorf("Expected 'web=512M worker=1G', Got '%s'", actual)
+	}
+}
@@ -4,19 +4,32 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"path"
 	"regexp"
 	"sort"
 	"strings"
 
 	"github.com/deis/deis/pkg/prettyprint"
+	"github.com/deis/deis/pkg/sealed"
 
 	"github.com/deis/deis/client/controller/api"
 	"github.com/deis/deis/client/controller/models/config"
 )
 
+// secretKeysVar is the config variable listing the keys set with config:set --secret.
+const secretKeysVar = "DEIS_SECRET_KEYS"
+
+// defaultSecretPatterns match config keys which are masked without being marked secret.
+// More patterns can be added with a comma separated DEIS_SECRET_PATTERNS.
+var defaultSecretPatterns = []string{
+	"*PASSWORD*", "*PASSWD*", "*SECRET*", "*TOKEN*", "*PRIVATE*", "*CREDENTIALS*",
+	"*_KEY", "*DATABASE_URL",
+}
+
 // ConfigList lists an app's config.
-func ConfigList(appID string, oneLine bool) error {
+func ConfigList(appID string, oneLine bool, reveal bool) error {
 	c, appID, err := load(appID)
 
 	if err != nil {
@@ -35,6 +48,10 @@ func ConfigList(appID string, oneLine bool) error {
 	}
 	sort.Strings(keys)
 
+	if !reveal {
+		config.Values = maskSecrets(config.Values, secretPatterns())
+	}
+
 	if oneLine {
 		for _, key := range keys {
 			fmt.Printf("%s=%s ", key, config.Values[key])
@@ -56,8 +73,10 @@ func ConfigList(appID string, oneLine bool) error {
 	return nil
 }
 
-// ConfigSet sets an app's config variables.
-func ConfigSet(appID string, configVars []string) error {
+// ConfigSet sets an app's config variables. Secret variables are recorded so that they
+// are masked by config:list, and if sealKey is a path to a PEM encoded RSA public key,
+// values are sealed with it before leaving the client.
+func ConfigSet(appID string, configVars []string, secret bool, sealKey string) error {
 	c, appID, err := load(appID)
 
 	if err != nil {
@@ -90,6 +109,27 @@ func ConfigSet(appID string, configVars []string) error {
 		configMap["SSH_KEY"] = base64.StdEncoding.EncodeToString([]byte(sshKey))
 	}
 
+	if sealKey != "" {
+		if err = sealValues(configMap, sealKey); err != nil {
+			return err
+		}
+	}
+
+	if secret {
+		current, err := config.List(c, appID)
+
+		if err != nil {
+			return err
+		}
+
+		marked := secretKeys(current.Values)
+		for key := range configMap {
+			marked[key] = true
+		}
+
+		configMap[secretKeysVar] = formatSecretKeys(marked)
+	}
+
 	fmt.Print("Creating config... ")
 
 	quit := progress()
@@ -109,7 +149,7 @@ func ConfigSet(appID string, configVars []string) error {
 		fmt.Print("done\n\n")
 	}
 
-	return ConfigList(appID, false)
+	return ConfigList(appID, false, false)
 }
 
 // ConfigUnset removes a config variable from an app.
@@ -120,6 +160,12 @@ func ConfigUnset(appID string, configVars []string) error {
 		return err
 	}
 
+	current, err := config.List(c, appID)
+
+	if err != nil {
+		return err
+	}
+
 	fmt.Print("Removing config... ")
 
 	quit := progress()
@@ -132,6 +178,19 @@ func ConfigUnset(appID string, configVars []string) error {
 		valuesMap[configVar] = nil
 	}
 
+	// Stop tracking unset keys as secret, so they are not masked if they are set again.
+	if marked := secretKeys(current.Values); len(marked) > 0 {
+		for _, configVar := range configVars {
+			delete(marked, configVar)
+		}
+
+		if len(marked) == 0 {
+			valuesMap[secretKeysVar] = nil
+		} else if _, ok := valuesMap[secretKeysVar]; !ok {
+			valuesMap[secretKeysVar] = formatSecretKeys(marked)
+		}
+	}
+
 	configObj.Values = valuesMap
 
 	_, err = config.Set(c, appID, configObj)
@@ -145,7 +204,7 @@ func ConfigUnset(appID string, configVars []string) error {
 
 	fmt.Print("done\n\n")
 
-	return ConfigList(appID, false)
+	return ConfigList(appID, false, false)
 }
 
 // ConfigPull pulls an app's config to a file.
@@ -210,7 +269,7 @@ func ConfigPush(appID string, fileName string) error {
 	}
 
 	config := strings.Split(string(contents), "\n")
-	return ConfigSet(appID, config[:len(config)-1])
+	return ConfigSet(appID, config[:len(config)-1], false, "")
 }
 
 func parseConfig(configVars []string) map[string]interface{} {
@@ -238,3 +297,117 @@ func formatConfig(configVars map[string]interface{}) string {
 
 	return formattedConfig
 }
+
+// secretPatterns returns the default secret patterns along with any from the
+// DEIS_SECRET_PATTERNS environment variable.
+func secretPatterns() []string {
+	patterns := append([]string{}, defaultSecretPatterns...)
+
+	for _, pattern := range strings.Split(os.Getenv("DEIS_SECRET_PATTERNS"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, strings.ToUpper(pattern))
+		}
+	}
+
+	return patterns
+}
+
+// secretKeys returns the keys marked secret in an app's config.
+func secretKeys(values map[string]interface{}) map[string]bool {
+	marked := make(map[string]bool)
+
+	if list, ok := values[secretKeysVar].(string); ok {
+		for _, key := range strings.Split(list, ",") {
+			if key != "" {
+				marked[key] = true
+			}
+		}
+	}
+
+	return marked
+}
+
+func formatSecretKeys(marked map[string]bool) string {
+	keys := make([]string, 0, len(marked))
+	for key := range marked {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return strings.Join(keys, ",")
+}
+
+// isSecret reports whether a config variable is marked secret, matches a secret
+// pattern, or is a URL carrying a password.
+func isSecret(key string, value string, marked map[string]bool, patterns []string) bool {
+	if key == secretKeysVar {
+		return false
+	}
+
+	if marked[key] {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, strings.ToUpper(key)); matched {
+			return true
+		}
+	}
+
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maskSecrets returns a copy of values with secret values masked.
+func maskSecrets(values map[string]interface{}, patterns []string) map[string]interface{} {
+	marked := secretKeys(values)
+	masked := make(map[string]interface{}, len(values))
+
+	for key, value := range values {
+		str := fmt.Sprintf("%v", value)
+
+		switch {
+		case sealed.IsSealed(str):
+			masked[key] = "<sealed>"
+		case isSecret(key, str, marked, patterns):
+			masked[key] = "********"
+		default:
+			masked[key] = value
+		}
+	}
+
+	return masked
+}
+
+// sealValues seals each value with the RSA public key stored at keyPath. SSH_KEY is left
+// as it is, since the builder reads it to fetch dependencies rather than the app at boot.
+func sealValues(configMap map[string]interface{}, keyPath string) error {
+	contents, err := ioutil.ReadFile(keyPath)
+
+	if err != nil {
+		return err
+	}
+
+	pub, err := sealed.ParsePublicKey(contents)
+
+	if err != nil {
+		return err
+	}
+
+	for key, value := range configMap {
+		if key == "SSH_KEY" {
+			continue
+		}
+
+		if configMap[key], err = sealed.Seal(pub, []byte(fmt.Sprintf("%v", value))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/deis/deis/pkg/sealed"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]interface{}{
+		"DEIS_SECRET_KEYS": "SIGNUP_CODE",
+		"SIGNUP_CODE":      "abc",
+		"MODE":             "test",
+		"API_TOKEN":        "def",
+		"STRIPE_KEY":       "ghi",
+		"REDIS_URL":        "redis://:hunter2@example.com:6379",
+		"HOME_URL":         "http://example.com",
+		"PORT":             5000,
+		"SEALED":           "sealed:v1:abc",
+	}
+
+	expected := map[string]interface{}{
+		"DEIS_SECRET_KEYS": "SIGNUP_CODE",
+		"SIGNUP_CODE":      "********",
+		"MODE":             "test",
+		"API_TOKEN":        "********",
+		"STRIPE_KEY":       "********",
+		"REDIS_URL":        "********",
+		"HOME_URL":         "http://example.com",
+		"PORT":             5000,
+		"SEALED":           "<sealed>",
+	}
+
+	actual := maskSecrets(values, defaultSecretPatterns)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, Got %v", expected, actual)
+	}
+
+	if values["SIGNUP_CODE"] != "abc" {
+		t.Error("Expected the original values to be left unmasked")
+	}
+}
+
+func TestSecretKeys(t *testing.T) {
+	t.Parallel()
+
+	marked := secretKeys(map[string]interface{}{"DEIS_SECRET_KEYS": "FOO,BAR"})
+	marked["BAZ"] = true
+
+	expected := "BAR,BAZ,FOO"
+	if actual := formatSecretKeys(marked); actual != expected {
+		t.Errorf("Expected %s, Got %s", expected, actual)
+	}
+}
+
+func TestSealValuesKeepsSSHKey(t *testing.T) {
+	t.Parallel()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile, err := ioutil.TempFile("", "deis-seal")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(keyFile.Name())
+
+	pem.Encode(keyFile, &pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	keyFile.Close()
+
+	configMap := map[string]interface{}{"DATABASE_URL": "postgres://db", "SSH_KEY": "a2V5"}
+
+	if err = sealValues(configMap, keyFile.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !sealed.IsSealed(configMap["DATABASE_URL"].(string)) {
+		t.Errorf("Expected DATABASE_URL to be sealed, Got %v", configMap["DATABASE_URL"])
+	}
+
+	if configMap["SSH_KEY"] != "a2V5" {
+		t.Errorf("Expected SSH_KEY to be left for the builder, Got %v", configMap["SSH_KEY"])
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/deis/deis/client/controller/api"
+	"github.com/deis/deis/client/controller/models/config"
+)
+
+// The router serves a maintenance page for an app while maintenanceVar is set, or
+// redirects to maintenanceRedirectVar if that is set as well. Both are config
+// variables, so they are published to etcd and survive router restarts.
+const (
+	maintenanceVar         = "DEIS_MAINTENANCE"
+	maintenanceRedirectVar = "DEIS_MAINTENANCE_REDIRECT"
+)
+
+// MaintenanceOn puts an app behind a maintenance page or redirect.
+func MaintenanceOn(appID string, redirect string) error {
+	c, appID, err := load(appID)
+
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{maintenanceVar: "true"}
+
+	if redirect != "" {
+		if err = validateRedirect(redirect); err != nil {
+			return err
+		}
+
+		values[maintenanceRedirectVar] = redirect
+	} else {
+		current, err := config.List(c, appID)
+
+		if err != nil {
+			return err
+		}
+
+		// Only unset the redirect if it exists, the controller rejects unknown keys.
+		if _, ok := current.Values[maintenanceRedirectVar]; ok {
+			values[maintenanceRedirectVar] = nil
+		}
+	}
+
+	fmt.Printf("Enabling maintenance mode for %s... ", appID)
+
+	quit := progress()
+	_, err = config.Set(c, appID, api.Config{Values: values})
+
+	quit <- true
+	<-quit
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("done")
+
+	return nil
+}
+
+// MaintenanceOff takes an app out of maintenance mode.
+func MaintenanceOff(appID string) error {
+	c, appID, err := load(appID)
+
+	if err != nil {
+		return err
+	}
+
+	current, err := config.List(c, appID)
+
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+
+	for _, key := range []string{maintenanceVar, maintenanceRedirectVar} {
+		if _, ok := current.Values[key]; ok {
+			values[key] = nil
+		}
+	}
+
+	if len(values) == 0 {
+		fmt.Printf("Maintenance mode is already off for %s\n", appID)
+		return nil
+	}
+
+	fmt.Printf("Disabling maintenance mode for %s... ", appID)
+
+	quit := progress()
+	_, err = config.Set(c, appID, api.Config{Values: values})
+
+	quit <- true
+	<-quit
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("done")
+
+	return nil
+}
+
+// MaintenanceStatus prints whether an app is in maintenance mode.
+func MaintenanceStatus(appID string) error {
+	c, appID, err := load(appID)
+
+	if err != nil {
+		return err
+	}
+
+	current, err := config.List(c, appID)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("=== %s Maintenance\n", appID)
+
+	if _, ok := current.Values[maintenanceVar]; !ok {
+		fmt.Println("off")
+		return nil
+	}
+
+	if redirect, ok := current.Values[maintenanceRedirectVar]; ok {
+		fmt.Printf("on, redirecting to %v\n", redirect)
+	} else {
+		fmt.Println("on, serving the maintenance page")
+	}
+
+	return nil
+}
+
+// validateRedirect ensures the redirect is an absolute http(s) URL, since it is written
+// verbatim into the router's nginx configuration.
+func validateRedirect(redirect string) error {
+	u, err := url.Parse(redirect)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s is not a valid http or https URL", redirect)
+	}
+
+	for _, char := range redirect {
+		if char == ';' || char == ' ' || char == '{' || char == '}' || char == '"' || char == '\'' {
+			return fmt.Errorf("%s contains characters which are not allowed in a redirect", redirect)
+		}
+	}
+
+	return nil
+}
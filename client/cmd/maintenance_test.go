@@ -0,0 +1,23 @@
+package cmd
+
+import "testing"
+
+func TestValidateRedirect(t *testing.T) {
+	t.Parallel()
+
+	valid := []string{"http://example.com", "https://status.example.com/deis?app=foo"}
+
+	for _, redirect := range valid {
+		if err := validateRedirect(redirect); err != nil {
+			t.Errorf("Expected %s to be valid, Got %v", redirect, err)
+		}
+	}
+
+	invalid := []string{"example.com", "ftp://example.com", "http://", "http://example.com/;deny all"}
+
+	for _, redirect := range invalid {
+		if err := validateRedirect(redirect); err == nil {
+			t.Errorf("Expected %s to be invalid", redirect)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/deis/deis/pkg/whitelist"
+
+	"github.com/deis/deis/client/controller/api"
+	"github.com/deis/deis/client/controller/models/config"
+)
+
+// whitelistVar is the config variable the router reads an app's IP whitelist from.
+const whitelistVar = "DEIS_WHITELIST"
+
+// WhitelistList lists the addresses allowed to connect to an app.
+func WhitelistList(appID string) error {
+	c, appID, err := load(appID)
+
+	if err != nil {
+		return err
+	}
+
+	current, err := config.List(c, appID)
+
+	if err != nil {
+		return err
+	}
+
+	entries, err := currentWhitelist(current.Values)
+
+	if err != nil {
+		return err
+	}
+
+	printWhitelist(appID, entries)
+	return nil
+}
+
+// WhitelistAdd adds addresses to an app's whitelist.
+func WhitelistAdd(appID string, addresses []string) error {
+	add := func(entries []whitelist.Entry, changes ...whitelist.Entry) ([]whitelist.Entry, error) {
+		return whitelist.Add(entries, changes...), nil
+	}
+
+	return updateWhitelist(appID, addresses, "Adding %s to %s whitelist... ", add)
+}
+
+// WhitelistRemove removes addresses from an app's whitelist.
+func WhitelistRemove(appID string, addresses []string) error {
+	return updateWhitelist(appID, addresses, "Removing %s from %s whitelist... ", whitelist.Remove)
+}
+
+// updateWhitelist validates addresses locally before applying the change to the app's
+// current whitelist, so typos never reach the router.
+func updateWhitelist(appID string, addresses []string, message string,
+	apply func([]whitelist.Entry, ...whitelist.Entry) ([]whitelist.Entry, error)) error {
+	changes := make([]whitelist.Entry, len(addresses))
+
+	for i, address := range addresses {
+		entry, err := whitelist.ParseEntry(address)
+
+		if err != nil {
+			return err
+		}
+
+		changes[i] = entry
+	}
+
+	c, appID, err := load(appID)
+
+	if err != nil {
+		return err
+	}
+
+	current, err := config.List(c, appID)
+
+	if err != nil {
+		return err
+	}
+
+	entries, err := currentWhitelist(current.Values)
+
+	if err != nil {
+		return fmt.Errorf("the existing whitelist is invalid: %v", err)
+	}
+
+	if entries, err = apply(entries, changes...); err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{whitelistVar: whitelist.Format(entries)}
+
+	// An empty whitelist must be unset rather than set, or the router would deny all.
+	if len(entries) == 0 {
+		values[whitelistVar] = nil
+	}
+
+	fmt.Printf(message, whitelist.Format(changes), appID)
+
+	quit := progress()
+	_, err = config.Set(c, appID, api.Config{Values: values})
+
+	quit <- true
+	<-quit
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Print("done\n\n")
+
+	printWhitelist(appID, entries)
+	return nil
+}
+
+// currentWhitelist parses the whitelist from an app's config values.
+func currentWhitelist(values map[string]interface{}) ([]whitelist.Entry, error) {
+	value, ok := values[whitelistVar]
+
+	if !ok {
+		return []whitelist.Entry{}, nil
+	}
+
+	return whitelist.Parse(fmt.Sprintf("%v", value))
+}
+
+func printWhitelist(appID string, entries []whitelist.Entry) {
+	fmt.Printf("=== %s Whitelist\n", appID)
+
+	if len(entries) == 0 {
+		fmt.Println("No whitelist, all addresses are allowed")
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.Label != "" {
+			fmt.Printf("%s (%s)\n", entry.Address, entry.Label)
+		} else {
+			fmt.Println(entry.Address)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import "testing"
+
+func TestCurrentWhitelist(t *testing.T) {
+	t.Parallel()
+
+	entries, err := currentWhitelist(map[string]interface{}{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("Expected an empty whitelist, Got %v", entries)
+	}
+
+	entries, err = currentWhitelist(map[string]interface{}{whitelistVar: "10.0.0.1:office,192.168.0.0/16"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].Label != "office" || entries[1].Address != "192.168.0.0/16" {
+		t.Errorf("Expected office and 192.168.0.0/16, Got %v", entries)
+	}
+
+	if _, err = currentWhitelist(map[string]interface{}{whitelistVar: "10.0.0.300"}); err == nil {
+		t.Error("Expected an invalid address to fail")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"net/url"
 	"os"
 	"path"
+
+	"golang.org/x/net/context"
 )
 
 // Client oversees the interaction between the client and controller
@@ -29,6 +31,25 @@ type Client struct {
 
 	// ResponseLimit is the number of results to return on requests that can be limited.
 	ResponseLimit int
+
+	// ctx is attached to every request made by the client, if set.
+	ctx context.Context
+}
+
+// WithContext returns a copy of the client whose requests are bound to ctx, so they
+// are aborted when ctx is cancelled or its deadline passes.
+func (c Client) WithContext(ctx context.Context) *Client {
+	c.ctx = ctx
+	return &c
+}
+
+// Context returns the context requests are bound to, defaulting to context.Background.
+func (c Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+
+	return c.ctx
 }
 
 // DefaultResponseLimit is the default number of responses to return on requests that can
@@ -51,7 +51,7 @@ func (c Client) Request(method string, path string, body []byte) (*http.Response
 
 	addUserAgent(&req.Header)
 
-	res, err := c.HTTPClient.Do(req)
+	res, err := c.do(req)
 
 	if err != nil {
 		return nil, err
@@ -66,6 +66,49 @@ func (c Client) Request(method string, path string, body []byte) (*http.Response
 	return res, nil
 }
 
+// do sends a request, abandoning it if the context of the client is canceled first.
+func (c Client) do(req *http.Request) (*http.Response, error) {
+	ctx := c.Context()
+	if ctx.Done() == nil {
+		return c.HTTPClient.Do(req)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		res *http.Response
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := c.HTTPClient.Do(req)
+		done <- result{res, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.res, r.err
+	case <-ctx.Done():
+		transport := c.HTTPClient.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		if tr, ok := transport.(interface {
+			CancelRequest(*http.Request)
+		}); ok {
+			tr.CancelRequest(req)
+		}
+		// release the connection once the request returns, without waiting for it
+		go func() {
+			if r := <-done; r.res != nil {
+				r.res.Body.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
 // LimitedRequest allows limiting the number of responses in a request.
 func (c Client) LimitedRequest(path string, results int) (string, int, error) {
 	body, err := c.BasicRequest("GET", path+"?page_size="+strconv.Itoa(results), nil)
@@ -7,8 +7,10 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/deis/deis/version"
+	"golang.org/x/net/context"
 )
 
 type fakeHTTPServer struct{}
@@ -266,3 +268,103 @@ func TestLimitedRequest(t *testing.T) {
 		t.Errorf("Expected %s, Got %s", expected, actual)
 	}
 }
+
+func TestRequestContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	handler := fakeHTTPServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient := CreateHTTPClient(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := Client{HTTPClient: httpClient, ControllerURL: *u, Token: "abc"}
+
+	if _, err = client.WithContext(ctx).BasicRequest("POST", "/basic/", []byte("test")); err == nil {
+		t.Error("Expected an error from a cancelled context")
+	}
+
+	// The original client must not be bound to the cancelled context.
+	if _, err = client.BasicRequest("POST", "/basic/", []byte("test")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRequestContextCancelledInFlight(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	u, err := url.Parse(server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := Client{HTTPClient: CreateHTTPClient(false), ControllerURL: *u, Token: "abc"}
+
+	if _, err = client.WithContext(ctx).BasicRequest("GET", "/slow/", nil); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, Got %v", context.DeadlineExceeded, err)
+	}
+}
+
+// blockingTransport holds every request until it is released, and cannot cancel them.
+type blockingTransport struct {
+	release chan struct{}
+}
+
+func (t blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-t.release
+	return nil, fmt.Errorf("released")
+}
+
+func TestRequestContextCancelledWithoutCancelRequest(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	u, err := url.Parse("http://localhost")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	httpClient := &http.Client{Transport: blockingTransport{release}}
+	client := Client{HTTPClient: httpClient, ControllerURL: *u, Token: "abc"}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.WithContext(ctx).BasicRequest("GET", "/slow/", nil)
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected %v, Got %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the request to return once its context was done")
+	}
+}
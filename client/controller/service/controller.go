@@ -0,0 +1,196 @@
+package service
+
+import (
+	"github.com/deis/deis/client/controller/api"
+	"github.com/deis/deis/client/controller/client"
+	"github.com/deis/deis/client/controller/models/apps"
+	"github.com/deis/deis/client/controller/models/auth"
+	"github.com/deis/deis/client/controller/models/builds"
+	"github.com/deis/deis/client/controller/models/certs"
+	"github.com/deis/deis/client/controller/models/config"
+	"github.com/deis/deis/client/controller/models/domains"
+	"github.com/deis/deis/client/controller/models/keys"
+	"github.com/deis/deis/client/controller/models/perms"
+	"github.com/deis/deis/client/controller/models/ps"
+	"github.com/deis/deis/client/controller/models/releases"
+	"github.com/deis/deis/client/controller/models/users"
+	"golang.org/x/net/context"
+)
+
+// The services below bind the client to the context of each call and delegate to the
+// models packages, which remain the single implementation of the controller API.
+
+type appsService struct{ c *client.Client }
+
+func (s appsService) List(ctx context.Context, results int) ([]api.App, int, error) {
+	return apps.List(s.c.WithContext(ctx), results)
+}
+
+func (s appsService) New(ctx context.Context, id string) (api.App, error) {
+	return apps.New(s.c.WithContext(ctx), id)
+}
+
+func (s appsService) Get(ctx context.Context, appID string) (api.App, error) {
+	return apps.Get(s.c.WithContext(ctx), appID)
+}
+
+func (s appsService) Logs(ctx context.Context, appID string, lines int) (string, error) {
+	return apps.Logs(s.c.WithContext(ctx), appID, lines)
+}
+
+func (s appsService) Run(ctx context.Context, appID string, command string) (api.AppRunResponse, error) {
+	return apps.Run(s.c.WithContext(ctx), appID, command)
+}
+
+func (s appsService) Delete(ctx context.Context, appID string) error {
+	return apps.Delete(s.c.WithContext(ctx), appID)
+}
+
+func (s appsService) Transfer(ctx context.Context, appID string, username string) error {
+	return apps.Transfer(s.c.WithContext(ctx), appID, username)
+}
+
+type authService struct{ c *client.Client }
+
+func (s authService) Register(ctx context.Context, username string, password string, email string) error {
+	return auth.Register(s.c.WithContext(ctx), username, password, email)
+}
+
+func (s authService) Login(ctx context.Context, username string, password string) (string, error) {
+	return auth.Login(s.c.WithContext(ctx), username, password)
+}
+
+func (s authService) Delete(ctx context.Context, username string) error {
+	return auth.Delete(s.c.WithContext(ctx), username)
+}
+
+func (s authService) Regenerate(ctx context.Context, username string, all bool) (string, error) {
+	return auth.Regenerate(s.c.WithContext(ctx), username, all)
+}
+
+func (s authService) Passwd(ctx context.Context, username string, password string, newPassword string) error {
+	return auth.Passwd(s.c.WithContext(ctx), username, password, newPassword)
+}
+
+type buildsService struct{ c *client.Client }
+
+func (s buildsService) List(ctx context.Context, appID string, results int) ([]api.Build, int, error) {
+	return builds.List(s.c.WithContext(ctx), appID, results)
+}
+
+func (s buildsService) New(ctx context.Context, appID string, image string,
+	procfile map[string]string) (api.Build, error) {
+	return builds.New(s.c.WithContext(ctx), appID, image, procfile)
+}
+
+type certsService struct{ c *client.Client }
+
+func (s certsService) List(ctx context.Context, results int) ([]api.Cert, int, error) {
+	return certs.List(s.c.WithContext(ctx), results)
+}
+
+func (s certsService) New(ctx context.Context, cert string, key string, commonName string) (api.Cert, error) {
+	return certs.New(s.c.WithContext(ctx), cert, key, commonName)
+}
+
+func (s certsService) Delete(ctx context.Context, commonName string) error {
+	return certs.Delete(s.c.WithContext(ctx), commonName)
+}
+
+type configService struct{ c *client.Client }
+
+func (s configService) List(ctx context.Context, appID string) (api.Config, error) {
+	return config.List(s.c.WithContext(ctx), appID)
+}
+
+func (s configService) Set(ctx context.Context, appID string, conf api.Config) (api.Config, error) {
+	return config.Set(s.c.WithContext(ctx), appID, conf)
+}
+
+type domainsService struct{ c *client.Client }
+
+func (s domainsService) List(ctx context.Context, appID string, results int) ([]api.Domain, int, error) {
+	return domains.List(s.c.WithContext(ctx), appID, results)
+}
+
+func (s domainsService) New(ctx context.Context, appID string, domain string) (api.Domain, error) {
+	return domains.New(s.c.WithContext(ctx), appID, domain)
+}
+
+func (s domainsService) Delete(ctx context.Context, appID string, domain string) error {
+	return domains.Delete(s.c.WithContext(ctx), appID, domain)
+}
+
+type keysService struct{ c *client.Client }
+
+func (s keysService) List(ctx context.Context, results int) ([]api.Key, int, error) {
+	return keys.List(s.c.WithContext(ctx), results)
+}
+
+func (s keysService) New(ctx context.Context, id string, pubKey string) (api.Key, error) {
+	return keys.New(s.c.WithContext(ctx), id, pubKey)
+}
+
+func (s keysService) Delete(ctx context.Context, keyID string) error {
+	return keys.Delete(s.c.WithContext(ctx), keyID)
+}
+
+type permsService struct{ c *client.Client }
+
+func (s permsService) List(ctx context.Context, appID string) ([]string, error) {
+	return perms.List(s.c.WithContext(ctx), appID)
+}
+
+func (s permsService) ListAdmins(ctx context.Context, results int) ([]string, int, error) {
+	return perms.ListAdmins(s.c.WithContext(ctx), results)
+}
+
+func (s permsService) New(ctx context.Context, appID string, username string) error {
+	return perms.New(s.c.WithContext(ctx), appID, username)
+}
+
+func (s permsService) NewAdmin(ctx context.Context, username string) error {
+	return perms.NewAdmin(s.c.WithContext(ctx), username)
+}
+
+func (s permsService) Delete(ctx context.Context, appID string, username string) error {
+	return perms.Delete(s.c.WithContext(ctx), appID, username)
+}
+
+func (s permsService) DeleteAdmin(ctx context.Context, username string) error {
+	return perms.DeleteAdmin(s.c.WithContext(ctx), username)
+}
+
+type psService struct{ c *client.Client }
+
+func (s psService) List(ctx context.Context, appID string, results int) ([]api.Process, int, error) {
+	return ps.List(s.c.WithContext(ctx), appID, results)
+}
+
+func (s psService) Scale(ctx context.Context, appID string, targets map[string]int) error {
+	return ps.Scale(s.c.WithContext(ctx), appID, targets)
+}
+
+func (s psService) Restart(ctx context.Context, appID string, procType string, num int) ([]api.Process, error) {
+	return ps.Restart(s.c.WithContext(ctx), appID, procType, num)
+}
+
+type releasesService struct{ c *client.Client }
+
+func (s releasesService) List(ctx context.Context, appID string, results int) ([]api.Release, int, error) {
+	return releases.List(s.c.WithContext(ctx), appID, results)
+}
+
+func (s releasesService) Get(ctx context.Context, appID string, version int) (api.Release, error) {
+	return releases.Get(s.c.WithContext(ctx), appID, version)
+}
+
+func (s releasesService) Rollback(ctx context.Context, appID string, version int) (int, error) {
+	return releases.Rollback(s.c.WithContext(ctx), appID, version)
+}
+
+type usersService struct{ c *client.Client }
+
+func (s usersService) List(ctx context.Context, results int) ([]api.User, int, error) {
+	return users.List(s.c.WithContext(ctx), results)
+}
@@ -0,0 +1,997 @@
+// Package fake provides an in-memory implementation of the controller services for
+// testing programs which consume the client library.
+package fake
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/deis/client/controller/api"
+	"github.com/deis/deis/client/controller/service"
+	"golang.org/x/net/context"
+)
+
+// ErrNotFound is returned when a requested resource does not exist.
+var ErrNotFound = errors.New("Not found.")
+
+// ErrExists is returned when creating a resource which already exists.
+var ErrExists = errors.New("Already exists.")
+
+// ErrInvalidCredentials is returned when a username and password do not match.
+var ErrInvalidCredentials = errors.New("Unable to log in with provided credentials.")
+
+// Controller is an in-memory stand-in for a Deis controller. The zero value is not
+// usable, create one with New.
+type Controller struct {
+	// Owner is recorded as the owner of every object created.
+	Owner string
+
+	// RunOutput is returned by every call to Apps.Run.
+	RunOutput api.AppRunResponse
+
+	mu      sync.Mutex
+	apps    map[string]*app
+	certs   []api.Cert
+	keys    []api.Key
+	admins  []string
+	users   map[string]*user
+	counter int
+}
+
+// user holds a registered account with its password and current token.
+type user struct {
+	user     api.User
+	password string
+	token    string
+}
+
+// app holds the state of a single application. Builds and releases are kept newest
+// first, matching the ordering of the controller.
+type app struct {
+	app      api.App
+	builds   []api.Build
+	config   api.Config
+	domains  []api.Domain
+	perms    []string
+	procs    []api.Process
+	releases []api.Release
+	logs     string
+}
+
+// New creates an empty controller whose objects are owned by owner.
+func New(owner string) *Controller {
+	return &Controller{Owner: owner, apps: make(map[string]*app), users: make(map[string]*user)}
+}
+
+// Services returns the services backed by the fake controller.
+func (f *Controller) Services() service.Services {
+	return service.Services{
+		Apps:     appsService{f},
+		Auth:     authService{f},
+		Builds:   buildsService{f},
+		Certs:    certsService{f},
+		Config:   configService{f},
+		Domains:  domainsService{f},
+		Keys:     keysService{f},
+		Perms:    permsService{f},
+		Ps:       psService{f},
+		Releases: releasesService{f},
+		Users:    usersService{f},
+	}
+}
+
+// SetLogs sets the logs returned for an app.
+func (f *Controller) SetLogs(appID string, logs string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.get(appID)
+
+	if err != nil {
+		return err
+	}
+
+	a.logs = logs
+	return nil
+}
+
+// lock acquires the controller's lock unless the context is already done.
+func (f *Controller) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	return nil
+}
+
+func (f *Controller) get(appID string) (*app, error) {
+	a, ok := f.apps[appID]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return a, nil
+}
+
+// account returns the user named username, or the owner when username is empty.
+func (f *Controller) account(username string) (*user, error) {
+	if username == "" {
+		username = f.Owner
+	}
+
+	u, ok := f.users[username]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return u, nil
+}
+
+func (f *Controller) now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func (f *Controller) uuid() string {
+	f.counter++
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", f.counter)
+}
+
+// release records a new release of an app, mirroring the controller's summaries.
+func (f *Controller) release(a *app, summary string) api.Release {
+	version := 1
+	if len(a.releases) > 0 {
+		version = a.releases[0].Version + 1
+	}
+
+	r := api.Release{
+		App:     a.app.ID,
+		Config:  a.config.UUID,
+		Created: f.now(),
+		Owner:   f.Owner,
+		Summary: summary,
+		Updated: f.now(),
+		UUID:    f.uuid(),
+		Version: version,
+	}
+
+	if len(a.builds) > 0 {
+		r.Build = a.builds[0].UUID
+	}
+
+	a.releases = append([]api.Release{r}, a.releases...)
+
+	for i := range a.procs {
+		a.procs[i].Release = fmt.Sprintf("v%d", version)
+	}
+
+	return r
+}
+
+// limit truncates a list of length n to the number of results requested.
+func limit(n int, results int) int {
+	if results >= 0 && results < n {
+		return results
+	}
+
+	return n
+}
+
+type appsService struct{ f *Controller }
+
+func (s appsService) List(ctx context.Context, results int) ([]api.App, int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []api.App{}, -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	ids := make([]string, 0, len(s.f.apps))
+	for id := range s.f.apps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	list := []api.App{}
+	for _, id := range ids[:limit(len(ids), results)] {
+		list = append(list, s.f.apps[id].app)
+	}
+
+	return list, len(ids), nil
+}
+
+func (s appsService) New(ctx context.Context, id string) (api.App, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.App{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	if id == "" {
+		id = fmt.Sprintf("app-%d", s.f.counter+1)
+	}
+
+	if _, ok := s.f.apps[id]; ok {
+		return api.App{}, ErrExists
+	}
+
+	a := &app{
+		app: api.App{
+			Created: s.f.now(),
+			ID:      id,
+			Owner:   s.f.Owner,
+			Updated: s.f.now(),
+			URL:     id + ".example.com",
+			UUID:    s.f.uuid(),
+		},
+	}
+	a.config = api.Config{Owner: s.f.Owner, App: id, UUID: s.f.uuid()}
+	s.f.release(a, s.f.Owner+" created initial release")
+	s.f.apps[id] = a
+
+	return a.app, nil
+}
+
+func (s appsService) Get(ctx context.Context, appID string) (api.App, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.App{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return api.App{}, err
+	}
+
+	return a.app, nil
+}
+
+func (s appsService) Logs(ctx context.Context, appID string, lines int) (string, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return "", err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return "", err
+	}
+
+	if lines <= 0 {
+		return a.logs, nil
+	}
+
+	logLines := strings.Split(a.logs, "\n")
+	if len(logLines) > lines {
+		logLines = logLines[len(logLines)-lines:]
+	}
+
+	return strings.Join(logLines, "\n"), nil
+}
+
+func (s appsService) Run(ctx context.Context, appID string, command string) (api.AppRunResponse, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.AppRunResponse{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	if _, err := s.f.get(appID); err != nil {
+		return api.AppRunResponse{}, err
+	}
+
+	return s.f.RunOutput, nil
+}
+
+func (s appsService) Delete(ctx context.Context, appID string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	if _, err := s.f.get(appID); err != nil {
+		return err
+	}
+
+	delete(s.f.apps, appID)
+	return nil
+}
+
+func (s appsService) Transfer(ctx context.Context, appID string, username string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return err
+	}
+
+	a.app.Owner = username
+	return nil
+}
+
+type authService struct{ f *Controller }
+
+func (s authService) Register(ctx context.Context, username string, password string, email string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	if _, ok := s.f.users[username]; ok {
+		return ErrExists
+	}
+
+	s.f.counter++
+	s.f.users[username] = &user{
+		user: api.User{
+			ID:         s.f.counter,
+			Username:   username,
+			Email:      email,
+			IsActive:   true,
+			DateJoined: s.f.now(),
+		},
+		password: password,
+		token:    s.f.uuid(),
+	}
+
+	return nil
+}
+
+func (s authService) Login(ctx context.Context, username string, password string) (string, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return "", err
+	}
+	defer s.f.mu.Unlock()
+
+	u, ok := s.f.users[username]
+
+	if !ok || u.password != password {
+		return "", ErrInvalidCredentials
+	}
+
+	u.user.LastLogin = s.f.now()
+	return u.token, nil
+}
+
+func (s authService) Delete(ctx context.Context, username string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	u, err := s.f.account(username)
+
+	if err != nil {
+		return err
+	}
+
+	delete(s.f.users, u.user.Username)
+	return nil
+}
+
+func (s authService) Regenerate(ctx context.Context, username string, all bool) (string, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return "", err
+	}
+	defer s.f.mu.Unlock()
+
+	if all {
+		for _, u := range s.f.users {
+			u.token = s.f.uuid()
+		}
+
+		return "", nil
+	}
+
+	u, err := s.f.account(username)
+
+	if err != nil {
+		return "", err
+	}
+
+	u.token = s.f.uuid()
+	return u.token, nil
+}
+
+func (s authService) Passwd(ctx context.Context, username string, password string, newPassword string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	u, err := s.f.account(username)
+
+	if err != nil {
+		return err
+	}
+
+	// like the controller, only a user changing their own password must give the old one
+	if username == "" && u.password != password {
+		return ErrInvalidCredentials
+	}
+
+	u.password = newPassword
+	return nil
+}
+
+type buildsService struct{ f *Controller }
+
+func (s buildsService) List(ctx context.Context, appID string, results int) ([]api.Build, int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []api.Build{}, -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return []api.Build{}, -1, err
+	}
+
+	return append([]api.Build{}, a.builds[:limit(len(a.builds), results)]...), len(a.builds), nil
+}
+
+func (s buildsService) New(ctx context.Context, appID string, image string,
+	procfile map[string]string) (api.Build, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.Build{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return api.Build{}, err
+	}
+
+	b := api.Build{
+		App:      appID,
+		Created:  s.f.now(),
+		Image:    image,
+		Owner:    s.f.Owner,
+		Procfile: procfile,
+		Updated:  s.f.now(),
+		UUID:     s.f.uuid(),
+	}
+
+	a.builds = append([]api.Build{b}, a.builds...)
+	s.f.release(a, fmt.Sprintf("%s deployed %s", s.f.Owner, image))
+
+	return b, nil
+}
+
+type certsService struct{ f *Controller }
+
+func (s certsService) List(ctx context.Context, results int) ([]api.Cert, int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []api.Cert{}, -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	return append([]api.Cert{}, s.f.certs[:limit(len(s.f.certs), results)]...), len(s.f.certs), nil
+}
+
+func (s certsService) New(ctx context.Context, cert string, key string, commonName string) (api.Cert, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.Cert{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	for _, c := range s.f.certs {
+		if c.Name == commonName {
+			return api.Cert{}, ErrExists
+		}
+	}
+
+	c := api.Cert{
+		Created: s.f.now(),
+		Updated: s.f.now(),
+		Name:    commonName,
+		Owner:   s.f.Owner,
+		ID:      len(s.f.certs) + 1,
+	}
+
+	s.f.certs = append(s.f.certs, c)
+	return c, nil
+}
+
+func (s certsService) Delete(ctx context.Context, commonName string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	for i, c := range s.f.certs {
+		if c.Name == commonName {
+			s.f.certs = append(s.f.certs[:i], s.f.certs[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+type configService struct{ f *Controller }
+
+func (s configService) List(ctx context.Context, appID string) (api.Config, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.Config{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return api.Config{}, err
+	}
+
+	return copyConfig(a.config), nil
+}
+
+// Set merges the values, limits and tags into the app's config. As with the
+// controller, a nil value unsets the key. A new release is created.
+func (s configService) Set(ctx context.Context, appID string, conf api.Config) (api.Config, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.Config{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return api.Config{}, err
+	}
+
+	c := copyConfig(a.config)
+	c.Values = merge(c.Values, conf.Values)
+	c.Memory = merge(c.Memory, conf.Memory)
+	c.CPU = merge(c.CPU, conf.CPU)
+	c.Tags = merge(c.Tags, conf.Tags)
+	c.UUID = s.f.uuid()
+	c.Updated = s.f.now()
+
+	a.config = c
+	s.f.release(a, s.f.Owner+" changed config")
+
+	return copyConfig(c), nil
+}
+
+func merge(dst map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	for key, value := range src {
+		if dst == nil {
+			dst = make(map[string]interface{})
+		}
+
+		if value == nil {
+			delete(dst, key)
+		} else {
+			dst[key] = value
+		}
+	}
+
+	return dst
+}
+
+func copyConfig(c api.Config) api.Config {
+	c.Values = merge(nil, c.Values)
+	c.Memory = merge(nil, c.Memory)
+	c.CPU = merge(nil, c.CPU)
+	c.Tags = merge(nil, c.Tags)
+	return c
+}
+
+type domainsService struct{ f *Controller }
+
+func (s domainsService) List(ctx context.Context, appID string, results int) ([]api.Domain, int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []api.Domain{}, -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return []api.Domain{}, -1, err
+	}
+
+	return append([]api.Domain{}, a.domains[:limit(len(a.domains), results)]...), len(a.domains), nil
+}
+
+func (s domainsService) New(ctx context.Context, appID string, domain string) (api.Domain, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.Domain{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return api.Domain{}, err
+	}
+
+	for _, d := range a.domains {
+		if d.Domain == domain {
+			return api.Domain{}, ErrExists
+		}
+	}
+
+	d := api.Domain{
+		App:     appID,
+		Created: s.f.now(),
+		Domain:  domain,
+		Owner:   s.f.Owner,
+		Updated: s.f.now(),
+	}
+
+	a.domains = append(a.domains, d)
+	return d, nil
+}
+
+func (s domainsService) Delete(ctx context.Context, appID string, domain string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return err
+	}
+
+	for i, d := range a.domains {
+		if d.Domain == domain {
+			a.domains = append(a.domains[:i], a.domains[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+type keysService struct{ f *Controller }
+
+func (s keysService) List(ctx context.Context, results int) ([]api.Key, int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []api.Key{}, -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	return append([]api.Key{}, s.f.keys[:limit(len(s.f.keys), results)]...), len(s.f.keys), nil
+}
+
+func (s keysService) New(ctx context.Context, id string, pubKey string) (api.Key, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.Key{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	for _, k := range s.f.keys {
+		if k.ID == id {
+			return api.Key{}, ErrExists
+		}
+	}
+
+	k := api.Key{
+		Created: s.f.now(),
+		ID:      id,
+		Owner:   s.f.Owner,
+		Public:  pubKey,
+		Updated: s.f.now(),
+		UUID:    s.f.uuid(),
+	}
+
+	s.f.keys = append(s.f.keys, k)
+	return k, nil
+}
+
+func (s keysService) Delete(ctx context.Context, keyID string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	for i, k := range s.f.keys {
+		if k.ID == keyID {
+			s.f.keys = append(s.f.keys[:i], s.f.keys[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+type permsService struct{ f *Controller }
+
+func (s permsService) List(ctx context.Context, appID string) ([]string, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []string{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	return append([]string{}, a.perms...), nil
+}
+
+func (s permsService) ListAdmins(ctx context.Context, results int) ([]string, int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []string{}, -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	return append([]string{}, s.f.admins[:limit(len(s.f.admins), results)]...), len(s.f.admins), nil
+}
+
+func (s permsService) New(ctx context.Context, appID string, username string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return err
+	}
+
+	a.perms, err = addUser(a.perms, username)
+	return err
+}
+
+func (s permsService) NewAdmin(ctx context.Context, username string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	var err error
+	s.f.admins, err = addUser(s.f.admins, username)
+	return err
+}
+
+func (s permsService) Delete(ctx context.Context, appID string, username string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return err
+	}
+
+	a.perms, err = removeUser(a.perms, username)
+	return err
+}
+
+func (s permsService) DeleteAdmin(ctx context.Context, username string) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	var err error
+	s.f.admins, err = removeUser(s.f.admins, username)
+	return err
+}
+
+func addUser(users []string, username string) ([]string, error) {
+	for _, user := range users {
+		if user == username {
+			return users, ErrExists
+		}
+	}
+
+	return append(users, username), nil
+}
+
+func removeUser(users []string, username string) ([]string, error) {
+	for i, user := range users {
+		if user == username {
+			return append(users[:i], users[i+1:]...), nil
+		}
+	}
+
+	return users, ErrNotFound
+}
+
+type psService struct{ f *Controller }
+
+func (s psService) List(ctx context.Context, appID string, results int) ([]api.Process, int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []api.Process{}, -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return []api.Process{}, -1, err
+	}
+
+	return append([]api.Process{}, a.procs[:limit(len(a.procs), results)]...), len(a.procs), nil
+}
+
+// Scale adds or removes processes so that each type has the requested number
+// running. Process types need not be declared in a Procfile.
+func (s psService) Scale(ctx context.Context, appID string, targets map[string]int) error {
+	if err := s.f.lock(ctx); err != nil {
+		return err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return err
+	}
+
+	release := ""
+	if len(a.releases) > 0 {
+		release = fmt.Sprintf("v%d", a.releases[0].Version)
+	}
+
+	procs := []api.Process{}
+
+	for _, proc := range a.procs {
+		if _, ok := targets[proc.Type]; !ok {
+			procs = append(procs, proc)
+		}
+	}
+
+	types := make([]string, 0, len(targets))
+	for psType := range targets {
+		types = append(types, psType)
+	}
+	sort.Strings(types)
+
+	for _, psType := range types {
+		for num := 1; num <= targets[psType]; num++ {
+			procs = append(procs, api.Process{
+				Owner:   s.f.Owner,
+				App:     appID,
+				Release: release,
+				Created: s.f.now(),
+				Updated: s.f.now(),
+				UUID:    s.f.uuid(),
+				Type:    psType,
+				Num:     num,
+				State:   "up",
+			})
+		}
+	}
+
+	a.procs = procs
+	return nil
+}
+
+func (s psService) Restart(ctx context.Context, appID string, procType string, num int) ([]api.Process, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []api.Process{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return []api.Process{}, err
+	}
+
+	restarted := []api.Process{}
+
+	for i, proc := range a.procs {
+		if (procType == "" || proc.Type == procType) && (num == -1 || proc.Num == num) {
+			a.procs[i].State = "up"
+			a.procs[i].Updated = s.f.now()
+			restarted = append(restarted, a.procs[i])
+		}
+	}
+
+	return restarted, nil
+}
+
+type releasesService struct{ f *Controller }
+
+func (s releasesService) List(ctx context.Context, appID string, results int) ([]api.Release, int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []api.Release{}, -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return []api.Release{}, -1, err
+	}
+
+	return append([]api.Release{}, a.releases[:limit(len(a.releases), results)]...), len(a.releases), nil
+}
+
+func (s releasesService) Get(ctx context.Context, appID string, version int) (api.Release, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return api.Release{}, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return api.Release{}, err
+	}
+
+	for _, r := range a.releases {
+		if r.Version == version {
+			return r, nil
+		}
+	}
+
+	return api.Release{}, ErrNotFound
+}
+
+// Rollback creates a new release from a previous one. A version of -1 rolls back to
+// the release before the current one, as with the controller.
+func (s releasesService) Rollback(ctx context.Context, appID string, version int) (int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	a, err := s.f.get(appID)
+
+	if err != nil {
+		return -1, err
+	}
+
+	if version == -1 {
+		if len(a.releases) < 2 {
+			return -1, ErrNotFound
+		}
+		version = a.releases[1].Version
+	}
+
+	for _, r := range a.releases {
+		if r.Version == version {
+			newRelease := s.f.release(a, fmt.Sprintf("%s rolled back to v%d", s.f.Owner, version))
+			return newRelease.Version, nil
+		}
+	}
+
+	return -1, ErrNotFound
+}
+
+type usersService struct{ f *Controller }
+
+func (s usersService) List(ctx context.Context, results int) ([]api.User, int, error) {
+	if err := s.f.lock(ctx); err != nil {
+		return []api.User{}, -1, err
+	}
+	defer s.f.mu.Unlock()
+
+	names := make([]string, 0, len(s.f.users))
+	for name := range s.f.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := []api.User{}
+	for _, name := range names[:limit(len(names), results)] {
+		list = append(list, s.f.users[name].user)
+	}
+
+	return list, len(names), nil
+}
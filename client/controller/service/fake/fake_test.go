@@ -0,0 +1,174 @@
+package fake
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/deis/deis/client/controller/api"
+	"golang.org/x/net/context"
+)
+
+func TestAppLifecycle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	services := New("test").Services()
+
+	if _, err := services.Apps.New(ctx, "example-go"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := services.Apps.New(ctx, "example-go"); err != ErrExists {
+		t.Errorf("Expected %v, Got %v", ErrExists, err)
+	}
+
+	if _, err := services.Builds.New(ctx, "example-go", "deis/example-go:latest", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := api.Config{Values: map[string]interface{}{"FOO": "bar", "BAZ": "qux"}}
+	if _, err := services.Config.Set(ctx, "example-go", conf); err != nil {
+		t.Fatal(err)
+	}
+
+	conf = api.Config{Values: map[string]interface{}{"BAZ": nil}}
+	actual, err := services.Config.Set(ctx, "example-go", conf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{"FOO": "bar"}
+	if !reflect.DeepEqual(expected, actual.Values) {
+		t.Errorf("Expected %v, Got %v", expected, actual.Values)
+	}
+
+	releases, count, err := services.Releases.List(ctx, "example-go", 1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 4 || len(releases) != 1 || releases[0].Version != 4 {
+		t.Errorf("Expected latest release v4 of 4, Got %v of %d", releases, count)
+	}
+
+	version, err := services.Releases.Rollback(ctx, "example-go", -1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != 5 {
+		t.Errorf("Expected 5, Got %d", version)
+	}
+
+	if err = services.Apps.Delete(ctx, "example-go"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = services.Apps.Get(ctx, "example-go"); err != ErrNotFound {
+		t.Errorf("Expected %v, Got %v", ErrNotFound, err)
+	}
+}
+
+func TestScale(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	services := New("test").Services()
+
+	if _, err := services.Apps.New(ctx, "example-go"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := services.Ps.Scale(ctx, "example-go", map[string]int{"web": 3, "worker": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := services.Ps.Scale(ctx, "example-go", map[string]int{"web": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	procs, count, err := services.Ps.List(ctx, "example-go", 100)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 processes, Got %d: %v", count, procs)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	services := New("test").Services()
+
+	if err := services.Auth.Register(ctx, "test", "opensesame", "test@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := services.Auth.Register(ctx, "test", "opensesame", "test@example.com"); err != ErrExists {
+		t.Errorf("Expected %v, Got %v", ErrExists, err)
+	}
+
+	token, err := services.Auth.Login(ctx, "test", "opensesame")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	regenerated, err := services.Auth.Regenerate(ctx, "", false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if regenerated == token {
+		t.Errorf("Expected a new token, Got %s", regenerated)
+	}
+
+	if err = services.Auth.Passwd(ctx, "", "wrong", "letmein"); err != ErrInvalidCredentials {
+		t.Errorf("Expected %v, Got %v", ErrInvalidCredentials, err)
+	}
+
+	if err = services.Auth.Passwd(ctx, "", "opensesame", "letmein"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = services.Auth.Login(ctx, "test", "opensesame"); err != ErrInvalidCredentials {
+		t.Errorf("Expected %v, Got %v", ErrInvalidCredentials, err)
+	}
+
+	users, count, err := services.Users.List(ctx, 100)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 || users[0].Username != "test" {
+		t.Errorf("Expected test, Got %v", users)
+	}
+
+	if err = services.Auth.Delete(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, count, _ = services.Users.List(ctx, 100); count != 0 {
+		t.Errorf("Expected no users, Got %d", count)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := New("test").Services().Apps.List(ctx, 100); err != context.Canceled {
+		t.Errorf("Expected %v, Got %v", context.Canceled, err)
+	}
+}
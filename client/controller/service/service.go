@@ -0,0 +1,126 @@
+// Package service exposes the Deis controller API as a set of interfaces, one per
+// resource, so that programs embedding the client can swap in a fake for testing.
+//
+// Every method takes a context.Context which is propagated to the underlying HTTP
+// request, allowing callers to cancel requests or bound them with a deadline.
+package service
+
+import (
+	"github.com/deis/deis/client/controller/api"
+	"github.com/deis/deis/client/controller/client"
+	"golang.org/x/net/context"
+)
+
+// AppsService manages applications.
+type AppsService interface {
+	List(ctx context.Context, results int) ([]api.App, int, error)
+	New(ctx context.Context, id string) (api.App, error)
+	Get(ctx context.Context, appID string) (api.App, error)
+	Logs(ctx context.Context, appID string, lines int) (string, error)
+	Run(ctx context.Context, appID string, command string) (api.AppRunResponse, error)
+	Delete(ctx context.Context, appID string) error
+	Transfer(ctx context.Context, appID string, username string) error
+}
+
+// AuthService manages user accounts and their tokens.
+type AuthService interface {
+	Register(ctx context.Context, username string, password string, email string) error
+	Login(ctx context.Context, username string, password string) (string, error)
+	Delete(ctx context.Context, username string) error
+	Regenerate(ctx context.Context, username string, all bool) (string, error)
+	Passwd(ctx context.Context, username string, password string, newPassword string) error
+}
+
+// BuildsService manages an app's builds.
+type BuildsService interface {
+	List(ctx context.Context, appID string, results int) ([]api.Build, int, error)
+	New(ctx context.Context, appID string, image string, procfile map[string]string) (api.Build, error)
+}
+
+// CertsService manages the certs registered with the controller.
+type CertsService interface {
+	List(ctx context.Context, results int) ([]api.Cert, int, error)
+	New(ctx context.Context, cert string, key string, commonName string) (api.Cert, error)
+	Delete(ctx context.Context, commonName string) error
+}
+
+// ConfigService manages an app's config, limits and tags.
+type ConfigService interface {
+	List(ctx context.Context, appID string) (api.Config, error)
+	Set(ctx context.Context, appID string, config api.Config) (api.Config, error)
+}
+
+// DomainsService manages the domains registered with an app.
+type DomainsService interface {
+	List(ctx context.Context, appID string, results int) ([]api.Domain, int, error)
+	New(ctx context.Context, appID string, domain string) (api.Domain, error)
+	Delete(ctx context.Context, appID string, domain string) error
+}
+
+// KeysService manages the current user's SSH keys.
+type KeysService interface {
+	List(ctx context.Context, results int) ([]api.Key, int, error)
+	New(ctx context.Context, id string, pubKey string) (api.Key, error)
+	Delete(ctx context.Context, keyID string) error
+}
+
+// PermsService manages app collaborators and administrators.
+type PermsService interface {
+	List(ctx context.Context, appID string) ([]string, error)
+	ListAdmins(ctx context.Context, results int) ([]string, int, error)
+	New(ctx context.Context, appID string, username string) error
+	NewAdmin(ctx context.Context, username string) error
+	Delete(ctx context.Context, appID string, username string) error
+	DeleteAdmin(ctx context.Context, username string) error
+}
+
+// PsService manages an app's processes.
+type PsService interface {
+	List(ctx context.Context, appID string, results int) ([]api.Process, int, error)
+	Scale(ctx context.Context, appID string, targets map[string]int) error
+	Restart(ctx context.Context, appID string, procType string, num int) ([]api.Process, error)
+}
+
+// ReleasesService manages an app's releases.
+type ReleasesService interface {
+	List(ctx context.Context, appID string, results int) ([]api.Release, int, error)
+	Get(ctx context.Context, appID string, version int) (api.Release, error)
+	Rollback(ctx context.Context, appID string, version int) (int, error)
+}
+
+// UsersService lists the users registered with the controller.
+type UsersService interface {
+	List(ctx context.Context, results int) ([]api.User, int, error)
+}
+
+// Services groups the services for every resource of the controller API.
+type Services struct {
+	Apps     AppsService
+	Auth     AuthService
+	Builds   BuildsService
+	Certs    CertsService
+	Config   ConfigService
+	Domains  DomainsService
+	Keys     KeysService
+	Perms    PermsService
+	Ps       PsService
+	Releases ReleasesService
+	Users    UsersService
+}
+
+// New creates services which talk to the controller c is configured for.
+func New(c *client.Client) Services {
+	return Services{
+		Apps:     appsService{c},
+		Auth:     authService{c},
+		Builds:   buildsService{c},
+		Certs:    certsService{c},
+		Config:   configService{c},
+		Domains:  domainsService{c},
+		Keys:     keysService{c},
+		Perms:    permsService{c},
+		Ps:       psService{c},
+		Releases: releasesService{c},
+		Users:    usersService{c},
+	}
+}
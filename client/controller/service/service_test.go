@@ -0,0 +1,83 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/deis/deis/client/controller/client"
+	"github.com/deis/deis/version"
+	"golang.org/x/net/context"
+)
+
+const appFixture string = `
+{
+    "created": "2014-01-01T00:00:00UTC",
+    "id": "example-go",
+    "owner": "test",
+    "structure": {},
+    "updated": "2014-01-01T00:00:00UTC",
+    "url": "example-go.example.com",
+    "uuid": "de1bf5b5-4a72-4f94-a10c-d2a3741cdf75"
+}`
+
+type fakeHTTPServer struct{}
+
+func (fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Add("DEIS_API_VERSION", version.APIVersion)
+
+	if req.URL.Path == "/v1/apps/example-go/" && req.Method == "GET" {
+		res.Write([]byte(appFixture))
+		return
+	}
+
+	res.WriteHeader(http.StatusNotFound)
+	res.Write(nil)
+}
+
+func newTestServices(t *testing.T) (Services, *httptest.Server) {
+	server := httptest.NewServer(fakeHTTPServer{})
+
+	u, err := url.Parse(server.URL)
+
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	httpClient := client.CreateHTTPClient(false)
+	return New(&client.Client{HTTPClient: httpClient, ControllerURL: *u, Token: "abc"}), server
+}
+
+func TestAppsGet(t *testing.T) {
+	t.Parallel()
+
+	services, server := newTestServices(t)
+	defer server.Close()
+
+	app, err := services.Apps.Get(context.Background(), "example-go")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "example-go"
+	if app.ID != expected {
+		t.Errorf("Expected %s, Got %s", expected, app.ID)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	services, server := newTestServices(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := services.Apps.Get(ctx, "example-go"); err == nil {
+		t.Error("Expected an error from a cancelled context")
+	}
+}
@@ -42,6 +42,8 @@ Subcommands, use 'deis help [subcommand]' to learn more::
   tags          manage tags for application containers
   releases      manage releases of an application
   certs         manage SSL endpoints for an app
+  maintenance   put an application behind a maintenance page
+  whitelist     manage the addresses allowed to connect to an application
 
   keys          manage ssh keys used for 'git push' deployments
   perms         manage permissions for applications
@@ -99,6 +101,10 @@ Use 'git push deis master' to deploy to an application.
 		err = parser.Releases(argv)
 	case "certs":
 		err = parser.Certs(argv)
+	case "maintenance":
+		err = parser.Maintenance(argv)
+	case "whitelist":
+		err = parser.Whitelist(argv)
 	case "keys":
 		err = parser.Keys(argv)
 	case "perms":
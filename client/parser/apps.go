@@ -126,6 +126,8 @@ Usage: deis apps:info [options]
 Options:
   -a --app=<app>
     the uniquely identifiable name for the application.
+  --json
+    print the summary as JSON.
 `
 	args, err := docopt.Parse(usage, argv, true, "", false, true)
 
@@ -135,7 +137,7 @@ Options:
 
 	app := safeGetValue(args, "--app")
 
-	return cmd.AppInfo(app)
+	return cmd.AppInfo(app, args["--json"].(bool))
 }
 
 func appOpen(argv []string) error {
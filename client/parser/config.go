@@ -49,11 +49,18 @@ func configList(argv []string) error {
 	usage := `
 Lists environment variables for an application.
 
+Secret values are masked unless --reveal is given. A value is secret if it was set
+with 'deis config:set --secret', if its key matches a secret pattern such as *PASSWORD*,
+*TOKEN* or *_KEY, or if it is a URL containing a password. Additional patterns can be
+given as a comma separated list in the DEIS_SECRET_PATTERNS environment variable.
+
 Usage: deis config:list [options]
 
 Options:
   --oneline
     print output on one line.
+  --reveal
+    print secret values in cleartext.
   -a --app=<app>
     the uniquely identifiable name of the application.
 `
@@ -64,13 +71,19 @@ Options:
 		return err
 	}
 
-	return cmd.ConfigList(safeGetValue(args, "--app"), args["--oneline"].(bool))
+	return cmd.ConfigList(safeGetValue(args, "--app"), args["--oneline"].(bool),
+		args["--reveal"].(bool))
 }
 
 func configSet(argv []string) error {
 	usage := `
 Sets environment variables for an application.
 
+Values can be sealed with the application's RSA public key, so that they are encrypted
+before leaving the client. The application decrypts them at boot with its private key,
+for example using github.com/deis/deis/pkg/sealed. SSH_KEY is never sealed, since the
+builder uses it to fetch private dependencies.
+
 Usage: deis config:set <var>=<value> [<var>=<value>...] [options]
 
 Arguments:
@@ -82,6 +95,10 @@ Arguments:
 Options:
   -a --app=<app>
     the uniquely identifiable name for the application.
+  --secret
+    mask the values in 'deis config:list'.
+  --seal=<public-key>
+    path to a PEM encoded RSA public key to seal the values with.
 `
 
 	args, err := docopt.Parse(usage, argv, true, "", false, true)
@@ -90,7 +107,8 @@ Options:
 		return err
 	}
 
-	return cmd.ConfigSet(safeGetValue(args, "--app"), args["<var>=<value>"].([]string))
+	return cmd.ConfigSet(safeGetValue(args, "--app"), args["<var>=<value>"].([]string),
+		args["--secret"].(bool), safeGetValue(args, "--seal"))
 }
 
 func configUnset(argv []string) error {
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"github.com/deis/deis/client/cmd"
+	docopt "github.com/docopt/docopt-go"
+)
+
+// Maintenance routes maintenance commands to their specific function.
+func Maintenance(argv []string) error {
+	usage := `
+Valid commands for maintenance:
+
+maintenance:on       put an application behind a maintenance page
+maintenance:off      take an application out of maintenance
+maintenance:status   show whether an application is in maintenance
+
+Use 'deis help [command]' to learn more.
+`
+
+	switch argv[0] {
+	case "maintenance:on":
+		return maintenanceOn(argv)
+	case "maintenance:off":
+		return maintenanceOff(argv)
+	case "maintenance:status":
+		return maintenanceStatus(argv)
+	default:
+		if printHelp(argv, usage) {
+			return nil
+		}
+
+		if argv[0] == "maintenance" {
+			argv[0] = "maintenance:status"
+			return maintenanceStatus(argv)
+		}
+
+		PrintUsage()
+		return nil
+	}
+}
+
+func maintenanceOn(argv []string) error {
+	usage := `
+Puts an application behind a maintenance page.
+
+The router answers every request with a 503 and the platform's maintenance page, or
+redirects to another URL if --redirect is given. The page can be customized by an
+administrator with 'deisctl config router set maintenancePage=<html>'.
+
+Usage: deis maintenance:on [options]
+
+Options:
+  -a --app=<app>
+    the uniquely identifiable name for the application.
+  -r --redirect=<url>
+    redirect requests to this URL instead of serving the maintenance page.
+`
+
+	args, err := docopt.Parse(usage, argv, true, "", false, true)
+
+	if err != nil {
+		return err
+	}
+
+	return cmd.MaintenanceOn(safeGetValue(args, "--app"), safeGetValue(args, "--redirect"))
+}
+
+func maintenanceOff(argv []string) error {
+	usage := `
+Takes an application out of maintenance.
+
+Usage: deis maintenance:off [options]
+
+Options:
+  -a --app=<app>
+    the uniquely identifiable name for the application.
+`
+
+	args, err := docopt.Parse(usage, argv, true, "", false, true)
+
+	if err != nil {
+		return err
+	}
+
+	return cmd.MaintenanceOff(safeGetValue(args, "--app"))
+}
+
+func maintenanceStatus(argv []string) error {
+	usage := `
+Shows whether an application is in maintenance.
+
+Usage: deis maintenance:status [options]
+
+Options:
+  -a --app=<app>
+    the uniquely identifiable name for the application.
+`
+
+	args, err := docopt.Parse(usage, argv, true, "", false, true)
+
+	if err != nil {
+		return err
+	}
+
+	return cmd.MaintenanceStatus(safeGetValue(args, "--app"))
+}
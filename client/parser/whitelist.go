@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"github.com/deis/deis/client/cmd"
+	docopt "github.com/docopt/docopt-go"
+)
+
+// Whitelist routes whitelist commands to their specific function.
+func Whitelist(argv []string) error {
+	usage := `
+Valid commands for whitelist:
+
+whitelist:add        add addresses to an application's whitelist
+whitelist:list       list the addresses allowed to connect to an application
+whitelist:remove     remove addresses from an application's whitelist
+
+Use 'deis help [command]' to learn more.
+`
+
+	switch argv[0] {
+	case "whitelist:add":
+		return whitelistAdd(argv)
+	case "whitelist:list":
+		return whitelistList(argv)
+	case "whitelist:remove":
+		return whitelistRemove(argv)
+	default:
+		if printHelp(argv, usage) {
+			return nil
+		}
+
+		if argv[0] == "whitelist" {
+			argv[0] = "whitelist:list"
+			return whitelistList(argv)
+		}
+
+		PrintUsage()
+		return nil
+	}
+}
+
+func whitelistAdd(argv []string) error {
+	usage := `
+Adds addresses to an application's whitelist.
+
+Once an application has a whitelist, the router denies connections from any address
+which is not on it.
+
+Usage: deis whitelist:add <address>... [options]
+
+Arguments:
+  <address>
+    an IPv4 address or CIDR block with an optional label, ex: 10.0.0.0/8:office
+
+Options:
+  -a --app=<app>
+    the uniquely identifiable name for the application.
+`
+
+	args, err := docopt.Parse(usage, argv, true, "", false, true)
+
+	if err != nil {
+		return err
+	}
+
+	return cmd.WhitelistAdd(safeGetValue(args, "--app"), args["<address>"].([]string))
+}
+
+func whitelistList(argv []string) error {
+	usage := `
+Lists the addresses allowed to connect to an application.
+
+Usage: deis whitelist:list [options]
+
+Options:
+  -a --app=<app>
+    the uniquely identifiable name for the application.
+`
+
+	args, err := docopt.Parse(usage, argv, true, "", false, true)
+
+	if err != nil {
+		return err
+	}
+
+	return cmd.WhitelistList(safeGetValue(args, "--app"))
+}
+
+func whitelistRemove(argv []string) error {
+	usage := `
+Removes addresses from an application's whitelist.
+
+Removing the last address removes the whitelist, allowing all addresses to connect.
+
+Usage: deis whitelist:remove <address>... [options]
+
+Arguments:
+  <address>
+    an IPv4 address or CIDR block.
+
+Options:
+  -a --app=<app>
+    the uniquely identifiable name for the application.
+`
+
+	args, err := docopt.Parse(usage, argv, true, "", false, true)
+
+	if err != nil {
+		return err
+	}
+
+	return cmd.WhitelistRemove(safeGetValue(args, "--app"), args["<address>"].([]string))
+}
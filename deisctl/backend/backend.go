@@ -2,22 +2,54 @@ package backend
 
 import (
 	"io"
-	"sync"
+	"time"
 )
 
-// Backend interface is used to interact with the cluster control plane
+// RollingRestartOptions control how RollingRestart replaces the instances of a component.
+type RollingRestartOptions struct {
+	// MaxUnavailable is how many instances may be down at once. Zero surges instead,
+	// starting each replacement before the instance it replaces is stopped.
+	MaxUnavailable int
+	// HealthKey is an optional key which must exist before a replacement counts as
+	// healthy. "{instance}" is replaced with the instance number.
+	HealthKey string
+	// Timeout bounds how long to wait for each replacement to become healthy.
+	Timeout time.Duration
+}
+
+// UnitState describes one running instance of a component, as reported by the backend.
+type UnitState struct {
+	Name string
+	// Active and Sub are the systemd active and sub states, or the backend's equivalents.
+	Active string
+	Sub    string
+	Host   string
+	// Since is when the instance became active, or zero if the backend does not know. Backends
+	// which can only read it from each machine leave it to ActiveSince.
+	Since time.Time
+}
+
+// Backend interface is used to interact with the cluster control plane. Create, Destroy,
+// Start and Stop act on every target at once, write progress to the writer, and return
+// the failures of all targets once each has finished. Scale, RollingRestart and Rebalance
+// act on the instances of one component, and return once it is done or has failed.
 type Backend interface {
-	Create([]string, *sync.WaitGroup, io.Writer, io.Writer)
-	Destroy([]string, *sync.WaitGroup, io.Writer, io.Writer)
-	Start([]string, *sync.WaitGroup, io.Writer, io.Writer)
-	Stop([]string, *sync.WaitGroup, io.Writer, io.Writer)
-	Scale(string, int, *sync.WaitGroup, io.Writer, io.Writer)
-	RollingRestart(string, *sync.WaitGroup, io.Writer, io.Writer)
+	Create([]string, io.Writer) error
+	Destroy([]string, io.Writer) error
+	Start([]string, io.Writer) error
+	Stop([]string, io.Writer) error
+	Scale(string, int, io.Writer) error
+	RollingRestart(string, RollingRestartOptions, io.Writer) error
+	Rebalance(string, io.Writer) error
 	SSH(string) error
 	SSHExec(string, string) error
+	Exec([]Machine, string, io.Writer, io.Writer) error
+	Machines() ([]Machine, error)
 	Dock(string, []string) error
 	ListUnits() error
 	ListUnitFiles() error
+	UnitStates() ([]UnitState, error)
+	ActiveSince([]UnitState) ([]UnitState, error)
 	Status(string) error
-	Journal(string) error
+	Journal([]string, JournalOptions) error
 }
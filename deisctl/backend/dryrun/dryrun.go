@@ -0,0 +1,281 @@
+// Package dryrun implements a deisctl backend which prints the unit operations a command
+// would perform instead of performing them.
+//
+// It wraps the backend of the cluster, which is only asked which units are installed and
+// where they run. The operations are then simulated against that list, so that a unit
+// created by one step of a command is started by the next, as it would be for real.
+package dryrun
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/deis/deis/deisctl/backend"
+)
+
+// unscheduled is shown as the machine of units which the scheduler has yet to place.
+const unscheduled = "(chosen by the scheduler)"
+
+// DryRunClient records and prints unit operations without performing them
+type DryRunClient struct {
+	backend.Backend
+
+	out   io.Writer
+	mutex sync.Mutex
+	count int
+
+	// units maps the units installed, as far as the recorded operations go, to the
+	// machine they run on
+	units map[string]string
+}
+
+// NewClient returns a client which prints the operations it is asked to perform on the
+// units of b, rather than performing them.
+func NewClient(b backend.Backend) *DryRunClient {
+	return &DryRunClient{Backend: b, out: os.Stdout}
+}
+
+var targetRegexp = regexp.MustCompile(`^(?:deis-)?([a-z-]+)(?:@(\d+|\*))?(?:\.service)?$`)
+
+// load reads the units installed in the cluster, the first time it is called. Units the
+// scheduler has not placed yet are installed too, on no machine.
+func (c *DryRunClient) load() error {
+	if c.units != nil {
+		return nil
+	}
+	states, err := c.Backend.UnitStates()
+	if err != nil {
+		return err
+	}
+	c.units = make(map[string]string)
+	for _, s := range states {
+		c.units[s.Name] = s.Host
+	}
+	return nil
+}
+
+// expand returns the names of the units of the targets, expanding @* to the instances
+// installed.
+func (c *DryRunClient) expand(targets []string) ([]string, error) {
+	var names []string
+	for _, target := range targets {
+		match := targetRegexp.FindStringSubmatch(target)
+		if match == nil {
+			return nil, fmt.Errorf("Could not parse target: %v", target)
+		}
+		switch match[2] {
+		case "":
+			names = append(names, "deis-"+match[1]+".service")
+		case "*":
+			names = append(names, c.instances(match[1])...)
+		default:
+			names = append(names, "deis-"+match[1]+"@"+match[2]+".service")
+		}
+	}
+	return names, nil
+}
+
+// instances returns the installed instances of a component, in numeric order.
+func (c *DryRunClient) instances(component string) []string {
+	var names []string
+	for name := range c.units {
+		if strings.HasPrefix(name, "deis-"+component+"@") {
+			names = append(names, name)
+		}
+	}
+	sort.Sort(byInstance(names))
+	return names
+}
+
+// record prints an operation on a unit, numbered in the order it would be performed.
+func (c *DryRunClient) record(operation, name, note string) {
+	c.count++
+	machine, installed := c.units[name]
+	switch {
+	case note != "":
+	case !installed || machine == "":
+		note = unscheduled
+	default:
+		note = machine
+	}
+	fmt.Fprintf(c.out, "%3d  %-8s %-32s %s\n", c.count, operation, name, note)
+}
+
+// each simulates an operation on the units of the targets.
+func (c *DryRunClient) each(operation string, targets []string, apply func(name string) string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.load(); err != nil {
+		return err
+	}
+	names, err := c.expand(targets)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		c.record(operation, name, apply(name))
+	}
+	return nil
+}
+
+// Create prints the units that would be created
+func (c *DryRunClient) Create(targets []string, out io.Writer) error {
+	return c.each("create", targets, func(name string) string {
+		if _, ok := c.units[name]; ok {
+			return "(already installed)"
+		}
+		c.units[name] = ""
+		return ""
+	})
+}
+
+// Destroy prints the units that would be destroyed
+func (c *DryRunClient) Destroy(targets []string, out io.Writer) error {
+	return c.each("destroy", targets, func(name string) string {
+		machine, ok := c.units[name]
+		if !ok {
+			return "(not installed)"
+		}
+		delete(c.units, name)
+		return orUnscheduled(machine)
+	})
+}
+
+// Start prints the units that would be started
+func (c *DryRunClient) Start(targets []string, out io.Writer) error {
+	return c.each("start", targets, notInstalled(c))
+}
+
+// Stop prints the units that would be stopped
+func (c *DryRunClient) Stop(targets []string, out io.Writer) error {
+	return c.each("stop", targets, notInstalled(c))
+}
+
+// Scale prints the instances that would be created and started, or stopped and destroyed,
+// to run the requested number of instances
+func (c *DryRunClient) Scale(component string, requested int, out io.Writer) error {
+	if requested < 0 {
+		return errors.New("cannot scale below 0")
+	}
+	c.mutex.Lock()
+	if err := c.load(); err != nil {
+		c.mutex.Unlock()
+		return err
+	}
+	existing := c.instances(component)
+	taken := map[int]bool{}
+	for _, name := range existing {
+		taken[instanceNumber(name)] = true
+	}
+	c.mutex.Unlock()
+
+	// new instances take the lowest free numbers, and the highest numbers are removed
+	var targets []string
+	for i := 1; len(existing)+len(targets) < requested; i++ {
+		if !taken[i] {
+			targets = append(targets, component+"@"+strconv.Itoa(i))
+		}
+	}
+	if len(targets) > 0 {
+		if err := c.Create(targets, out); err != nil {
+			return err
+		}
+		if err := c.Start(targets, out); err != nil {
+			return err
+		}
+	}
+
+	targets = nil
+	for i := len(existing) - 1; i >= requested; i-- {
+		targets = append(targets, component+"@"+strconv.Itoa(instanceNumber(existing[i])))
+	}
+	if len(targets) > 0 {
+		if err := c.Stop(targets, out); err != nil {
+			return err
+		}
+		return c.Destroy(targets, out)
+	}
+	return nil
+}
+
+// RollingRestart prints the instances that would be restarted, batch by batch
+func (c *DryRunClient) RollingRestart(target string, opts backend.RollingRestartOptions, out io.Writer) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.load(); err != nil {
+		return err
+	}
+	component := strings.TrimSuffix(strings.TrimPrefix(target, "deis-"), "@*")
+	names := c.instances(component)
+	if len(names) == 0 {
+		names, _ = c.expand([]string{component})
+	}
+
+	batch := opts.MaxUnavailable
+	if batch < 1 {
+		batch = 1
+	}
+	for i, name := range names {
+		note := fmt.Sprintf("%s, batch %d", orUnscheduled(c.units[name]), i/batch+1)
+		if opts.MaxUnavailable == 0 {
+			note = fmt.Sprintf("%s, surged", orUnscheduled(c.units[name]))
+		}
+		c.record("restart", name, note)
+	}
+	return nil
+}
+
+// SSH is not performed in a dry run
+func (c *DryRunClient) SSH(target string) error {
+	return fmt.Errorf("ssh is not available with --dry-run")
+}
+
+// SSHExec is not performed in a dry run
+func (c *DryRunClient) SSHExec(target, command string) error {
+	return fmt.Errorf("ssh is not available with --dry-run")
+}
+
+// Dock is not performed in a dry run
+func (c *DryRunClient) Dock(target string, command []string) error {
+	return fmt.Errorf("dock is not available with --dry-run")
+}
+
+// notInstalled notes operations on units which are not installed.
+func notInstalled(c *DryRunClient) func(string) string {
+	return func(name string) string {
+		if _, ok := c.units[name]; !ok {
+			return "(not installed)"
+		}
+		return ""
+	}
+}
+
+func orUnscheduled(machine string) string {
+	if machine == "" {
+		return unscheduled
+	}
+	return machine
+}
+
+// byInstance sorts unit names by their instance number.
+type byInstance []string
+
+func (s byInstance) Len() int      { return len(s) }
+func (s byInstance) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byInstance) Less(i, j int) bool {
+	return instanceNumber(s[i]) < instanceNumber(s[j])
+}
+
+func instanceNumber(name string) int {
+	n, _ := strconv.Atoi(strings.TrimSuffix(name[strings.Index(name, "@")+1:], ".service"))
+	return n
+}
@@ -0,0 +1,175 @@
+package dryrun
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/deis/deis/deisctl/backend"
+)
+
+// cluster only answers which units are installed; any other call panics, since a dry
+// run must not change anything.
+type cluster struct {
+	backend.Backend
+	states []backend.UnitState
+}
+
+func (c cluster) UnitStates() ([]backend.UnitState, error) {
+	return c.states, nil
+}
+
+func newTestClient() (*DryRunClient, *bytes.Buffer) {
+	var out bytes.Buffer
+	c := NewClient(cluster{states: []backend.UnitState{
+		{Name: "deis-controller.service", Host: "10.0.0.1"},
+		{Name: "deis-router@1.service", Host: "10.0.0.1"},
+		{Name: "deis-router@2.service", Host: "10.0.0.2"},
+	}})
+	c.out = &out
+	return c, &out
+}
+
+func squeeze(s string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestOperations(t *testing.T) {
+	t.Parallel()
+
+	c, out := newTestClient()
+	var ew bytes.Buffer
+
+	for _, err := range []error{
+		c.Stop([]string{"router@*", "controller"}, &ew),
+		c.Destroy([]string{"controller"}, &ew),
+		c.Create([]string{"controller", "builder"}, &ew),
+		c.Start([]string{"controller", "registry@1"}, &ew),
+	} {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	expected := `1 stop deis-router@1.service 10.0.0.1
+2 stop deis-router@2.service 10.0.0.2
+3 stop deis-controller.service 10.0.0.1
+4 destroy deis-controller.service 10.0.0.1
+5 create deis-controller.service (chosen by the scheduler)
+6 create deis-builder.service (chosen by the scheduler)
+7 start deis-controller.service (chosen by the scheduler)
+8 start deis-registry@1.service (not installed)`
+	if squeeze(out.String()) != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+	}
+	if ew.Len() != 0 {
+		t.Error(ew.String())
+	}
+}
+
+func TestUnscheduled(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	c := NewClient(cluster{states: []backend.UnitState{
+		{Name: "deis-builder.service", Active: "inactive", Sub: "unscheduled"},
+	}})
+	c.out = &out
+	var ew bytes.Buffer
+
+	for _, err := range []error{
+		c.Create([]string{"builder"}, &ew),
+		c.Start([]string{"builder"}, &ew),
+	} {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	expected := `1 create deis-builder.service (already installed)
+2 start deis-builder.service (chosen by the scheduler)`
+	if squeeze(out.String()) != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+	}
+}
+
+func TestScale(t *testing.T) {
+	t.Parallel()
+
+	c, out := newTestClient()
+
+	if err := c.Scale("router", 3, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Scale("router", 1, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `1 create deis-router@3.service (chosen by the scheduler)
+2 start deis-router@3.service (chosen by the scheduler)
+3 stop deis-router@3.service (chosen by the scheduler)
+4 stop deis-router@2.service 10.0.0.2
+5 destroy deis-router@3.service (chosen by the scheduler)
+6 destroy deis-router@2.service 10.0.0.2`
+	if squeeze(out.String()) != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+	}
+
+	// instances are numbered around gaps, and the highest numbers are removed first
+	out.Reset()
+	c = NewClient(cluster{states: []backend.UnitState{
+		{Name: "deis-router@1.service", Host: "10.0.0.1"},
+		{Name: "deis-router@3.service", Host: "10.0.0.3"},
+	}})
+	c.out = out
+	if err := c.Scale("router", 3, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Scale("router", 1, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `1 create deis-router@2.service (chosen by the scheduler)
+2 start deis-router@2.service (chosen by the scheduler)
+3 stop deis-router@3.service 10.0.0.3
+4 stop deis-router@2.service (chosen by the scheduler)
+5 destroy deis-router@3.service 10.0.0.3
+6 destroy deis-router@2.service (chosen by the scheduler)`
+	if squeeze(out.String()) != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+	}
+
+	err := c.Scale("router", -1, ioutil.Discard)
+	if expected := "cannot scale below 0"; err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %v", expected, err))
+	}
+}
+
+func TestRollingRestart(t *testing.T) {
+	t.Parallel()
+
+	c, out := newTestClient()
+
+	for _, target := range []string{"router", "controller"} {
+		if err := c.RollingRestart(target, backend.RollingRestartOptions{MaxUnavailable: 1}, ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := `1 restart deis-router@1.service 10.0.0.1, batch 1
+2 restart deis-router@2.service 10.0.0.2, batch 2
+3 restart deis-controller.service 10.0.0.1, batch 1`
+	if squeeze(out.String()) != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+	}
+
+	if err := c.SSH("controller"); err == nil {
+		t.Error("Expected ssh to be refused in a dry run")
+	}
+}
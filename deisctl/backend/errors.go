@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// colorCodes match the terminal escape sequences which colorize reported errors.
+var colorCodes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Errors collects the failures which asynchronous operations write to their error
+// writer, passing them through to w as they are reported, and the errors which
+// synchronous operations return. Since each operation runs in its own goroutine, it is
+// safe for concurrent use.
+type Errors struct {
+	w      io.Writer
+	mutex  sync.Mutex
+	errors []string
+}
+
+// NewErrors returns an error writer which passes errors through to w, or only collects
+// them if w is nil.
+func NewErrors(w io.Writer) *Errors {
+	return &Errors{w: w}
+}
+
+// Write records one reported failure and passes it through.
+func (e *Errors) Write(p []byte) (int, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.add(string(p))
+	if e.w == nil {
+		return len(p), nil
+	}
+	return e.w.Write(p)
+}
+
+// Add records the error returned by an operation, if any. The failures listed by an error
+// returned from Err are recorded one by one.
+func (e *Errors) Add(err error) {
+	if err == nil {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if f, ok := err.(Failures); ok {
+		e.errors = append(e.errors, f...)
+		return
+	}
+	e.add(err.Error())
+}
+
+func (e *Errors) add(msg string) {
+	if msg = strings.TrimSpace(colorCodes.ReplaceAllString(msg, "")); msg != "" {
+		e.errors = append(e.errors, msg)
+	}
+}
+
+// Err returns an error listing every failure reported so far, or nil if there were none.
+func (e *Errors) Err() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if len(e.errors) == 0 {
+		return nil
+	}
+	return append(Failures(nil), e.errors...)
+}
+
+// Failures lists the reasons operations on several units failed.
+type Failures []string
+
+func (f Failures) Error() string {
+	if len(f) == 1 {
+		return f[0]
+	}
+	return fmt.Sprintf("%d operations failed:\n  %s", len(f), strings.Join(f, "\n  "))
+}
+
+// InParallel calls op for each of n units at once, and returns the failures of all of
+// them once every call has returned.
+func InParallel(n int, op func(int) error) error {
+	var wg sync.WaitGroup
+	errs := NewErrors(nil)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs.Add(op(i))
+		}(i)
+	}
+	wg.Wait()
+	return errs.Err()
+}
+
+// Report writes each failure listed by err to w on a line of its own, so that an Errors
+// writer records them one by one.
+func Report(w io.Writer, err error) {
+	if f, ok := err.(Failures); ok {
+		for _, msg := range f {
+			fmt.Fprintln(w, msg)
+		}
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(w, err.Error())
+	}
+}
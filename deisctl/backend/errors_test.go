@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/deis/deis/pkg/prettyprint"
+)
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	errs := NewErrors(&b)
+	if err := errs.Err(); err != nil {
+		t.Error(fmt.Errorf("Expected nil, Got %v", err))
+	}
+
+	fmt.Fprintf(errs, prettyprint.Colorize("{{.Red}}The service '%s' failed while starting.{{.Default}}\n"), "deis-builder.service")
+	expected := "The service 'deis-builder.service' failed while starting."
+	if err := errs.Err(); err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, err))
+	}
+
+	fmt.Fprintln(errs, "deis-router@1.service: timed out after 20m0s waiting to stop (last state: deactivating/stop-sigterm)")
+	expected = `2 operations failed:
+  The service 'deis-builder.service' failed while starting.
+  deis-router@1.service: timed out after 20m0s waiting to stop (last state: deactivating/stop-sigterm)`
+	if err := errs.Err(); err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, err))
+	}
+
+	// errors are passed through as they are reported
+	if !bytes.Contains(b.Bytes(), []byte("deis-router@1.service: timed out")) {
+		t.Error(fmt.Errorf("Expected the errors to be written, Got %q", b.String()))
+	}
+}
+
+func TestInParallel(t *testing.T) {
+	t.Parallel()
+
+	err := InParallel(3, func(i int) error {
+		if i == 1 {
+			return nil
+		}
+		return fmt.Errorf("unit %d failed", i)
+	})
+	f, ok := err.(Failures)
+	if !ok || len(f) != 2 {
+		t.Fatal(fmt.Errorf("Expected 2 failures, Got %v", err))
+	}
+
+	// failures are recorded one by one, whether returned or reported
+	errs := NewErrors(nil)
+	errs.Add(err)
+	Report(errs, err)
+	if got := errs.Err().(Failures); len(got) != 4 {
+		t.Error(fmt.Errorf("Expected 4 failures, Got %v", got))
+	}
+}
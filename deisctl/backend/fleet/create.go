@@ -5,27 +5,25 @@ import (
 	"io"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/coreos/fleet/job"
 	"github.com/coreos/fleet/schema"
 	"github.com/coreos/fleet/unit"
 
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/units"
 	"github.com/deis/deis/pkg/prettyprint"
 )
 
 // Create schedules unit files for the given components.
-func (c *FleetClient) Create(
-	targets []string, wg *sync.WaitGroup, out, ew io.Writer) {
-
+func (c *FleetClient) Create(targets []string, out io.Writer) error {
 	units := make([]*schema.Unit, len(targets))
 
 	for i, target := range targets {
 		unitName, unitFile, err := c.createUnitFile(target)
 		if err != nil {
-			fmt.Fprintf(ew, "Error creating: %s\n", err)
-			return
+			return fmt.Errorf("Error creating: %s", err)
 		}
 		units[i] = &schema.Unit{
 			Name:    unitName,
@@ -33,21 +31,17 @@ func (c *FleetClient) Create(
 		}
 	}
 
-	for _, unit := range units {
-		wg.Add(1)
-		go doCreate(c, unit, wg, out, ew)
-	}
+	return backend.InParallel(len(units), func(i int) error {
+		return doCreate(c, units[i], out)
+	})
 }
 
-func doCreate(c *FleetClient, unit *schema.Unit, wg *sync.WaitGroup, out, ew io.Writer) {
-	defer wg.Done()
-
+func doCreate(c *FleetClient, unit *schema.Unit, out io.Writer) error {
 	// create unit definition
 	if err := c.Fleet.CreateUnit(unit); err != nil {
 		// ignore units that already exist
 		if err.Error() != "job already exists" {
-			fmt.Fprintln(ew, err.Error())
-			return
+			return err
 		}
 	}
 
@@ -57,17 +51,27 @@ func doCreate(c *FleetClient, unit *schema.Unit, wg *sync.WaitGroup, out, ew io.
 
 	// schedule the unit
 	if err := c.Fleet.SetUnitTargetState(unit.Name, desiredState); err != nil {
-		fmt.Fprintln(ew, err)
-		return
+		return err
 	}
 
 	// loop until the unit actually exists in unit states
+	deadline := c.deadline()
+	var lastErr error
 outerLoop:
 	for {
 		time.Sleep(250 * time.Millisecond)
+		if expired(deadline) {
+			reason := "not scheduled by fleet"
+			if lastErr != nil {
+				reason = "last error: " + lastErr.Error()
+			}
+			return fmt.Errorf("%s: timed out after %v waiting to load (%s)", unit.Name, c.unitTimeout, reason)
+		}
+		// errors polling fleet are retried until the unit times out
 		unitStates, err := c.Fleet.UnitStates()
 		if err != nil {
-			fmt.Fprintln(ew, err)
+			lastErr = err
+			continue
 		}
 		for _, us := range unitStates {
 			if strings.HasPrefix(us.Name, unit.Name) {
@@ -77,6 +81,7 @@ outerLoop:
 	}
 
 	fmt.Fprintln(out, msg)
+	return nil
 }
 
 func (c *FleetClient) createUnitFile(target string) (unitName string, uf *unit.UnitFile, err error) {
@@ -105,9 +110,32 @@ func (c *FleetClient) createServiceUnit(component string, num int) (name string,
 	if err != nil {
 		return "", nil, err
 	}
-	uf, err = NewUnit(component, c.templatePaths, decorate)
+	vars, err := units.LoadVariables(component, c.configBackend)
+	if err != nil {
+		return "", nil, err
+	}
+	uf, err = NewUnit(component, c.templatePaths, decorate, vars)
 	if err != nil {
 		return
 	}
+	if num != 0 && (&job.Unit{Name: name, Unit: *uf}).IsGlobal() {
+		uf = perMachineUnit(component, uf)
+	}
 	return name, uf, nil
 }
+
+// perMachineUnit turns the unit of a component which runs on every machine into an
+// instance which conflicts with the other instances, so that scaling it runs it on as
+// many machines as there are instances.
+func perMachineUnit(component string, uf *unit.UnitFile) *unit.UnitFile {
+	var options []*schema.UnitOption
+	for _, opt := range schema.MapUnitFileToSchemaUnitOptions(uf) {
+		if opt.Section == "X-Fleet" && opt.Name == "Global" {
+			continue
+		}
+		options = append(options, opt)
+	}
+	options = append(options, &schema.UnitOption{Section: "X-Fleet", Name: "Conflicts",
+		Value: "deis-" + strings.TrimPrefix(component, "deis-") + "@*.service"})
+	return schema.MapSchemaUnitOptionsToUnitFile(options)
+}
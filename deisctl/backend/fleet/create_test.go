@@ -38,14 +38,13 @@ func TestCreate(t *testing.T) {
 	c := &FleetClient{templatePaths: []string{name}, Fleet: &testFleetClient, configBackend: testConfigBackend}
 
 	var errOutput string
-	var wg sync.WaitGroup
 
 	logMutex := sync.Mutex{}
 
 	se := newOutErr()
-	c.Create([]string{"controller", "builder", "router@1"}, &wg, se.out, se.ew)
-
-	wg.Wait()
+	if err := c.Create([]string{"controller", "builder", "router@1"}, se.out); err != nil {
+		t.Fatal(err)
+	}
 
 	logMutex.Lock()
 	if errOutput != "" {
@@ -4,55 +4,69 @@ import (
 	"fmt"
 	"io"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/deis/deis/deisctl/backend"
 	"github.com/deis/deis/pkg/prettyprint"
 )
 
 // Destroy units for a given target
-func (c *FleetClient) Destroy(targets []string, wg *sync.WaitGroup, out, ew io.Writer) {
+func (c *FleetClient) Destroy(targets []string, out io.Writer) error {
 	// expand @* targets
 	expandedTargets, err := c.expandTargets(targets)
 	if err != nil {
-		fmt.Fprintln(ew, err.Error())
-		return
+		return err
 	}
 
-	for _, target := range expandedTargets {
-		wg.Add(1)
-		go doDestroy(c, target, wg, out, ew)
-	}
-	return
+	return backend.InParallel(len(expandedTargets), func(i int) error {
+		return doDestroy(c, expandedTargets[i], out)
+	})
 }
 
-func doDestroy(c *FleetClient, target string, wg *sync.WaitGroup, out, ew io.Writer) {
-	defer wg.Done()
-
+func doDestroy(c *FleetClient, target string, out io.Writer) error {
 	// prepare string representation
 	component, num, err := splitTarget(target)
 	if err != nil {
-		fmt.Fprintln(ew, err.Error())
-		return
+		return err
 	}
 	name, err := formatUnitName(component, num)
 	if err != nil {
-		fmt.Fprintln(ew, err.Error())
-		return
+		return err
 	}
 	tpl := prettyprint.Colorize("{{.Yellow}}%v:{{.Default}} destroyed")
 	destroyed := fmt.Sprintf(tpl, name)
 
+	// a unit which was never installed is already gone
+	if u, err := c.Fleet.Unit(name); err != nil {
+		return err
+	} else if u == nil {
+		fmt.Fprintf(out, notInstalledFmt+"\n", name)
+		return nil
+	}
+
 	// tell fleet to destroy the unit
-	c.Fleet.DestroyUnit(name)
+	if err := c.Fleet.DestroyUnit(name); err != nil && err.Error() != "job does not exist" {
+		return err
+	}
 
 	// loop until the unit is actually gone from unit states
+	deadline := c.deadline()
+	var lastErr error
 outerLoop:
 	for {
 		time.Sleep(250 * time.Millisecond)
+		if expired(deadline) {
+			reason := "still reported by fleet"
+			if lastErr != nil {
+				reason = "last error: " + lastErr.Error()
+			}
+			return fmt.Errorf("%s: timed out after %v waiting to be destroyed (%s)", name, c.unitTimeout, reason)
+		}
+		// errors polling fleet are retried until the unit times out
 		unitStates, err := c.Fleet.UnitStates()
 		if err != nil {
-			fmt.Fprintln(ew, err.Error())
+			lastErr = err
+			continue
 		}
 		for _, us := range unitStates {
 			if strings.HasPrefix(us.Name, name) {
@@ -60,6 +74,6 @@ outerLoop:
 			}
 		}
 		fmt.Fprintln(out, destroyed)
-		return
+		return nil
 	}
 }
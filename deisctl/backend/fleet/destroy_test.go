@@ -27,14 +27,13 @@ func TestDestroy(t *testing.T) {
 	c := &FleetClient{Fleet: &testFleetClient}
 
 	var errOutput string
-	var wg sync.WaitGroup
 
 	logMutex := sync.Mutex{}
 
 	oe := newOutErr()
-	c.Destroy([]string{"controller", "registry", "router@1"}, &wg, oe.out, oe.ew)
-
-	wg.Wait()
+	if err := c.Destroy([]string{"controller", "registry", "router@1"}, oe.out); err != nil {
+		t.Fatal(err)
+	}
 
 	logMutex.Lock()
 	if errOutput != "" {
@@ -0,0 +1,165 @@
+package fleet
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Endpoints returns the etcd members deisctl talks to, from a comma-separated --endpoint
+// list or from the SRV records of --discovery-srv. The list starts at a random member
+// so that clients spread across the cluster, and requests fail over to the members
+// after it in turn.
+func Endpoints() ([]string, error) {
+	eps, err := endpoints(Flags.Endpoint, Flags.DiscoverySRV, Flags.EtcdCertFile != "" || Flags.EtcdCAFile != "")
+	if err != nil {
+		return nil, err
+	}
+	i := rand.New(rand.NewSource(time.Now().UnixNano())).Intn(len(eps))
+	return append(eps[i:], eps[:i]...), nil
+}
+
+// endpoints parses the etcd members, which must all use https when secure is set.
+func endpoints(endpoint, srvDomain string, secure bool) ([]string, error) {
+	var eps []string
+	if srvDomain != "" {
+		var err error
+		if eps, err = discover(net.LookupSRV, srvDomain, secure); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, ep := range strings.Split(endpoint, ",") {
+			if ep = strings.TrimSpace(ep); ep != "" {
+				eps = append(eps, ep)
+			}
+		}
+	}
+	if len(eps) == 0 {
+		return nil, fmt.Errorf("no etcd endpoint given, use --endpoint or --discovery-srv")
+	}
+
+	for i, ep := range eps {
+		if !strings.Contains(ep, "://") {
+			if secure {
+				ep = "https://" + ep
+			} else {
+				ep = "http://" + ep
+			}
+		}
+		u, err := url.Parse(ep)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid etcd endpoint %q, expected a URL such as http://127.0.0.1:4001", eps[i])
+		}
+		if secure && u.Scheme != "https" {
+			return nil, fmt.Errorf("etcd endpoint %s does not use https, but TLS certificates were given", ep)
+		}
+		eps[i] = ep
+	}
+	return eps, nil
+}
+
+// discover looks up the etcd members published for a domain, as in
+// _etcd-client-ssl._tcp.example.com for https members and _etcd-client._tcp.example.com
+// for http members.
+func discover(lookupSRV func(service, proto, name string) (string, []*net.SRV, error),
+	domain string, secure bool) ([]string, error) {
+	services := []struct{ service, scheme string }{{"etcd-client-ssl", "https"}}
+	if !secure {
+		services = append(services, struct{ service, scheme string }{"etcd-client", "http"})
+	}
+
+	var eps []string
+	var lastErr error
+	for _, s := range services {
+		_, addrs, err := lookupSRV(s.service, "tcp", domain)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, addr := range addrs {
+			host := strings.TrimSuffix(addr.Target, ".")
+			eps = append(eps, fmt.Sprintf("%s://%s", s.scheme, net.JoinHostPort(host, fmt.Sprint(addr.Port))))
+		}
+	}
+	if len(eps) == 0 {
+		return nil, fmt.Errorf("could not discover etcd members of %s: %v", domain, lastErr)
+	}
+	return eps, nil
+}
+
+// TLSConfig returns the TLS configuration for etcd built from --etcd-cafile,
+// --etcd-certfile and --etcd-keyfile. The files are checked up front, so that a missing,
+// mismatched or expired certificate is reported as such rather than as an unreachable
+// cluster.
+func TLSConfig() (*tls.Config, error) {
+	return readTLSConfig(Flags.EtcdCAFile, Flags.EtcdCertFile, Flags.EtcdKeyFile, time.Now())
+}
+
+func readTLSConfig(cafile, certfile, keyfile string, now time.Time) (*tls.Config, error) {
+	if (certfile == "") != (keyfile == "") {
+		return nil, fmt.Errorf("--etcd-certfile and --etcd-keyfile must be given together")
+	}
+	if certfile == "" && cafile == "" {
+		// as before, members using https are not verified without certificates
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS10}
+
+	if certfile != "" {
+		certPEM, err := ioutil.ReadFile(certfile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read --etcd-certfile: %v", err)
+		}
+		keyPEM, err := ioutil.ReadFile(keyfile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read --etcd-keyfile: %v", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("%s and %s are not a valid certificate and key pair: %v", certfile, keyfile, err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the certificate in %s: %v", certfile, err)
+		}
+		if now.After(leaf.NotAfter) {
+			return nil, fmt.Errorf("the certificate in %s expired on %s", certfile, leaf.NotAfter.Format(time.RFC3339))
+		}
+		if now.Before(leaf.NotBefore) {
+			return nil, fmt.Errorf("the certificate in %s is not valid before %s", certfile, leaf.NotBefore.Format(time.RFC3339))
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if cafile != "" {
+		caPEM, err := ioutil.ReadFile(cafile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read --etcd-cafile: %v", err)
+		}
+		pool := x509.NewCertPool()
+		for block, rest := pem.Decode(caPEM); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			ca, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the CA certificate in %s: %v", cafile, err)
+			}
+			pool.AddCert(ca)
+		}
+		if len(pool.Subjects()) == 0 {
+			return nil, fmt.Errorf("%s holds no PEM encoded CA certificates", cafile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,157 @@
+package fleet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEndpoints(t *testing.T) {
+	t.Parallel()
+
+	eps, err := endpoints(" http://10.0.0.1:4001, 10.0.0.2:4001,,", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"http://10.0.0.1:4001", "http://10.0.0.2:4001"}
+	if !reflect.DeepEqual(eps, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, eps))
+	}
+
+	eps, err = endpoints("10.0.0.1:2379", "", true)
+	if err != nil || eps[0] != "https://10.0.0.1:2379" {
+		t.Error(fmt.Errorf("Expected https://10.0.0.1:2379, Got %v (%v)", eps, err))
+	}
+
+	_, err = endpoints("https://10.0.0.1:2379,http://10.0.0.2:4001", "", true)
+	expectedErr := "etcd endpoint http://10.0.0.2:4001 does not use https, but TLS certificates were given"
+	if err == nil || err.Error() != expectedErr {
+		t.Error(fmt.Errorf("Expected %s, Got %v", expectedErr, err))
+	}
+
+	if _, err = endpoints("ftp://10.0.0.1", "", false); err == nil {
+		t.Error(fmt.Errorf("Expected an invalid endpoint error, Got nil"))
+	}
+	if _, err = endpoints(" , ", "", false); err == nil {
+		t.Error(fmt.Errorf("Expected a missing endpoint error, Got nil"))
+	}
+}
+
+func TestDiscoverEndpoints(t *testing.T) {
+	t.Parallel()
+
+	lookup := func(service, proto, name string) (string, []*net.SRV, error) {
+		if name != "example.com" {
+			return "", nil, fmt.Errorf("no such host")
+		}
+		if service == "etcd-client-ssl" {
+			return "", []*net.SRV{{Target: "etcd1.example.com.", Port: 2379}}, nil
+		}
+		return "", []*net.SRV{{Target: "etcd2.example.com.", Port: 4001}}, nil
+	}
+
+	eps, err := discover(lookup, "example.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"https://etcd1.example.com:2379", "http://etcd2.example.com:4001"}
+	if !reflect.DeepEqual(eps, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, eps))
+	}
+
+	// only members using https are discovered when certificates were given
+	eps, err = discover(lookup, "example.com", true)
+	if err != nil || !reflect.DeepEqual(eps, expected[:1]) {
+		t.Error(fmt.Errorf("Expected %v, Got %v (%v)", expected[:1], eps, err))
+	}
+
+	_, err = discover(lookup, "example.org", false)
+	if err == nil || !strings.HasPrefix(err.Error(), "could not discover etcd members of example.org") {
+		t.Error(fmt.Errorf("Expected a discovery error, Got %v", err))
+	}
+}
+
+// writeCert writes a self-signed certificate valid from notBefore to notAfter and its
+// key, returning their paths.
+func writeCert(t *testing.T, dir, name string, notBefore, notAfter time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestReadTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "deisctl-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	cert, key := writeCert(t, dir, "client", now.Add(-time.Hour), now.Add(time.Hour))
+	expired, expiredKey := writeCert(t, dir, "expired", now.Add(-2*time.Hour), now.Add(-time.Hour))
+
+	cfg, err := readTLSConfig(cert, cert, key, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Certificates) != 1 || cfg.RootCAs == nil || cfg.InsecureSkipVerify {
+		t.Error(fmt.Errorf("Expected a verifying config with a client certificate, Got %+v", cfg))
+	}
+
+	cfg, err = readTLSConfig("", "", "", now)
+	if err != nil || !cfg.InsecureSkipVerify {
+		t.Error(fmt.Errorf("Expected the default config, Got %+v (%v)", cfg, err))
+	}
+
+	for _, test := range []struct {
+		cafile, certfile, keyfile string
+		expected                  string
+	}{
+		{"", cert, "", "--etcd-certfile and --etcd-keyfile must be given together"},
+		{"", filepath.Join(dir, "missing.pem"), key, "could not read --etcd-certfile"},
+		{"", cert, expiredKey, cert + " and " + expiredKey + " are not a valid certificate and key pair"},
+		{"", expired, expiredKey, "the certificate in " + expired + " expired on"},
+		{key, "", "", key + " holds no PEM encoded CA certificates"},
+	} {
+		_, err := readTLSConfig(test.cafile, test.certfile, test.keyfile, now)
+		if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Error(fmt.Errorf("Expected %s, Got %v", test.expected, err))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package fleet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/deis/deis/deisctl/backend"
+)
+
+// Machines returns the machines of the fleet cluster, ordered by ID
+func (c *FleetClient) Machines() ([]backend.Machine, error) {
+	states, err := c.Fleet.Machines()
+	if err != nil {
+		return nil, err
+	}
+	var machines []backend.Machine
+	for _, ms := range states {
+		machines = append(machines, backend.Machine{ID: ms.ID, Host: ms.PublicIP, Metadata: ms.Metadata})
+	}
+	sort.Sort(byID(machines))
+	return machines, nil
+}
+
+type byID []backend.Machine
+
+func (m byID) Len() int           { return len(m) }
+func (m byID) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m byID) Less(i, j int) bool { return m[i].ID < m[j].ID }
+
+// Exec runs a command on several machines in parallel. The output of each machine is
+// printed under a header once every machine has finished, and the machines on which the
+// command failed are reported.
+func (c *FleetClient) Exec(machines []backend.Machine, cmd string, out, ew io.Writer) error {
+	outputs := make([]bytes.Buffer, len(machines))
+	exits := make([]int, len(machines))
+	var wg sync.WaitGroup
+	for i, m := range machines {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			// collect errors along with the output, so that they are shown under the same header
+			exits[i] = c.runCommand("("+cmd+") 2>&1", id, &outputs[i])
+		}(i, m.ID)
+	}
+	wg.Wait()
+
+	errs := backend.NewErrors(ew)
+	for i, m := range machines {
+		host := machineName(m)
+		fmt.Fprintf(out, "==> %s <==\n", host)
+		out.Write(outputs[i].Bytes())
+		if exits[i] != 0 {
+			fmt.Fprintf(errs, "%s: exited with status %d\n", host, exits[i])
+		}
+	}
+	return errs.Err()
+}
+
+// machineName describes a machine as fleetctl list-machines does, by its IP and the start
+// of its ID
+func machineName(m backend.Machine) string {
+	id := m.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return fmt.Sprintf("%s (%s)", m.Host, id)
+}
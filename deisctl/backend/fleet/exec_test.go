@@ -0,0 +1,64 @@
+package fleet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/fleet/machine"
+)
+
+// mockExecCommandRunner fails on the machine at 2.2.2.2.
+type mockExecCommandRunner struct{}
+
+func (mockExecCommandRunner) LocalCommand(string, io.Writer) (int, error) {
+	return 0, nil
+}
+
+func (mockExecCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration, stdout io.Writer) (int, error) {
+	fmt.Fprintf(stdout, "%s on %s\n", cmd, addr)
+	if addr == "2.2.2.2" {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+
+	testMachines := []machine.MachineState{
+		{ID: "b2e5f1c3d4", PublicIP: "2.2.2.2", Metadata: map[string]string{"role": "dataPlane"}},
+		{ID: "a1b2c3d4e5", PublicIP: "1.1.1.1", Metadata: map[string]string{"role": "control"}},
+	}
+	var out, ew bytes.Buffer
+	c := &FleetClient{Fleet: &stubFleetClient{testMachineStates: testMachines},
+		runner: mockExecCommandRunner{}, errWriter: &ew}
+
+	machines, err := c.Machines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(machines) != 2 || machines[0].Host != "1.1.1.1" || machines[1].Metadata["role"] != "dataPlane" {
+		t.Fatal(fmt.Errorf("Expected machines ordered by ID, Got %v", machines))
+	}
+
+	err = c.Exec(machines, "docker ps", &out, &ew)
+	expected := `==> 1.1.1.1 (a1b2c3d4) <==
+(docker ps) 2>&1 on 1.1.1.1
+==> 2.2.2.2 (b2e5f1c3) <==
+(docker ps) 2>&1 on 2.2.2.2
+`
+	if out.String() != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+	}
+	expectedErr := "2.2.2.2 (b2e5f1c3): exited with status 1"
+	if err == nil || err.Error() != expectedErr {
+		t.Error(fmt.Errorf("Expected %s, Got %v", expectedErr, err))
+	}
+	if !strings.Contains(ew.String(), expectedErr) {
+		t.Error(fmt.Errorf("Expected %s to be reported, Got %q", expectedErr, ew.String()))
+	}
+}
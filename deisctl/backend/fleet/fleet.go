@@ -3,10 +3,12 @@ package fleet
 import (
 	"io"
 	"os"
-	"path"
+	"sync"
 	"text/tabwriter"
+	"time"
 
 	"github.com/deis/deis/deisctl/config"
+	"github.com/deis/deis/deisctl/units"
 
 	"github.com/coreos/fleet/client"
 	"github.com/coreos/fleet/machine"
@@ -22,8 +24,17 @@ type FleetClient struct {
 
 	templatePaths []string
 	runner        commandRunner
-	out           *tabwriter.Writer
-	errWriter     io.Writer
+
+	// the base names of the units deisctl manages, read once by unitNames
+	namesOnce sync.Once
+	names     []string
+
+	// unitTimeout bounds how long to wait for a unit to reach the requested state, or
+	// is zero to wait indefinitely
+	unitTimeout time.Duration
+
+	out       *tabwriter.Writer
+	errWriter io.Writer
 }
 
 // NewClient returns a client used to communicate with Fleet
@@ -34,16 +45,9 @@ func NewClient(cb config.Backend) (*FleetClient, error) {
 		return nil, err
 	}
 
-	// path hierarchy for finding systemd service templates
-	templatePaths := []string{
-		os.Getenv("DEISCTL_UNITS"),
-		path.Join(os.Getenv("HOME"), ".deis", "units"),
-		"/var/lib/deis/units",
-	}
-
 	out := new(tabwriter.Writer)
 	out.Init(os.Stdout, 0, 8, 1, '\t', 0)
 
-	return &FleetClient{Fleet: client, configBackend: cb, templatePaths: templatePaths, runner: sshCommandRunner{},
-		out: out, errWriter: os.Stderr}, nil
+	return &FleetClient{Fleet: client, configBackend: cb, templatePaths: units.TemplatePaths(), runner: sshCommandRunner{},
+		unitTimeout: time.Duration(Flags.UnitTimeout*1000) * time.Millisecond, out: out, errWriter: os.Stderr}, nil
 }
@@ -2,6 +2,7 @@ package fleet
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -34,7 +35,8 @@ func (c *stubFleetClient) Unit(name string) (*schema.Unit, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("Unit %s not found", name)
+	// like fleet, report a unit which does not exist as nil rather than as an error
+	return nil, nil
 }
 func (c *stubFleetClient) Units() ([]*schema.Unit, error) {
 	c.unitsMutex.Lock()
@@ -58,16 +60,57 @@ func (c *failingFleetClient) SetUnitTargetState(name, target string) error {
 		return err
 	}
 
+	// the unit fails on the first machine, if there is one
+	var machineID string
+	if len(c.testMachineStates) > 0 {
+		machineID = c.testMachineStates[0].ID
+	}
 	last := len(c.testUnitStates) - 1
 	c.testUnitStates[last] = &schema.UnitState{
 		Name:               name,
 		SystemdSubState:    "failed",
 		SystemdActiveState: "failed",
+		MachineID:          machineID,
 	}
 
 	return nil
 }
 
+// stuckFleetClient never finishes activating the units it is asked to launch.
+type stuckFleetClient struct {
+	stubFleetClient
+}
+
+func (c *stuckFleetClient) SetUnitTargetState(name, target string) error {
+	c.unitStatesMutex.Lock()
+	defer c.unitStatesMutex.Unlock()
+
+	c.testUnitStates = append(c.testUnitStates, &schema.UnitState{
+		Name:               name,
+		SystemdSubState:    "start-pre",
+		SystemdActiveState: "activating",
+	})
+
+	return nil
+}
+
+// flakyFleetClient fails to report unit states a given number of times.
+type flakyFleetClient struct {
+	stubFleetClient
+	failures int
+}
+
+func (c *flakyFleetClient) UnitStates() ([]*schema.UnitState, error) {
+	c.unitStatesMutex.Lock()
+	if c.failures > 0 {
+		c.failures--
+		c.unitStatesMutex.Unlock()
+		return nil, errors.New("connection refused")
+	}
+	c.unitStatesMutex.Unlock()
+	return c.stubFleetClient.UnitStates()
+}
+
 func (c *stubFleetClient) SetUnitTargetState(name, target string) error {
 
 	var activeState string
@@ -87,6 +130,9 @@ func (c *stubFleetClient) SetUnitTargetState(name, target string) error {
 	if err != nil {
 		return err
 	}
+	if unit == nil {
+		return fmt.Errorf("unit does not exist and options field empty")
+	}
 
 	c.unitsMutex.Lock()
 	unit.DesiredState = target
@@ -125,6 +171,15 @@ func (c *stubFleetClient) DestroyUnit(name string) error {
 	}
 	c.unitsMutex.Unlock()
 
+	// fleet forgets the state of destroyed units
+	c.unitStatesMutex.Lock()
+	for i := len(c.testUnitStates) - 1; i >= 0; i-- {
+		if c.testUnitStates[i].Name == name {
+			c.testUnitStates = append(c.testUnitStates[:i], c.testUnitStates[i+1:]...)
+		}
+	}
+	c.unitStatesMutex.Unlock()
+
 	return nil
 }
 
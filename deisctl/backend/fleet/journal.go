@@ -2,34 +2,88 @@ package fleet
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/utils"
 )
 
-// Journal prints the systemd journal of target unit(s)
-func (c *FleetClient) Journal(target string) (err error) {
-	units, err := c.Units(target)
+// journalLines is how many lines of each unit's journal Journal prints by default.
+const journalLines = 40
+
+// Journal prints the systemd journals of the target units, read concurrently from the
+// machines running them and interleaved by time. A target ending in @* stands for every
+// instance of a component.
+func (c *FleetClient) Journal(targets []string, opts backend.JournalOptions) error {
+	var names []string
+	for _, target := range targets {
+		units, err := c.Units(strings.TrimSuffix(target, "*"))
+		if err != nil {
+			return err
+		}
+		names = append(names, units...)
+	}
+
+	printer, err := backend.NewJournalPrinter(os.Stdout, names, opts)
 	if err != nil {
-		return
+		return err
 	}
-	for _, unit := range units {
-		c.runJournal(unit)
+
+	errs := backend.NewErrors(c.errWriter)
+	entries := make(chan backend.JournalEntry)
+	var wg sync.WaitGroup
+	for _, name := range names {
+		machineID, err := c.journalMachine(name)
+		if err != nil {
+			fmt.Fprintln(errs, strings.TrimSpace(err.Error()))
+			continue
+		}
+
+		wg.Add(1)
+		go func(name, machineID string) {
+			defer wg.Done()
+			c.runJournal(name, machineID, opts, entries, errs)
+		}(name, machineID)
 	}
-	return
+	go func() {
+		wg.Wait()
+		close(entries)
+	}()
+
+	printer.Print(entries)
+	return errs.Err()
 }
 
-// runJournal tails the systemd journal for a given unit
-func (c *FleetClient) runJournal(name string) (exit int) {
+// journalMachine returns the machine whose journal holds the entries of a unit
+func (c *FleetClient) journalMachine(name string) (string, error) {
 	u, err := c.Fleet.Unit(name)
-	if suToGlobal(*u) {
-		fmt.Fprintf(c.errWriter, "Unable to get journal for global unit %s. Check on a host directly using journalctl.\n", name)
-		return 1
+	if err == nil && u != nil && suToGlobal(*u) {
+		return "", fmt.Errorf("Unable to get journal for global unit %s. Check on a host directly using journalctl.", name)
 	}
-
 	machineID, err := c.findUnit(name)
+	return machineID, err
+}
 
-	if err != nil {
-		return 1
+// runJournal sends the journal entries of a unit to a channel until journalctl exits
+func (c *FleetClient) runJournal(name, machineID string, opts backend.JournalOptions,
+	entries chan<- backend.JournalEntry, ew io.Writer) {
+
+	args := append([]string{"journalctl", "--unit", name}, opts.JournalctlArgs(journalLines)...)
+	for i, arg := range args {
+		args[i] = utils.ShellQuote(arg)
 	}
 
-	command := fmt.Sprintf("journalctl --unit %s --no-pager -n 40 -f", name)
-	return c.runCommand(command, machineID)
+	r, w := io.Pipe()
+	go func() {
+		if exit := c.runCommand(strings.Join(args, " "), machineID, w); exit != 0 {
+			fmt.Fprintf(ew, "journalctl exited with status %d for %s\n", exit, name)
+		}
+		w.Close()
+	}()
+	if err := backend.ReadJournal(r, name, entries); err != nil {
+		fmt.Fprintf(ew, "Error reading the journal of %s: %v\n", name, err)
+	}
 }
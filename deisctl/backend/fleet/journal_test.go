@@ -3,29 +3,33 @@ package fleet
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/schema"
+	"github.com/deis/deis/deisctl/backend"
 )
 
 type mockJournalCommandRunner struct {
 	validUnits []string
+	args       string
 }
 
-func (mockJournalCommandRunner) LocalCommand(string) (int, error) {
+func (mockJournalCommandRunner) LocalCommand(string, io.Writer) (int, error) {
 	return 0, nil
 }
 
-func (m mockJournalCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration) (int, error) {
+func (m mockJournalCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration, stdout io.Writer) (int, error) {
 	if addr != "1.1.1.1" || timeout != 0 {
 		return -1, fmt.Errorf("Got %s %d, which is unexpected", cmd, addr, timeout)
 	}
 
 	for _, unit := range m.validUnits {
-		if fmt.Sprintf("journalctl --unit %s --no-pager -n 40 -f", unit) == cmd {
+		if fmt.Sprintf("journalctl --unit %s %s", unit, m.args) == cmd {
 			return 0, nil
 		}
 	}
@@ -62,9 +66,10 @@ func TestJournal(t *testing.T) {
 
 	c := &FleetClient{Fleet: &stubFleetClient{testUnits: testUnits, testMachineStates: testMachines,
 		unitsMutex: &sync.Mutex{}}, errWriter: &testWriter, runner: mockJournalCommandRunner{
-		validUnits: []string{"deis-router@1.service", "deis-router@2.service"}}}
+		validUnits: []string{"deis-router@1.service", "deis-router@2.service"},
+		args:       "--no-pager --output json -n 40"}}
 
-	err := c.Journal("router")
+	err := c.Journal([]string{"router"}, backend.JournalOptions{})
 
 	if err != nil {
 		t.Error(err)
@@ -75,4 +80,30 @@ func TestJournal(t *testing.T) {
 	if commandErr != "" {
 		t.Error(commandErr)
 	}
+
+	// @* stands for every instance
+	err = c.Journal([]string{"router@*"}, backend.JournalOptions{})
+	if err != nil {
+		t.Error(err)
+	}
+	if commandErr := testWriter.String(); commandErr != "" {
+		t.Error(commandErr)
+	}
+
+	// options are passed to journalctl, quoted for the remote shell
+	c.runner = mockJournalCommandRunner{validUnits: []string{"deis-router@1.service"},
+		args: "--no-pager --output json --since '2016-01-02 15:04:05' --follow"}
+	err = c.Journal([]string{"router@1"}, backend.JournalOptions{Follow: true, Since: "2016-01-02 15:04:05"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// a unit whose journal cannot be read is reported once the others are printed
+	c.runner = mockJournalCommandRunner{validUnits: []string{"deis-router@1.service"},
+		args: "--no-pager --output json --since -600s"}
+	err = c.Journal([]string{"router"}, backend.JournalOptions{Since: "10m"})
+	expected := "journalctl exited with status -1 for deis-router@2.service"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Error(fmt.Errorf("Expected %s, Got %v", expected, err))
+	}
 }
@@ -1,14 +1,21 @@
 package fleet
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/schema"
+	"github.com/deis/deis/deisctl/backend"
 	"github.com/deis/deis/deisctl/units"
+	"github.com/deis/deis/deisctl/utils"
 )
 
+// timestampLayout is how systemctl show formats timestamps.
+const timestampLayout = "Mon 2006-01-02 15:04:05 MST"
+
 const (
 	//defaultListUnitFields  = "unit,state,load,active,sub,machine"
 	defaultListUnitsFields = "unit,machine,load,active,sub"
@@ -64,6 +71,20 @@ var (
 	}
 )
 
+// unitNames returns the base names of the units deisctl manages, reading the manifest of
+// custom components only the first time. A malformed manifest leaves the custom
+// components out, as the client warns when it reads the manifest.
+func (c *FleetClient) unitNames() []string {
+	c.namesOnce.Do(func() {
+		names, err := units.AllNames(c.templatePaths)
+		if err != nil {
+			names = units.Names
+		}
+		c.names = names
+	})
+	return c.names
+}
+
 // ListUnits prints all Deis-related units to Stdout
 func (c *FleetClient) ListUnits() (err error) {
 	var states []*schema.UnitState
@@ -72,9 +93,10 @@ func (c *FleetClient) ListUnits() (err error) {
 	if err != nil {
 		return err
 	}
+	names := c.unitNames()
 
 	for _, us := range unitStates {
-		for _, prefix := range units.Names {
+		for _, prefix := range names {
 			if strings.HasPrefix(us.Name, prefix) {
 				states = append(states, us)
 				break
@@ -85,6 +107,123 @@ func (c *FleetClient) ListUnits() (err error) {
 	return
 }
 
+// UnitStates returns the state of every Deis-related unit and the machine it runs on,
+// including units which are installed but which fleet has not scheduled yet. Fleet does not
+// record when units became active, so Since is left for ActiveSince.
+func (c *FleetClient) UnitStates() ([]backend.UnitState, error) {
+	units, err := c.Fleet.Units()
+	if err != nil {
+		return nil, err
+	}
+	unitStates, err := c.Fleet.UnitStates()
+	if err != nil {
+		return nil, err
+	}
+	names := c.unitNames()
+	deis := func(name string) bool {
+		for _, prefix := range names {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var states []backend.UnitState
+	reported := make(map[string]bool)
+	for _, us := range unitStates {
+		if !deis(us.Name) {
+			continue
+		}
+		state := backend.UnitState{Name: us.Name, Active: us.SystemdActiveState, Sub: us.SystemdSubState}
+		if ms := c.cachedMachineState(us.MachineID); ms != nil {
+			state.Host = ms.PublicIP
+		}
+		states = append(states, state)
+		reported[us.Name] = true
+	}
+
+	// fleet reports no state for units it has not scheduled on a machine
+	for _, u := range units {
+		if !deis(u.Name) || reported[u.Name] {
+			continue
+		}
+		state := backend.UnitState{Name: u.Name, Active: "inactive", Sub: "unscheduled"}
+		if ms := c.cachedMachineState(u.MachineID); ms != nil {
+			state.Host = ms.PublicIP
+			state.Sub = u.CurrentState
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// ActiveSince returns the states with the time each active unit became active, read from
+// systemd over SSH on the machines running them.
+func (c *FleetClient) ActiveSince(states []backend.UnitState) ([]backend.UnitState, error) {
+	unitStates, err := c.Fleet.UnitStates()
+	if err != nil {
+		return nil, err
+	}
+	machineIDs := make(map[string]string)
+	for _, us := range unitStates {
+		if us.SystemdActiveState == "active" && us.MachineID != "" {
+			machineIDs[us.Name] = us.MachineID
+		}
+	}
+
+	active := make(map[string][]string)
+	for _, s := range states {
+		if id, ok := machineIDs[s.Name]; ok {
+			active[id] = append(active[id], s.Name)
+		}
+	}
+	since := make(map[string]time.Time)
+	for machineID, names := range active {
+		for name, t := range c.activeSince(machineID, names) {
+			since[name] = t
+		}
+	}
+
+	withSince := append([]backend.UnitState(nil), states...)
+	for i := range withSince {
+		if t, ok := since[withSince[i].Name]; ok {
+			withSince[i].Since = t
+		}
+	}
+	return withSince, nil
+}
+
+// activeSince asks systemd on a machine when each of the given units became active, since
+// fleet does not record it. Units whose timestamp cannot be read are left out.
+func (c *FleetClient) activeSince(machineID string, names []string) map[string]time.Time {
+	args := []string{"systemctl", "show", "-p", "Id", "-p", "ActiveEnterTimestamp"}
+	for _, name := range names {
+		args = append(args, utils.ShellQuote(name))
+	}
+
+	var out bytes.Buffer
+	if exit := c.runCommand(strings.Join(args, " "), machineID, &out); exit != 0 {
+		return nil
+	}
+
+	// systemctl separates the properties of each unit with a blank line
+	since := make(map[string]time.Time)
+	for _, block := range strings.Split(out.String(), "\n\n") {
+		props := make(map[string]string)
+		for _, line := range strings.Split(block, "\n") {
+			parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+			if len(parts) == 2 {
+				props[parts[0]] = parts[1]
+			}
+		}
+		if t, err := time.Parse(timestampLayout, props["ActiveEnterTimestamp"]); err == nil {
+			since[props["Id"]] = t
+		}
+	}
+	return since
+}
+
 // printUnits writes units to stdout using a tabwriter
 func (c *FleetClient) printUnits(states []*schema.UnitState) {
 	cols := strings.Split(defaultListUnitsFields, ",")
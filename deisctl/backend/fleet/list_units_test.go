@@ -2,12 +2,21 @@ package fleet
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
 	"sync"
 	"testing"
 	"text/tabwriter"
+	"time"
 
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/schema"
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/units"
 )
 
 func TestListUnits(t *testing.T) {
@@ -72,3 +81,153 @@ deis-router@1.service	654321.../2.2.2.2	loaded	active	running
 		t.Errorf("Expected '%s', Got '%s'", expected, actual)
 	}
 }
+
+func TestUnitStates(t *testing.T) {
+	t.Parallel()
+
+	testUnitStates := []*schema.UnitState{
+		&schema.UnitState{
+			Name:               "deis-router@1.service",
+			MachineID:          "654321",
+			SystemdActiveState: "failed",
+			SystemdSubState:    "failed",
+		},
+		&schema.UnitState{
+			Name:               "myapp_v2.web.1.service",
+			MachineID:          "654321",
+			SystemdActiveState: "active",
+			SystemdSubState:    "running",
+		},
+	}
+
+	testMachines := []machine.MachineState{
+		machine.MachineState{ID: "654321", PublicIP: "2.2.2.2"},
+	}
+
+	// deis-router@2.service is installed, but fleet has not scheduled it
+	testUnits := []*schema.Unit{
+		&schema.Unit{Name: "deis-router@1.service", MachineID: "654321", CurrentState: "launched"},
+		&schema.Unit{Name: "deis-router@2.service", CurrentState: "inactive"},
+	}
+
+	c := &FleetClient{Fleet: &stubFleetClient{testUnits: testUnits, testUnitStates: testUnitStates,
+		testMachineStates: testMachines, unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}}
+
+	states, err := c.UnitStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []backend.UnitState{
+		{Name: "deis-router@1.service", Active: "failed", Sub: "failed", Host: "2.2.2.2"},
+		{Name: "deis-router@2.service", Active: "inactive", Sub: "unscheduled"},
+	}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("Expected %v, Got %v", expected, states)
+	}
+}
+
+type mockShowCommandRunner struct {
+	output string
+}
+
+func (mockShowCommandRunner) LocalCommand(string, io.Writer) (int, error) {
+	return 0, nil
+}
+
+func (m mockShowCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration, stdout io.Writer) (int, error) {
+	expected := "systemctl show -p Id -p ActiveEnterTimestamp deis-router@1.service deis-router@2.service"
+	if addr != "2.2.2.2" || cmd != expected {
+		return -1, fmt.Errorf("Got %s %s, which is unexpected", cmd, addr)
+	}
+	io.WriteString(stdout, m.output)
+	return 0, nil
+}
+
+func TestActiveSince(t *testing.T) {
+	t.Parallel()
+
+	testUnitStates := []*schema.UnitState{
+		&schema.UnitState{
+			Name:               "deis-router@1.service",
+			MachineID:          "654321",
+			SystemdActiveState: "active",
+			SystemdSubState:    "running",
+		},
+		&schema.UnitState{
+			Name:               "deis-router@2.service",
+			MachineID:          "654321",
+			SystemdActiveState: "active",
+			SystemdSubState:    "running",
+		},
+	}
+
+	testMachines := []machine.MachineState{
+		machine.MachineState{ID: "654321", PublicIP: "2.2.2.2"},
+	}
+
+	output := "ActiveEnterTimestamp=Sun 2026-10-18 10:00:00 UTC\nId=deis-router@1.service\n\n" +
+		"ActiveEnterTimestamp=\nId=deis-router@2.service\n"
+	var ew bytes.Buffer
+	c := &FleetClient{Fleet: &stubFleetClient{testUnitStates: testUnitStates,
+		testMachineStates: testMachines, unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}},
+		runner: mockShowCommandRunner{output: output}, errWriter: &ew}
+
+	states, err := c.UnitStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// listing units does not reach the machines
+	for _, s := range states {
+		if !s.Since.IsZero() {
+			t.Errorf("Expected no uptime for %s, Got %v", s.Name, s.Since)
+		}
+	}
+
+	states, err = c.ActiveSince(states)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ew.String() != "" {
+		t.Fatal(ew.String())
+	}
+
+	since := []time.Time{time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC), time.Time{}}
+	var got []time.Time
+	for _, s := range states {
+		got = append(got, s.Since.UTC())
+	}
+	if len(got) != 2 || !got[0].Equal(since[0]) || !got[1].IsZero() {
+		t.Errorf("Expected %v, Got %v", since, got)
+	}
+}
+
+func TestUnitNames(t *testing.T) {
+	t.Parallel()
+
+	name, err := ioutil.TempDir("", "deisctl-fleetctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(name)
+	manifest := path.Join(name, units.ManifestFile)
+	ioutil.WriteFile(manifest, []byte("components:\n- name: metrics\n"), 0644)
+
+	c := &FleetClient{templatePaths: []string{name}}
+	expected := append(append([]string(nil), units.Names...), "deis-metrics")
+	if names := c.unitNames(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, Got %v", expected, names)
+	}
+	// the manifest is read once per client
+	os.Remove(manifest)
+	if names := c.unitNames(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, Got %v", expected, names)
+	}
+
+	// a malformed manifest leaves the custom components out
+	ioutil.WriteFile(manifest, []byte("components: [\n"), 0644)
+	c = &FleetClient{templatePaths: []string{name}}
+	if names := c.unitNames(); !reflect.DeepEqual(names, units.Names) {
+		t.Errorf("Expected %v, Got %v", units.Names, names)
+	}
+}
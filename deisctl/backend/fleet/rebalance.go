@@ -0,0 +1,216 @@
+package fleet
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/coreos/fleet/schema"
+	"github.com/deis/deis/deisctl/backend"
+)
+
+// move relocates an instance of a component from one machine to another.
+type move struct {
+	num      int
+	from, to string
+}
+
+// Rebalance spreads the instances of a component evenly across the machines matching the
+// MachineMetadata of its unit. Instances are moved one at a time from the busiest machine
+// to the idlest, and a move must be running before the next begins.
+func (c *FleetClient) Rebalance(component string, out io.Writer) error {
+	component = strings.TrimPrefix(strings.TrimSuffix(component, "@*"), "deis-")
+	nums, err := c.instanceNums(component)
+	if err != nil {
+		return err
+	}
+	if len(nums) == 1 && nums[0] == 0 {
+		return fmt.Errorf("%s is not scalable, so there is nothing to rebalance", component)
+	}
+
+	machines, err := c.Machines()
+	if err != nil {
+		return err
+	}
+	filter, err := c.placementFilter(component)
+	if err != nil {
+		return err
+	}
+	eligible := backend.FilterMachines(machines, filter)
+	if len(eligible) == 0 {
+		return fmt.Errorf("no machines match the metadata of %s: %s", component, filter)
+	}
+
+	placement, err := c.placement(component, nums)
+	if err != nil {
+		return err
+	}
+	moves := planMoves(nums, placement, eligible)
+	if len(moves) == 0 {
+		fmt.Fprintf(out, "%s is already balanced across %d machines\n", component, len(eligible))
+		return nil
+	}
+
+	names := make(map[string]string)
+	for _, m := range machines {
+		names[m.ID] = machineName(m)
+	}
+	for _, m := range moves {
+		from, ok := names[m.from]
+		if !ok {
+			// the machine has left the cluster
+			from = m.from
+		}
+		fmt.Fprintf(out, "moving %s from %s to %s\n", instanceTarget(component, m.num), from, names[m.to])
+		if err := c.moveInstance(component, m, placement, out); err != nil {
+			return fmt.Errorf("rebalance of %s aborted: %v", component, err)
+		}
+	}
+	return nil
+}
+
+// placementFilter returns the MachineMetadata of the unit a component's instances are
+// created from, which selects the machines they may run on.
+func (c *FleetClient) placementFilter(component string) (backend.MetadataFilter, error) {
+	_, uf, err := c.createServiceUnit(component, 1)
+	if err != nil {
+		return nil, err
+	}
+	return backend.ParseMetadataFilter(strings.Join(uf.Contents["X-Fleet"]["MachineMetadata"], ","))
+}
+
+// placement maps the instances of a component to the IDs of the machines running them.
+// Instances fleet has not scheduled yet are left out.
+func (c *FleetClient) placement(component string, nums []int) (map[int]string, error) {
+	states, err := c.Fleet.UnitStates()
+	if err != nil {
+		return nil, err
+	}
+	placement := make(map[int]string)
+	for _, num := range nums {
+		name, err := formatUnitName(component, num)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range states {
+			if s.Name == name && s.MachineID != "" {
+				placement[num] = s.MachineID
+			}
+		}
+	}
+	return placement, nil
+}
+
+// planMoves returns the moves which leave the instances of a component on the eligible
+// machines, with no machine running more than one instance above any other. Instances on
+// other machines are moved first, then the busiest machine gives an instance to the idlest
+// until the load is even.
+func planMoves(nums []int, placement map[int]string, eligible []backend.Machine) []move {
+	load := make(map[string][]int)
+	for _, m := range eligible {
+		load[m.ID] = nil
+	}
+	var strays []int
+	for _, num := range nums {
+		id, ok := placement[num]
+		if !ok {
+			continue
+		}
+		if _, ok := load[id]; ok {
+			load[id] = append(load[id], num)
+		} else {
+			strays = append(strays, num)
+		}
+	}
+
+	// ties go to the first machine, by ID
+	busiest := func() string {
+		id := eligible[0].ID
+		for _, m := range eligible {
+			if len(load[m.ID]) > len(load[id]) {
+				id = m.ID
+			}
+		}
+		return id
+	}
+	idlest := func() string {
+		id := eligible[0].ID
+		for _, m := range eligible {
+			if len(load[m.ID]) < len(load[id]) {
+				id = m.ID
+			}
+		}
+		return id
+	}
+
+	var moves []move
+	for _, num := range strays {
+		to := idlest()
+		moves = append(moves, move{num, placement[num], to})
+		load[to] = append(load[to], num)
+	}
+	for {
+		from, to := busiest(), idlest()
+		if len(load[from])-len(load[to]) <= 1 {
+			return moves
+		}
+		// move the newest instance, leaving the first instances where they are
+		last := len(load[from]) - 1
+		num := load[from][last]
+		load[from] = load[from][:last]
+		load[to] = append(load[to], num)
+		moves = append(moves, move{num, from, to})
+	}
+}
+
+// moveInstance recreates an instance away from the machines running its siblings and waits
+// for it to run. Rather than pinning the instance to the machine it is moved to, which
+// would keep fleet from rescheduling it if that machine left the cluster, it conflicts with
+// the siblings on every other machine and keeps the MachineMetadata of its unit. Any
+// machine fleet may then choose is as idle as the one planned, and placement is updated
+// with the machine it chose.
+func (c *FleetClient) moveInstance(component string, m move, placement map[int]string, out io.Writer) error {
+	target := instanceTarget(component, m.num)
+
+	if err := c.removeUnits([]string{target}, out); err != nil {
+		return err
+	}
+
+	name, uf, err := c.createUnitFile(target)
+	if err != nil {
+		return err
+	}
+	unit := &schema.Unit{
+		Name:    name,
+		Options: schema.MapUnitFileToSchemaUnitOptions(uf),
+	}
+	for num, id := range placement {
+		if num == m.num || id == m.to {
+			continue
+		}
+		sibling, err := formatUnitName(component, num)
+		if err != nil {
+			return err
+		}
+		unit.Options = append(unit.Options, &schema.UnitOption{Section: "X-Fleet", Name: "Conflicts", Value: sibling})
+	}
+	if err := doCreate(c, unit, out); err != nil {
+		return err
+	}
+	if err := c.Start([]string{target}, out); err != nil {
+		return err
+	}
+	if err := c.waitForHealthy(component, m.num, backend.RollingRestartOptions{Timeout: defaultHealthTimeout}); err != nil {
+		return err
+	}
+
+	state, err := c.unitState(name)
+	if err != nil {
+		return err
+	}
+	placement[m.num] = m.to
+	if state != nil && state.MachineID != "" {
+		placement[m.num] = state.MachineID
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package fleet
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/coreos/fleet/machine"
+	"github.com/deis/deis/deisctl/backend"
+)
+
+func TestPlanMoves(t *testing.T) {
+	t.Parallel()
+
+	eligible := []backend.Machine{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	for _, test := range []struct {
+		placement map[int]string
+		expected  []move
+	}{
+		// already even
+		{map[int]string{1: "a", 2: "b", 3: "c", 4: "a"}, nil},
+		// every instance on one machine after the others rebooted
+		{map[int]string{1: "a", 2: "a", 3: "a", 4: "a"}, []move{{4, "a", "b"}, {3, "a", "c"}}},
+		// instances on a machine which no longer matches are moved first
+		{map[int]string{1: "x", 2: "a", 3: "a", 4: "b"}, []move{{1, "x", "c"}}},
+		// unscheduled instances are left to fleet
+		{map[int]string{1: "a", 2: "a"}, []move{{2, "a", "b"}}},
+	} {
+		moves := planMoves([]int{1, 2, 3, 4}, test.placement, eligible)
+		if !reflect.DeepEqual(moves, test.expected) {
+			t.Errorf("Expected %v, Got %v", test.expected, moves)
+		}
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	t.Parallel()
+
+	units, states := runningUnits("deis-router@1.service", "deis-router@2.service", "deis-router@3.service")
+	for _, s := range states {
+		s.MachineID = "a"
+	}
+	testFleetClient := &stubFleetClient{testUnits: units, testUnitStates: states,
+		testMachineStates: []machine.MachineState{
+			{ID: "a", PublicIP: "10.0.0.1"}, {ID: "b", PublicIP: "10.0.0.2"}, {ID: "c", PublicIP: "10.0.0.3"},
+		},
+		unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}
+	c := newRollingRestartClient(t, testFleetClient)
+
+	se := newOutErr()
+	if err := c.Rebalance("router", se.out); err != nil {
+		t.Fatal(err)
+	}
+
+	// moved instances avoid the siblings on other machines rather than being pinned
+	conflicts := make(map[string][]string)
+	for _, u := range testFleetClient.testUnits {
+		for _, opt := range u.Options {
+			if opt.Section == "X-Fleet" && opt.Name == "MachineID" {
+				t.Errorf("Expected %s not to be pinned to %s", u.Name, opt.Value)
+			}
+			if opt.Section == "X-Fleet" && opt.Name == "Conflicts" {
+				conflicts[u.Name] = append(conflicts[u.Name], opt.Value)
+			}
+		}
+		sort.Strings(conflicts[u.Name])
+	}
+	expected := map[string][]string{
+		"deis-router@3.service": {"deis-router@1.service", "deis-router@2.service"},
+		"deis-router@2.service": {"deis-router@1.service", "deis-router@3.service"},
+	}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("Expected %v, Got %v", expected, conflicts)
+	}
+}
+
+func TestRebalanceSingleton(t *testing.T) {
+	t.Parallel()
+
+	units, states := runningUnits("deis-controller.service")
+	testFleetClient := &stubFleetClient{testUnits: units, testUnitStates: states,
+		unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}
+	c := newRollingRestartClient(t, testFleetClient)
+
+	se := newOutErr()
+	err := c.Rebalance("controller", se.out)
+
+	expected := "controller is not scalable, so there is nothing to rebalance"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected '%s', Got '%v'", expected, err)
+	}
+}
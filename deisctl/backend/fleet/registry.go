@@ -9,7 +9,6 @@ import (
 
 	"github.com/coreos/fleet/client"
 	"github.com/coreos/fleet/etcd"
-	"github.com/coreos/fleet/pkg"
 	"github.com/coreos/fleet/registry"
 	"github.com/coreos/fleet/ssh"
 )
@@ -19,6 +18,7 @@ var Flags = struct {
 	Debug                 bool
 	Version               bool
 	Endpoint              string
+	DiscoverySRV          string
 	EtcdKeyPrefix         string
 	EtcdKeyFile           string
 	EtcdCertFile          string
@@ -29,6 +29,7 @@ var Flags = struct {
 	Tunnel                string
 	RequestTimeout        float64
 	SSHTimeout            float64
+	UnitTimeout           float64
 }{}
 
 const (
@@ -79,7 +80,11 @@ func getRegistryClient() (client.API, error) {
 		}
 	}
 
-	tlsConfig, err := pkg.ReadTLSConfigFiles(Flags.EtcdCAFile, Flags.EtcdCertFile, Flags.EtcdKeyFile)
+	machines, err := Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := TLSConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +95,6 @@ func getRegistryClient() (client.API, error) {
 	}
 
 	timeout := time.Duration(Flags.RequestTimeout*1000) * time.Millisecond
-	machines := []string{Flags.Endpoint}
 	eClient, err := etcd.NewClient(machines, trans, timeout)
 	if err != nil {
 		return nil, err
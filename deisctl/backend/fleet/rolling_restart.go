@@ -1,37 +1,271 @@
 package fleet
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"sync"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/fleet/schema"
+	"github.com/deis/deis/deisctl/backend"
 )
 
-// RollingRestart for instance units
-func (c *FleetClient) RollingRestart(component string, wg *sync.WaitGroup, out, ew io.Writer) {
-	if component != "router" {
-		fmt.Fprint(ew, "invalid component. supported for: router")
-		return
+// defaultHealthTimeout matches the TimeoutStartSec of the platform units, which
+// includes pulling the component's image.
+const defaultHealthTimeout = 20 * time.Minute
+
+// RollingRestart replaces the instances of a component a few at a time, waiting for every
+// replacement to run and report healthy before moving on, and aborting on the first failure.
+func (c *FleetClient) RollingRestart(component string, opts backend.RollingRestartOptions, out io.Writer) error {
+	if opts.MaxUnavailable < 0 {
+		return errors.New("max unavailable cannot be below 0")
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultHealthTimeout
 	}
 
-	components, err := c.Units(component)
+	component = strings.TrimPrefix(strings.TrimSuffix(component, "@*"), "deis-")
+	nums, err := c.instanceNums(component)
 	if err != nil {
-		io.WriteString(ew, err.Error())
-		return
+		return err
+	}
+
+	// singletons have a single unit without an instance number
+	if len(nums) == 1 && nums[0] == 0 {
+		restart := c.replaceInstances
+		if opts.MaxUnavailable == 0 {
+			restart = c.surgeSingleton
+		}
+		if err := restart(component, nums, opts, out); err != nil {
+			return fmt.Errorf("rolling restart of %s aborted: %v", component, err)
+		}
+		return nil
 	}
-	if len(components) < 1 {
-		fmt.Fprint(ew, "rolling restart requires at least 1 component")
-		return
+
+	if opts.MaxUnavailable == 0 {
+		for _, num := range nums {
+			if err := c.surgeInstance(component, num, opts, out); err != nil {
+				return fmt.Errorf("rolling restart of %s aborted: %v", component, err)
+			}
+		}
+		return nil
 	}
-	for num := range components {
-		unitName := fmt.Sprintf("%s@%v", component, num+1)
 
-		c.Stop([]string{unitName}, wg, out, ew)
-		wg.Wait()
-		c.Destroy([]string{unitName}, wg, out, ew)
-		wg.Wait()
-		c.Create([]string{unitName}, wg, out, ew)
-		wg.Wait()
-		c.Start([]string{unitName}, wg, out, ew)
-		wg.Wait()
+	for len(nums) > 0 {
+		batch := nums
+		if len(batch) > opts.MaxUnavailable {
+			batch = nums[:opts.MaxUnavailable]
+		}
+		nums = nums[len(batch):]
+
+		if err := c.replaceInstances(component, batch, opts, out); err != nil {
+			return fmt.Errorf("rolling restart of %s aborted: %v", component, err)
+		}
+	}
+	return nil
+}
+
+// instanceNums returns the sorted instance numbers of a component's units, or a single
+// zero for a singleton component.
+func (c *FleetClient) instanceNums(component string) ([]int, error) {
+	units, err := c.Units("deis-" + component)
+	if err != nil {
+		return nil, err
+	}
+
+	var nums []int
+	for _, u := range units {
+		name, num, err := splitTarget(strings.TrimPrefix(u, "deis-"))
+		// skip units which merely share the component's prefix
+		if err != nil || name != component {
+			continue
+		}
+		nums = append(nums, num)
+	}
+	if len(nums) == 0 {
+		return nil, fmt.Errorf("could not find unit: %s", component)
+	}
+	sort.Ints(nums)
+	return nums, nil
+}
+
+// replaceInstances recreates a batch of instances in place and waits for them to become healthy.
+func (c *FleetClient) replaceInstances(component string, nums []int, opts backend.RollingRestartOptions,
+	out io.Writer) error {
+
+	targets := make([]string, len(nums))
+	for i, num := range nums {
+		targets[i] = instanceTarget(component, num)
+	}
+
+	for _, op := range []func([]string, io.Writer) error{c.Stop, c.Destroy, c.Create, c.Start} {
+		if err := op(targets, out); err != nil {
+			return err
+		}
+	}
+
+	for _, num := range nums {
+		if err := c.waitForHealthy(component, num, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// surgeInstance restarts an instance without reducing the serving instances, by first
+// starting a temporary instance under the next free instance number, then restarting the
+// instance in place while the temporary one serves, and finally removing the temporary
+// one. The component keeps its instance numbers, so scaling it later finds no gaps.
+func (c *FleetClient) surgeInstance(component string, num int, opts backend.RollingRestartOptions,
+	out io.Writer) error {
+
+	next, err := c.nextUnit(component)
+	if err != nil {
+		return err
+	}
+	surge := instanceTarget(component, next)
+
+	err = c.Create([]string{surge}, out)
+	if err == nil {
+		err = c.Start([]string{surge}, out)
+	}
+	if err == nil {
+		err = c.waitForHealthy(component, next, opts)
+	}
+	if err != nil {
+		c.removeUnits([]string{surge}, out)
+		return err
+	}
+
+	if err := c.replaceInstances(component, []int{num}, opts, out); err != nil {
+		return fmt.Errorf("%v (deis-%s.service is still serving in its place)", err, surge)
+	}
+	return c.removeUnits([]string{surge}, out)
+}
+
+// surgeSingleton restarts a singleton without downtime by first starting a temporary copy
+// of it on another machine, then restarting the original in place while the copy serves,
+// and finally removing the copy.
+func (c *FleetClient) surgeSingleton(component string, nums []int, opts backend.RollingRestartOptions,
+	out io.Writer) error {
+
+	name, uf, err := c.createServiceUnit(component, 0)
+	if err != nil {
+		return err
+	}
+	surge := component + "-surge"
+	surgeName, err := formatUnitName(surge, 0)
+	if err != nil {
+		return err
+	}
+
+	// the copy usually binds the same host ports, so keep it away from the original
+	options := append(schema.MapUnitFileToSchemaUnitOptions(uf),
+		&schema.UnitOption{Section: "X-Fleet", Name: "Conflicts", Value: name})
+
+	if err := doCreate(c, &schema.Unit{Name: surgeName, Options: options}, out); err != nil {
+		return err
+	}
+	err = c.Start([]string{surge}, out)
+	if err == nil {
+		err = c.waitForUnit(surgeName, healthKey(opts, 0), opts.Timeout)
+	}
+	if err != nil {
+		c.removeUnits([]string{surge}, out)
+		return err
+	}
+
+	if err := c.replaceInstances(component, nums, opts, out); err != nil {
+		return fmt.Errorf("%v (%s is still serving in its place)", err, surgeName)
+	}
+	return c.removeUnits([]string{surge}, out)
+}
+
+// removeUnits stops and destroys units.
+func (c *FleetClient) removeUnits(targets []string, out io.Writer) error {
+	if err := c.Stop(targets, out); err != nil {
+		return err
+	}
+	return c.Destroy(targets, out)
+}
+
+// healthKey returns the health key of an instance, or "" if none was given.
+func healthKey(opts backend.RollingRestartOptions, num int) string {
+	if opts.HealthKey == "" {
+		return ""
+	}
+	return strings.Replace(opts.HealthKey, "{instance}", strconv.Itoa(num), -1)
+}
+
+// waitForHealthy waits for an instance to be running and, if a health key is given,
+// for the key to be published.
+func (c *FleetClient) waitForHealthy(component string, num int, opts backend.RollingRestartOptions) error {
+	name, err := formatUnitName(component, num)
+	if err != nil {
+		return err
+	}
+	return c.waitForUnit(name, healthKey(opts, num), opts.Timeout)
+}
+
+// waitForUnit waits for a unit to be running and, if healthKey is not empty, for the key
+// to be published.
+func (c *FleetClient) waitForUnit(name, healthKey string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for {
+		// errors polling fleet are retried until the unit times out
+		state, err := c.unitState(name)
+		if err != nil {
+			lastErr = err
+		}
+
+		switch {
+		case err != nil, state == nil:
+		case state.SystemdSubState == "failed":
+			return fmt.Errorf("%s failed while starting%s", name, c.journalTail(state))
+		case state.SystemdSubState == "running" && healthKey == "":
+			return nil
+		case state.SystemdSubState == "running":
+			if _, err := c.configBackend.Get(healthKey); err == nil {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if lastErr != nil && state == nil {
+				return fmt.Errorf("%s did not start within %v (last error: %v)", name, timeout, lastErr)
+			}
+			if healthKey != "" {
+				return fmt.Errorf("%s did not publish %s within %v", name, healthKey, timeout)
+			}
+			return fmt.Errorf("%s did not start within %v", name, timeout)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// unitState returns the current state of a unit, or nil if fleet has not reported it yet.
+func (c *FleetClient) unitState(name string) (*schema.UnitState, error) {
+	states, err := c.Fleet.UnitStates()
+	if err != nil {
+		return nil, err
+	}
+
+	// FIXME: fleet UnitStates API forces us to iterate for now
+	for _, s := range states {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func instanceTarget(component string, num int) string {
+	if num == 0 {
+		return component
 	}
+	return component + "@" + strconv.Itoa(num)
 }
@@ -0,0 +1,194 @@
+package fleet
+
+import (
+	"io/ioutil"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/config/model"
+	"github.com/deis/deis/deisctl/test/mock"
+
+	"github.com/coreos/fleet/schema"
+)
+
+var colorCodes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func newRollingRestartClient(t *testing.T, fleet *stubFleetClient, nodes ...*model.ConfigNode) *FleetClient {
+	name, err := ioutil.TempDir("", "deisctl-fleetctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, unit := range []string{"deis-router.service", "deis-controller.service"} {
+		ioutil.WriteFile(path.Join(name, unit), []byte("[Unit]"), 777)
+	}
+
+	nodes = append(nodes, &model.ConfigNode{Key: "/deis/platform/enablePlacementOptions", Value: "false"})
+	return &FleetClient{templatePaths: []string{name}, Fleet: fleet,
+		configBackend: mock.ConfigBackend{Expected: nodes}}
+}
+
+func runningUnits(names ...string) ([]*schema.Unit, []*schema.UnitState) {
+	var units []*schema.Unit
+	var states []*schema.UnitState
+	for _, name := range names {
+		units = append(units, &schema.Unit{Name: name, DesiredState: "launched"})
+		states = append(states, &schema.UnitState{Name: name, SystemdActiveState: "active",
+			SystemdSubState: "running"})
+	}
+	return units, states
+}
+
+func unitNames(units []*schema.Unit) []string {
+	var names []string
+	for _, u := range units {
+		names = append(names, u.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRollingRestart(t *testing.T) {
+	t.Parallel()
+
+	units, states := runningUnits("deis-router@1.service", "deis-router@2.service", "deis-router@3.service")
+	testFleetClient := &stubFleetClient{testUnits: units, testUnitStates: states,
+		unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}
+	c := newRollingRestartClient(t, testFleetClient)
+
+	se := newOutErr()
+	if err := c.RollingRestart("router", backend.RollingRestartOptions{MaxUnavailable: 2}, se.out); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"deis-router@1.service", "deis-router@2.service", "deis-router@3.service"}
+	if got := unitNames(testFleetClient.testUnits); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, Got %v", expected, got)
+	}
+	for _, state := range testFleetClient.testUnitStates {
+		if state.SystemdSubState != "running" {
+			t.Errorf("Unit %s is %s, expected running", state.Name, state.SystemdSubState)
+		}
+	}
+}
+
+func TestRollingRestartSurge(t *testing.T) {
+	t.Parallel()
+
+	units, states := runningUnits("deis-router@1.service", "deis-router@2.service")
+	testFleetClient := &stubFleetClient{testUnits: units, testUnitStates: states,
+		unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}
+	c := newRollingRestartClient(t, testFleetClient)
+
+	se := newOutErr()
+	if err := c.RollingRestart("router", backend.RollingRestartOptions{}, se.out); err != nil {
+		t.Fatal(err)
+	}
+
+	// router@3 served while each router restarted in place, and was removed after
+	expected := []string{"deis-router@1.service", "deis-router@2.service"}
+	if got := unitNames(testFleetClient.testUnits); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, Got %v", expected, got)
+	}
+	var created []string
+	for _, line := range strings.Split(strings.TrimSpace(se.out.String()), "\n") {
+		if strings.Contains(line, "loaded") {
+			created = append(created, strings.Fields(colorCodes.ReplaceAllString(line, ""))[0])
+		}
+	}
+	expectedCreated := []string{"deis-router@3.service:", "deis-router@1.service:",
+		"deis-router@3.service:", "deis-router@2.service:"}
+	if !reflect.DeepEqual(created, expectedCreated) {
+		t.Errorf("Expected %v to be created in turn, Got %v", expectedCreated, created)
+	}
+}
+
+func TestRollingRestartSingletonSurge(t *testing.T) {
+	t.Parallel()
+
+	units, states := runningUnits("deis-controller.service")
+	testFleetClient := &stubFleetClient{testUnits: units, testUnitStates: states,
+		unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}
+	c := newRollingRestartClient(t, testFleetClient)
+
+	se := newOutErr()
+	if err := c.RollingRestart("controller", backend.RollingRestartOptions{}, se.out); err != nil {
+		t.Fatal(err)
+	}
+
+	// the surge copy is started before the controller is replaced, and removed after
+	var steps []string
+	for _, line := range strings.Split(strings.TrimSpace(se.out.String()), "\n") {
+		steps = append(steps, strings.Join(strings.Fields(colorCodes.ReplaceAllString(line, "")), " "))
+	}
+	expectedSteps := []string{
+		"deis-controller-surge.service: loaded",
+		"deis-controller-surge.service: active/running",
+		"deis-controller.service: inactive/dead",
+		"deis-controller.service: destroyed",
+		"deis-controller.service: loaded",
+		"deis-controller.service: active/running",
+		"deis-controller-surge.service: inactive/dead",
+		"deis-controller-surge.service: destroyed",
+	}
+	if !reflect.DeepEqual(steps, expectedSteps) {
+		t.Errorf("Expected %v, Got %v", expectedSteps, steps)
+	}
+
+	expected := []string{"deis-controller.service"}
+	if got := unitNames(testFleetClient.testUnits); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, Got %v", expected, got)
+	}
+}
+
+func TestRollingRestartHealthKey(t *testing.T) {
+	t.Parallel()
+
+	units, states := runningUnits("deis-router@1.service", "deis-router@2.service")
+	testFleetClient := &stubFleetClient{testUnits: units, testUnitStates: states,
+		unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}
+	// only the first router publishes its health key
+	c := newRollingRestartClient(t, testFleetClient,
+		&model.ConfigNode{Key: "/deis/router/1/healthy", Value: "true"})
+
+	se := newOutErr()
+	opts := backend.RollingRestartOptions{MaxUnavailable: 1, HealthKey: "/deis/router/{instance}/healthy",
+		Timeout: 500 * time.Millisecond}
+	err := c.RollingRestart("router", opts, se.out)
+
+	expected := "rolling restart of router aborted: deis-router@2.service did not publish /deis/router/2/healthy"
+	if err == nil || !strings.HasPrefix(err.Error(), expected) {
+		t.Errorf("Expected '%s', Got '%v'", expected, err)
+	}
+}
+
+func TestRollingRestartAbortsOnFailure(t *testing.T) {
+	t.Parallel()
+
+	units, states := runningUnits("deis-router@1.service", "deis-router@2.service")
+	fc := &failingFleetClient{stubFleetClient{testUnits: units, testUnitStates: states,
+		unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}}
+	c := newRollingRestartClient(t, &fc.stubFleetClient)
+	c.Fleet = fc
+
+	se := newOutErr()
+	err := c.RollingRestart("router", backend.RollingRestartOptions{MaxUnavailable: 1}, se.out)
+
+	if err == nil || !strings.Contains(err.Error(), "rolling restart of router aborted") {
+		t.Errorf("Expected the rolling restart to abort, Got '%v'", err)
+	}
+
+	// the second router must not have been touched
+	for _, unit := range fc.testUnits {
+		if unit.Name == "deis-router@2.service" && unit.DesiredState != "launched" {
+			t.Errorf("Expected deis-router@2.service to be left running, Got %s", unit.DesiredState)
+		}
+	}
+}
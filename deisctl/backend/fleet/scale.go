@@ -1,60 +1,130 @@
 package fleet
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"math"
-	"strconv"
+	"sort"
 	"strings"
-	"sync"
-)
 
-// Scale creates or destroys units to match the desired number
-func (c *FleetClient) Scale(
-	component string, requested int, wg *sync.WaitGroup, out, ew io.Writer) {
+	"github.com/coreos/fleet/job"
+	"github.com/coreos/fleet/machine"
+)
 
+// Scale creates or destroys units to match the desired number. Instance numbers freed by
+// earlier operations are reused first, and the highest numbered instances are removed.
+func (c *FleetClient) Scale(component string, requested int, out io.Writer) error {
 	if requested < 0 {
-		fmt.Fprintln(ew, "cannot scale below 0")
-		return
+		return errors.New("cannot scale below 0")
 	}
 	// check how many currently exist
 	components, err := c.Units(component)
 	if err != nil {
 		// skip checking the first time; we just want a tally
 		if !strings.Contains(err.Error(), "could not find unit") {
-			fmt.Fprintln(ew, err.Error())
-			return
+			return err
+		}
+	}
+
+	// a component which ran on every machine is replaced by its instances
+	var instances []int
+	global := false
+	for _, u := range components {
+		switch {
+		case strings.HasPrefix(u, "deis-"+component+"@"):
+			_, num, err := splitJobName(u)
+			if err != nil {
+				return err
+			}
+			instances = append(instances, num)
+		case u == "deis-"+component+".service":
+			global = requested > 0
 		}
 	}
+	sort.Ints(instances)
 
-	timesToScale := int(math.Abs(float64(requested - len(components))))
 	switch {
-	case timesToScale == 0:
-		return
-	case requested-len(components) > 0:
-		c.scaleUp(component, len(components), timesToScale, wg, out, ew)
-	default:
-		c.scaleDown(component, len(components), timesToScale, wg, out, ew)
+	case requested > len(instances):
+		return c.scaleUp(component, instances, requested-len(instances), global, out)
+	case requested < len(instances):
+		return c.scaleDown(component, instances[requested:], out)
 	}
+	return nil
 }
 
-func (c *FleetClient) scaleUp(component string, numExistingContainers, numTimesToScale int,
-	wg *sync.WaitGroup, out, ew io.Writer) {
-	for i := 0; i < numTimesToScale; i++ {
-		target := component + "@" + strconv.Itoa(numExistingContainers+i+1)
-		c.Create([]string{target}, wg, out, ew)
+// scaleUp creates and starts instances of a component under the lowest free instance
+// numbers. replace removes the unit which ran the component on every machine first, as its
+// instances take over.
+func (c *FleetClient) scaleUp(component string, existing []int, numTimesToScale int,
+	replace bool, out io.Writer) error {
+	if err := c.checkPlacement(component, len(existing)+numTimesToScale); err != nil {
+		return err
+	}
+	if replace {
+		if err := c.removeUnits([]string{component}, out); err != nil {
+			return err
+		}
+	}
+	var targets []string
+	for _, num := range freeNums(existing, numTimesToScale) {
+		targets = append(targets, instanceTarget(component, num))
+	}
+	if err := c.Create(targets, out); err != nil {
+		return err
 	}
-	wg.Wait()
-	for i := 0; i < numTimesToScale; i++ {
-		target := component + "@" + strconv.Itoa(numExistingContainers+i+1)
-		c.Start([]string{target}, wg, out, ew)
+	return c.Start(targets, out)
+}
+
+// scaleDown stops the given instances, highest numbered first, before destroying them, so
+// that they shut down cleanly.
+func (c *FleetClient) scaleDown(component string, nums []int, out io.Writer) error {
+	var targets []string
+	for i := len(nums) - 1; i >= 0; i-- {
+		targets = append(targets, instanceTarget(component, nums[i]))
 	}
+	return c.removeUnits(targets, out)
 }
 
-func (c *FleetClient) scaleDown(component string, numExistingContainers, numTimesToScale int,
-	wg *sync.WaitGroup, out, ew io.Writer) {
-	for i := 0; i < numTimesToScale; i++ {
-		target := component + "@" + strconv.Itoa(numExistingContainers-i)
-		c.Destroy([]string{target}, wg, out, ew)
+// checkPlacement returns an error if the machines of the cluster cannot run the requested
+// number of instances of a component, given the MachineMetadata its unit requires and,
+// when its instances conflict with each other, one instance per machine. Instances of a
+// component which otherwise runs on every machine always conflict. Fleet would otherwise
+// leave the extra instances unscheduled.
+func (c *FleetClient) checkPlacement(component string, requested int) error {
+	name, uf, err := c.createServiceUnit(component, 1)
+	if err != nil {
+		return err
+	}
+	j := job.NewJob(name, *uf)
+
+	metadata := j.RequiredTargetMetadata()
+	spread := false
+	for _, conflict := range j.Conflicts() {
+		if strings.HasPrefix(conflict, "deis-"+component+"@") {
+			spread = true
+		}
+	}
+	if len(metadata) == 0 && !spread {
+		return nil
+	}
+
+	machines, err := c.Fleet.Machines()
+	if err != nil {
+		return err
+	}
+	eligible := 0
+	for i := range machines {
+		if machine.HasMetadata(&machines[i], metadata) {
+			eligible++
+		}
+	}
+
+	switch {
+	case eligible == 0:
+		return fmt.Errorf("cannot scale %s: no machine matches its MachineMetadata", component)
+	case spread && requested > eligible:
+		return fmt.Errorf("cannot scale %s to %d: each instance needs its own machine, and only %d machines are eligible",
+			component, requested, eligible)
 	}
+	return nil
 }
@@ -2,14 +2,17 @@ package fleet
 
 import (
 	"io/ioutil"
+	"os"
 	"path"
-	"strings"
+	"reflect"
+	"sort"
 	"sync"
 	"testing"
 
 	"github.com/deis/deis/deisctl/config/model"
 	"github.com/deis/deis/deisctl/test/mock"
 
+	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/schema"
 )
 
@@ -37,22 +40,11 @@ func TestScaleUp(t *testing.T) {
 
 	c := &FleetClient{templatePaths: []string{name}, Fleet: &testFleetClient, configBackend: testConfigBackend}
 
-	var errOutput string
-	var wg sync.WaitGroup
-
-	logMutex := sync.Mutex{}
-
 	se := newOutErr()
 
-	c.Scale("router", 3, &wg, se.out, se.ew)
-
-	wg.Wait()
-
-	logMutex.Lock()
-	if errOutput != "" {
-		t.Fatal(errOutput)
+	if err := c.Scale("router", 3, se.out); err != nil {
+		t.Fatal(err)
 	}
-	logMutex.Unlock()
 
 	expectedUnits := []string{"deis-router@1.service", "deis-router@2.service",
 		"deis-router@3.service"}
@@ -95,21 +87,10 @@ func TestScaleDown(t *testing.T) {
 
 	c := &FleetClient{Fleet: &testFleetClient}
 
-	var errOutput string
-	var wg sync.WaitGroup
-
-	logMutex := sync.Mutex{}
-
 	se := newOutErr()
-	c.Scale("router", 1, &wg, se.out, se.ew)
-
-	wg.Wait()
-
-	logMutex.Lock()
-	if errOutput != "" {
-		t.Fatal(errOutput)
+	if err := c.Scale("router", 1, se.out); err != nil {
+		t.Fatal(err)
 	}
-	logMutex.Unlock()
 
 	expectedUnits := []string{"deis-router@1.service"}
 
@@ -129,27 +110,173 @@ func TestScaleDown(t *testing.T) {
 	}
 }
 
+func TestScaleGaps(t *testing.T) {
+	t.Parallel()
+
+	name, err := ioutil.TempDir("", "deisctl-fleetctl")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ioutil.WriteFile(path.Join(name, "deis-router.service"), []byte("[Unit]"), 777)
+
+	testUnits := []*schema.Unit{
+		&schema.Unit{
+			Name:         "deis-router@1.service",
+			DesiredState: "launched",
+		},
+		&schema.Unit{
+			Name:         "deis-router@3.service",
+			DesiredState: "launched",
+		},
+		&schema.Unit{
+			Name:         "deis-router@4.service",
+			DesiredState: "launched",
+		},
+	}
+
+	testFleetClient := stubFleetClient{testUnits: testUnits, testUnitStates: []*schema.UnitState{}, unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}
+
+	testConfigBackend := mock.ConfigBackend{Expected: []*model.ConfigNode{{Key: "/deis/platform/enablePlacementOptions", Value: "true"}}}
+
+	c := &FleetClient{templatePaths: []string{name}, Fleet: &testFleetClient, configBackend: testConfigBackend}
+
+	names := func() []string {
+		var names []string
+		for _, unit := range testFleetClient.testUnits {
+			names = append(names, unit.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	se := newOutErr()
+	if err := c.Scale("router", 2, se.out); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"deis-router@1.service", "deis-router@3.service"}
+	if got := names(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, Got %v", expected, got)
+	}
+
+	if err := c.Scale("router", 4, se.out); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"deis-router@1.service", "deis-router@2.service", "deis-router@3.service", "deis-router@4.service"}
+	if got := names(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, Got %v", expected, got)
+	}
+}
+
 func TestScaleError(t *testing.T) {
 	t.Parallel()
 
 	c := &FleetClient{Fleet: &stubFleetClient{}}
 
-	var errOutput string
-	var wg sync.WaitGroup
+	se := newOutErr()
+	err := c.Scale("router", -1, se.out)
+
+	expected := "cannot scale below 0"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected '%s', Got '%v'", expected, err)
+	}
+}
+
+func TestScaleUpPlacement(t *testing.T) {
+	t.Parallel()
+
+	name, err := ioutil.TempDir("", "deisctl-fleetctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(path.Join(name, "deis-router.service"),
+		[]byte("[X-Fleet]\nConflicts=deis-router@*.service\nMachineMetadata=routerMesh=true\n"), 0644)
+
+	testUnits := []*schema.Unit{
+		&schema.Unit{
+			Name:         "deis-router@1.service",
+			DesiredState: "launched",
+		},
+	}
+	testMachines := []machine.MachineState{
+		{ID: "123", Metadata: map[string]string{"routerMesh": "true"}},
+		{ID: "456", Metadata: map[string]string{"routerMesh": "true"}},
+		{ID: "789", Metadata: map[string]string{"controlPlane": "true"}},
+	}
 
-	logMutex := sync.Mutex{}
+	testFleetClient := stubFleetClient{testUnits: testUnits, testUnitStates: []*schema.UnitState{},
+		testMachineStates: testMachines, unitsMutex: &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}
+	testConfigBackend := mock.ConfigBackend{Expected: []*model.ConfigNode{{Key: "/deis/platform/enablePlacementOptions", Value: "false"}}}
+	c := &FleetClient{templatePaths: []string{name}, Fleet: &testFleetClient, configBackend: testConfigBackend}
 
 	se := newOutErr()
-	c.Scale("router", -1, &wg, se.out, se.ew)
 
-	wg.Wait()
+	err = c.Scale("router", 3, se.out)
 
-	expected := "cannot scale below 0"
-	errOutput = strings.TrimSpace(se.ew.String())
+	expected := "cannot scale router to 3: each instance needs its own machine, and only 2 machines are eligible"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected '%s', Got '%v'", expected, err)
+	}
+	if len(testFleetClient.testUnits) != 1 {
+		t.Errorf("Expected no unit to be created, Got %d units", len(testFleetClient.testUnits))
+	}
 
-	logMutex.Lock()
-	if errOutput != expected {
-		t.Errorf("Expected '%s', Got '%s'", expected, errOutput)
+	se = newOutErr()
+	if err := c.Scale("router", 2, se.out); err != nil {
+		t.Fatal(err)
+	}
+	if len(testFleetClient.testUnits) != 2 {
+		t.Errorf("Expected 2 units, Got %d", len(testFleetClient.testUnits))
+	}
+}
+
+func TestScaleGlobal(t *testing.T) {
+	t.Parallel()
+
+	name, err := ioutil.TempDir("", "deisctl-fleetctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(name)
+	ioutil.WriteFile(path.Join(name, "deis-logspout@.service"), []byte("[X-Fleet]\nGlobal=true\n"), 0644)
+
+	units, states := runningUnits("deis-logspout.service")
+	testFleetClient := stubFleetClient{testUnits: units, testUnitStates: states,
+		testMachineStates: []machine.MachineState{{ID: "123"}, {ID: "456"}, {ID: "789"}},
+		unitsMutex:        &sync.Mutex{}, unitStatesMutex: &sync.Mutex{}}
+	testConfigBackend := mock.ConfigBackend{Expected: []*model.ConfigNode{{Key: "/deis/platform/enablePlacementOptions", Value: "false"}}}
+	c := &FleetClient{templatePaths: []string{name}, Fleet: &testFleetClient, configBackend: testConfigBackend}
+
+	se := newOutErr()
+	err = c.Scale("logspout", 4, se.out)
+
+	expected := "cannot scale logspout to 4: each instance needs its own machine, and only 3 machines are eligible"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected '%s', Got '%v'", expected, err)
+	}
+
+	se = newOutErr()
+	if err := c.Scale("logspout", 2, se.out); err != nil {
+		t.Fatal(err)
+	}
+	// the instances replace the unit which ran on every machine, one per machine
+	for _, u := range testFleetClient.testUnits {
+		if suToGlobal(*u) {
+			t.Errorf("Expected %s not to be global", u.Name)
+		}
+		conflicts := false
+		for _, opt := range u.Options {
+			if opt.Section == "X-Fleet" && opt.Name == "Conflicts" && opt.Value == "deis-logspout@*.service" {
+				conflicts = true
+			}
+		}
+		if !conflicts {
+			t.Errorf("Expected %s to conflict with the other instances", u.Name)
+		}
+	}
+	names := unitNames(testFleetClient.testUnits)
+	if expected := []string{"deis-logspout@1.service", "deis-logspout@2.service"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, Got %v", expected, names)
 	}
-	logMutex.Unlock()
 }
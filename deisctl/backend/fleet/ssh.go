@@ -2,14 +2,15 @@ package fleet
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/ssh"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 // SSH opens an interactive shell to a machine in the cluster
@@ -77,12 +78,12 @@ func (c *FleetClient) sshConnect(name string) (*ssh.SSHForwardingClient, *machin
 	return sshClient, ms, err
 }
 
-// runCommand will attempt to run a command on a given machine. It will attempt
-// to SSH to the machine if it is identified as being remote.
-func (c *FleetClient) runCommand(cmd string, machID string) (retcode int) {
+// runCommand will attempt to run a command on a given machine, writing its output to
+// stdout. It will attempt to SSH to the machine if it is identified as being remote.
+func (c *FleetClient) runCommand(cmd string, machID string, stdout io.Writer) (retcode int) {
 	var err error
 	if machine.IsLocalMachineID(machID) {
-		retcode, err = c.runner.LocalCommand(cmd)
+		retcode, err = c.runner.LocalCommand(cmd, stdout)
 		if err != nil {
 			fmt.Fprintf(c.errWriter, "Error running local command: %v\n", err)
 		}
@@ -90,9 +91,10 @@ func (c *FleetClient) runCommand(cmd string, machID string) (retcode int) {
 		ms, err := c.machineState(machID)
 		if err != nil || ms == nil {
 			fmt.Fprintf(c.errWriter, "Error getting machine IP: %v\n", err)
+			retcode = -1
 		} else {
 			sshTimeout := time.Duration(Flags.SSHTimeout*1000) * time.Millisecond
-			retcode, err = c.runner.RemoteCommand(cmd, ms.PublicIP, sshTimeout)
+			retcode, err = c.runner.RemoteCommand(cmd, ms.PublicIP, sshTimeout, stdout)
 			if err != nil {
 				fmt.Fprintf(c.errWriter, "Error running remote command: %v\n", err)
 			}
@@ -102,18 +104,17 @@ func (c *FleetClient) runCommand(cmd string, machID string) (retcode int) {
 }
 
 type commandRunner interface {
-	LocalCommand(string) (int, error)
-	RemoteCommand(string, string, time.Duration) (int, error)
+	LocalCommand(string, io.Writer) (int, error)
+	RemoteCommand(string, string, time.Duration, io.Writer) (int, error)
 }
 
 type sshCommandRunner struct{}
 
 // runLocalCommand runs the given command locally and returns any error encountered and the exit code of the command
-func (sshCommandRunner) LocalCommand(cmd string) (int, error) {
-	cmdSlice := strings.Split(cmd, " ")
-	osCmd := exec.Command(cmdSlice[0], cmdSlice[1:]...)
+func (sshCommandRunner) LocalCommand(cmd string, stdout io.Writer) (int, error) {
+	osCmd := exec.Command("/bin/sh", "-c", cmd)
 	osCmd.Stderr = os.Stderr
-	osCmd.Stdout = os.Stdout
+	osCmd.Stdout = stdout
 	osCmd.Start()
 	err := osCmd.Wait()
 	if err != nil {
@@ -131,7 +132,7 @@ func (sshCommandRunner) LocalCommand(cmd string) (int, error) {
 
 // runRemoteCommand runs the given command over SSH on the given IP, and returns
 // any error encountered and the exit status of the command
-func (sshCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration) (exit int, err error) {
+func (sshCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration, stdout io.Writer) (exit int, err error) {
 	var sshClient *ssh.SSHForwardingClient
 	if tun := getTunnelFlag(); tun != "" {
 		sshClient, err = ssh.NewTunnelledSSHClient("core", tun, addr, getChecker(), false, timeout)
@@ -144,8 +145,23 @@ func (sshCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Dura
 
 	defer sshClient.Close()
 
-	err, exit = ssh.Execute(sshClient, cmd)
-	return
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return -1, err
+	}
+	defer session.Close()
+	session.Stdout = stdout
+	session.Stderr = os.Stderr
+
+	err = session.Run(cmd)
+	// if the session terminated normally, err is an ExitError holding the exit status
+	if exitErr, ok := err.(*gossh.ExitError); ok {
+		return exitErr.ExitStatus(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
 }
 
 // findUnits returns the machine ID of a running unit
@@ -154,10 +170,10 @@ func (c *FleetClient) findUnit(name string) (machID string, err error) {
 	switch {
 	case err != nil:
 		return "", fmt.Errorf("Error retrieving Unit %s: %v", name, err)
-	case suToGlobal(*u):
-		return "", fmt.Errorf("Unable to connect to global unit %s.\n", name)
 	case u == nil:
 		return "", fmt.Errorf("Unit %s does not exist.\n", name)
+	case suToGlobal(*u):
+		return "", fmt.Errorf("Unable to connect to global unit %s.\n", name)
 	case u.CurrentState == "":
 		return "", fmt.Errorf("Unit %s does not appear to be running.\n", name)
 	}
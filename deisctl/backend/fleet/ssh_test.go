@@ -3,6 +3,8 @@ package fleet
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"reflect"
 	"sync"
 	"testing"
@@ -14,11 +16,11 @@ import (
 
 type mockCommandRunner struct{}
 
-func (mockCommandRunner) LocalCommand(string) (int, error) {
+func (mockCommandRunner) LocalCommand(string, io.Writer) (int, error) {
 	return 0, nil
 }
 
-func (mockCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration) (int, error) {
+func (mockCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration, stdout io.Writer) (int, error) {
 	if cmd != "true" || addr != "1.1.1.1" || timeout != 0 {
 		return -1, fmt.Errorf("Got %s %s %d, which is unexpected", cmd, addr, timeout)
 	}
@@ -44,7 +46,7 @@ func TestRunCommand(t *testing.T) {
 	c := &FleetClient{Fleet: &stubFleetClient{testMachineStates: testMachines},
 		runner: mockCommandRunner{}, errWriter: &testWriter}
 
-	code := c.runCommand("true", "test-1")
+	code := c.runCommand("true", "test-1", ioutil.Discard)
 
 	err := testWriter.String()
 	if err != "" || code != 0 {
@@ -69,7 +71,7 @@ func TestFindUnit(t *testing.T) {
 	}
 	c := &FleetClient{Fleet: &stubFleetClient{testUnits: testUnits, unitsMutex: &sync.Mutex{}}}
 
-	expectedErr := "Error retrieving Unit foo: Unit foo not found"
+	expectedErr := "Unit foo does not exist.\n"
 
 	_, err := c.findUnit("foo")
 
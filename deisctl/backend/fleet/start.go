@@ -3,74 +3,77 @@ package fleet
 import (
 	"fmt"
 	"io"
-	"sync"
 	"time"
 
 	"github.com/coreos/fleet/schema"
+	"github.com/deis/deis/deisctl/backend"
 	"github.com/deis/deis/pkg/prettyprint"
 )
 
 // Start units and wait for their desiredState
-func (c *FleetClient) Start(targets []string, wg *sync.WaitGroup, out, ew io.Writer) {
+func (c *FleetClient) Start(targets []string, out io.Writer) error {
 	// expand @* targets
 	expandedTargets, err := c.expandTargets(targets)
 	if err != nil {
-		io.WriteString(ew, err.Error())
-		return
+		return err
 	}
 
-	for _, target := range expandedTargets {
-		wg.Add(1)
-		go doStart(c, target, wg, out, ew)
-	}
-	return
+	return backend.InParallel(len(expandedTargets), func(i int) error {
+		return doStart(c, expandedTargets[i], out)
+	})
 }
 
-func doStart(c *FleetClient, target string, wg *sync.WaitGroup, out, ew io.Writer) {
-	defer wg.Done()
-
+func doStart(c *FleetClient, target string, out io.Writer) error {
 	// prepare string representation
 	component, num, err := splitTarget(target)
 	if err != nil {
-		io.WriteString(ew, err.Error())
-		return
+		return err
 	}
 	name, err := formatUnitName(component, num)
 	if err != nil {
-		io.WriteString(ew, err.Error())
-		return
+		return err
 	}
 
 	requestState := "launched"
 	desiredState := "running"
 
 	if err := c.Fleet.SetUnitTargetState(name, requestState); err != nil {
-		io.WriteString(ew, err.Error())
-		return
+		return err
 	}
 
 	// start with the likely subState to avoid sending it across the channel
 	lastSubState := "dead"
 
+	deadline := c.deadline()
+	var currentState *schema.UnitState
+	var lastErr error
+
 	for {
-		// poll for unit states
+		if expired(deadline) {
+			return c.timedOut(name, "start", currentState, lastErr)
+		}
+
+		// poll for unit states, retrying errors until the unit times out
 		states, err := c.Fleet.UnitStates()
 		if err != nil {
-			io.WriteString(ew, err.Error())
-			return
+			lastErr = err
+			time.Sleep(250 * time.Millisecond)
+			continue
 		}
 
 		// FIXME: fleet UnitStates API forces us to iterate for now
-		var currentState *schema.UnitState
+		currentState = nil
 		for _, s := range states {
 			if name == s.Name {
 				currentState = s
 				break
 			}
 		}
+
+		// fleet has not reported the unit on its machine yet
 		if currentState == nil {
-			fmt.Fprintf(ew, "Could not find unit: %v\n", name)
-			return
+			time.Sleep(250 * time.Millisecond)
+			continue
 		}
 
 		// if subState changed, send it across the output channel
@@ -82,15 +85,17 @@ func doStart(c *FleetClient, target string, wg *sync.WaitGroup, out, ew io.Write
 		// break when desired state is reached
 		if currentState.SystemdSubState == desiredState {
 			fmt.Fprintln(out)
-			return
+			return nil
 		}
 
 		lastSubState = currentState.SystemdSubState
 
 		if lastSubState == "failed" {
-			o := prettyprint.Colorize("{{.Red}}The service '%s' failed while starting.{{.Default}}\n")
-			fmt.Fprintf(ew, o, target)
-			return
+			if tail := c.journalTail(currentState); tail != "" {
+				return fmt.Errorf("The service '%s' failed while starting:%s", target, tail)
+			}
+			return fmt.Errorf("The service '%s' failed while starting. Run `deisctl journal %s` to see why.",
+				target, name)
 		}
 		time.Sleep(250 * time.Millisecond)
 	}
@@ -4,7 +4,9 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/coreos/fleet/machine"
 	"github.com/coreos/fleet/schema"
 )
 
@@ -32,14 +34,13 @@ func TestStart(t *testing.T) {
 	c := &FleetClient{Fleet: &testFleetClient}
 
 	var errOutput string
-	var wg sync.WaitGroup
 
 	logMutex := sync.Mutex{}
 
 	se := newOutErr()
-	c.Start([]string{"controller", "builder", "publisher"}, &wg, se.out, se.ew)
-
-	wg.Wait()
+	if err := c.Start([]string{"controller", "builder", "publisher"}, se.out); err != nil {
+		t.Fatal(err)
+	}
 
 	logMutex.Lock()
 	if errOutput != "" {
@@ -76,15 +77,73 @@ func TestStartFail(t *testing.T) {
 		unitStatesMutex: &sync.Mutex{},
 		unitsMutex:      &sync.Mutex{},
 	}}
-	var wg sync.WaitGroup
 	c := &FleetClient{Fleet: fc}
 
 	var b syncBuffer
-	c.Start([]string{"deis-builder.service"}, &wg, &b, &b)
-	wg.Wait()
+	err := c.Start([]string{"deis-builder.service"}, &b)
+
+	if err == nil || !strings.Contains(err.Error(), "failed while starting") {
+		t.Errorf("Expected failure during start. Got '%v'", err)
+	}
+
+}
+
+func TestStartFailJournal(t *testing.T) {
+	t.Parallel()
+
+	fc := &failingFleetClient{stubFleetClient{
+		testUnits:         startTestUnits,
+		testMachineStates: []machine.MachineState{{ID: "a1b2c3d4", PublicIP: "1.1.1.1"}},
+		unitStatesMutex:   &sync.Mutex{},
+		unitsMutex:        &sync.Mutex{},
+	}}
+	c := &FleetClient{Fleet: fc, runner: mockExecCommandRunner{}}
+
+	var b syncBuffer
+	err := c.Start([]string{"deis-builder.service"}, &b)
+
+	// the failure carries the tail of the unit's journal
+	expected := "The service 'deis-builder.service' failed while starting:\n" +
+		"      journalctl --unit deis-builder.service --no-pager --lines 10 on 1.1.1.1"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected '%s'. Got '%v'", expected, err)
+	}
+}
+
+func TestStartTimeout(t *testing.T) {
+	t.Parallel()
+
+	fc := &stuckFleetClient{stubFleetClient{
+		testUnits:       startTestUnits,
+		unitStatesMutex: &sync.Mutex{},
+		unitsMutex:      &sync.Mutex{},
+	}}
+	c := &FleetClient{Fleet: fc, unitTimeout: 500 * time.Millisecond}
+
+	var b syncBuffer
+	err := c.Start([]string{"deis-builder.service"}, &b)
 
-	if !strings.Contains(b.String(), "failed while starting") {
-		t.Errorf("Expected failure during start. Got '%s'", b.String())
+	expected := "deis-builder.service: timed out after 500ms waiting to start (last state: activating/start-pre)"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected '%s'. Got '%v'", expected, err)
 	}
+}
+
+func TestStartRetry(t *testing.T) {
+	t.Parallel()
 
+	fc := &flakyFleetClient{stubFleetClient{
+		testUnits:       startTestUnits,
+		unitStatesMutex: &sync.Mutex{},
+		unitsMutex:      &sync.Mutex{},
+	}, 2}
+	c := &FleetClient{Fleet: fc}
+
+	var b syncBuffer
+	if err := c.Start([]string{"deis-builder.service"}, &b); err != nil {
+		t.Fatal(err)
+	}
+	if fc.failures != 0 {
+		t.Errorf("Expected both failures to be retried, %d left", fc.failures)
+	}
 }
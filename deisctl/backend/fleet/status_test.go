@@ -3,6 +3,7 @@ package fleet
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"sync"
 	"testing"
 	"time"
@@ -15,11 +16,11 @@ type mockStatusCommandRunner struct {
 	validUnits []string
 }
 
-func (mockStatusCommandRunner) LocalCommand(string) (int, error) {
+func (mockStatusCommandRunner) LocalCommand(string, io.Writer) (int, error) {
 	return 0, nil
 }
 
-func (m mockStatusCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration) (int, error) {
+func (m mockStatusCommandRunner) RemoteCommand(cmd string, addr string, timeout time.Duration, stdout io.Writer) (int, error) {
 	if addr != "1.1.1.1" || timeout != 0 {
 		return -1, fmt.Errorf("Got %s %d, which is unexpected", cmd, addr, timeout)
 	}
@@ -3,76 +3,91 @@ package fleet
 import (
 	"fmt"
 	"io"
-	"sync"
 	"time"
 
 	"github.com/coreos/fleet/schema"
+	"github.com/deis/deis/deisctl/backend"
 	"github.com/deis/deis/pkg/prettyprint"
 )
 
 var stateFmt = prettyprint.Colorize("{{.Yellow}}%v:{{.Default}} %v/%v")
 
+// notInstalledFmt reports a unit which needs no stopping or destroying since fleet does
+// not know it.
+var notInstalledFmt = prettyprint.Colorize("{{.Yellow}}%v:{{.Default}} not installed")
+
 // Stop units and wait for their desiredState
-func (c *FleetClient) Stop(targets []string, wg *sync.WaitGroup, out, ew io.Writer) {
+func (c *FleetClient) Stop(targets []string, out io.Writer) error {
 	// expand @* targets
 	expandedTargets, err := c.expandTargets(targets)
 	if err != nil {
-		fmt.Fprintln(ew, err.Error())
-		return
+		return err
 	}
 
-	for _, target := range expandedTargets {
-		wg.Add(1)
-		go doStop(c, target, wg, out, ew)
-	}
-	return
+	return backend.InParallel(len(expandedTargets), func(i int) error {
+		return doStop(c, expandedTargets[i], out)
+	})
 }
 
-func doStop(c *FleetClient, target string, wg *sync.WaitGroup, out, ew io.Writer) {
-	defer wg.Done()
-
+func doStop(c *FleetClient, target string, out io.Writer) error {
 	// prepare string representation
 	component, num, err := splitTarget(target)
 	if err != nil {
-		fmt.Fprintln(ew, err.Error())
-		return
+		return err
 	}
 	name, err := formatUnitName(component, num)
 	if err != nil {
-		fmt.Fprintln(ew, err.Error())
-		return
+		return err
+	}
+
+	// a unit which was never installed is already stopped
+	if u, err := c.Fleet.Unit(name); err != nil {
+		return err
+	} else if u == nil {
+		fmt.Fprintf(out, notInstalledFmt+"\n", name)
+		return nil
 	}
 
 	requestState := "loaded"
 	desiredState := "dead"
 
 	if err := c.Fleet.SetUnitTargetState(name, requestState); err != nil {
-		fmt.Fprintln(ew, err.Error())
-		return
+		return err
 	}
 
 	// start with the likely subState to avoid sending it across the channel
 	lastSubState := "running"
 
+	deadline := c.deadline()
+	var currentState *schema.UnitState
+	var lastErr error
+
 	for {
-		// poll for unit states
+		if expired(deadline) {
+			return c.timedOut(name, "stop", currentState, lastErr)
+		}
+
+		// poll for unit states, retrying errors until the unit times out
 		states, err := c.Fleet.UnitStates()
 		if err != nil {
-			fmt.Fprintln(ew, err.Error())
-			return
+			lastErr = err
+			time.Sleep(250 * time.Millisecond)
+			continue
 		}
 
 		// FIXME: fleet UnitStates API forces us to iterate for now
-		var currentState *schema.UnitState
+		currentState = nil
 		for _, s := range states {
 			if name == s.Name {
 				currentState = s
 				break
 			}
 		}
+
+		// a unit which is not scheduled on any machine is not running
 		if currentState == nil {
-			fmt.Fprintf(ew, "Could not find unit: %v\n", name)
-			return
+			fmt.Fprintln(out, prettyprint.Overwritef(stateFmt, name, "inactive", "unscheduled"))
+			return nil
 		}
 
 		// if subState changed, send it across the output channel
@@ -84,15 +99,13 @@ func doStop(c *FleetClient, target string, wg *sync.WaitGroup, out, ew io.Writer
 		// break when desired state is reached
 		if currentState.SystemdSubState == desiredState {
 			fmt.Fprintln(out)
-			return
+			return nil
 		}
 
 		lastSubState = currentState.SystemdSubState
 
 		if lastSubState == "failed" {
-			o := prettyprint.Colorize("{{.Red}}The service '%s' failed while stopping.{{.Default}}\n")
-			fmt.Fprintf(ew, o, target)
-			return
+			return fmt.Errorf("The service '%s' failed while stopping.%s", target, c.journalTail(currentState))
 		}
 
 		time.Sleep(250 * time.Millisecond)
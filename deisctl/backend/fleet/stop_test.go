@@ -32,14 +32,13 @@ func TestStop(t *testing.T) {
 	c := &FleetClient{Fleet: &testFleetClient}
 
 	var errOutput string
-	var wg sync.WaitGroup
 
 	logMutex := sync.Mutex{}
 
 	se := newOutErr()
-	c.Stop([]string{"controller", "builder", "publisher"}, &wg, se.out, se.ew)
-
-	wg.Wait()
+	if err := c.Stop([]string{"controller", "builder", "publisher"}, se.out); err != nil {
+		t.Fatal(err)
+	}
 
 	logMutex.Lock()
 	if errOutput != "" {
@@ -91,15 +90,32 @@ func TestStopFail(t *testing.T) {
 		unitStatesMutex: &sync.Mutex{},
 		unitsMutex:      &sync.Mutex{},
 	}}
-	var wg sync.WaitGroup
 	c := &FleetClient{Fleet: fc}
 
 	var b syncBuffer
-	c.Stop([]string{"deis-builder.service"}, &wg, &b, &b)
-	wg.Wait()
+	err := c.Stop([]string{"deis-builder.service"}, &b)
 
-	if !strings.Contains(b.String(), "failed while stopping") {
-		t.Errorf("Expected 'failed while stopping'. Got '%s'", b.String())
+	if err == nil || !strings.Contains(err.Error(), "failed while stopping") {
+		t.Errorf("Expected 'failed while stopping'. Got '%v'", err)
 	}
 
 }
+
+func TestStopNotInstalled(t *testing.T) {
+	t.Parallel()
+
+	fc := &stubFleetClient{
+		testUnits:       stopTestUnits,
+		unitStatesMutex: &sync.Mutex{},
+		unitsMutex:      &sync.Mutex{},
+	}
+	c := &FleetClient{Fleet: fc}
+
+	var b syncBuffer
+	if err := c.Stop([]string{"router@1"}, &b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "not installed") {
+		t.Errorf("Expected 'not installed'. Got '%s'", b.String())
+	}
+}
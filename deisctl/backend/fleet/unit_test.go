@@ -141,7 +141,7 @@ Description=deis-controller`
 
 	ioutil.WriteFile(path.Join(name, unit+".service"), []byte(unitFile), 777)
 
-	uf, err := NewUnit(unit[5:], []string{name}, false)
+	uf, err := NewUnit(unit[5:], []string{name}, false, units.Variables{})
 
 	if err != nil {
 		t.Fatal(err)
@@ -153,45 +153,3 @@ Description=deis-controller`
 		t.Errorf("Expected: %s, Got %s", expected)
 	}
 }
-
-func TestReadTemplate(t *testing.T) {
-	t.Parallel()
-
-	name, err := ioutil.TempDir("", "deisctl-fleetctl")
-	expected := []byte("test")
-
-	if err != nil {
-		t.Error(err)
-	}
-
-	for _, unit := range units.Names {
-		ioutil.WriteFile(path.Join(name, unit+".service"), expected, 777)
-		output, err := readTemplate(unit[5:], []string{name})
-
-		if err != nil {
-			t.Error(err)
-		}
-
-		if string(output) != string(expected) {
-			t.Errorf("Unit %s: Expected %s, Got %s", unit, expected, output)
-		}
-	}
-}
-
-func TestReadTemplateError(t *testing.T) {
-	t.Parallel()
-
-	name, err := ioutil.TempDir("", "deisctl-fleetctl")
-
-	if err != nil {
-		t.Error(err)
-	}
-
-	_, err = readTemplate("foo", []string{name})
-	expected := "Could not find unit template for foo"
-	errorf := err.Error()
-
-	if errorf != expected {
-		t.Errorf("Expected %s, Got %s", expected, errorf)
-	}
-}
@@ -1,6 +1,7 @@
 package fleet
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
 	"regexp"
@@ -8,6 +9,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/coreos/fleet/schema"
+	"github.com/deis/deis/deisctl/utils"
 )
 
 func nextUnitNum(units []string) (num int, err error) {
@@ -26,6 +30,19 @@ func nextUnitNum(units []string) (num int, err error) {
 	return num, nil
 }
 
+// freeNums returns the n lowest instance numbers which are not among the sorted numbers
+// taken.
+func freeNums(taken []int, n int) []int {
+	var nums []int
+	for num := 1; len(nums) < n; num++ {
+		if i := sort.SearchInts(taken, num); i < len(taken) && taken[i] == num {
+			continue
+		}
+		nums = append(nums, num)
+	}
+	return nums
+}
+
 func lastUnitNum(units []string) (num int, err error) {
 	count, err := countUnits(units)
 	if err != nil {
@@ -114,3 +131,53 @@ func randomValue(src []string) string {
 	idx := r.Intn(len(src))
 	return src[idx]
 }
+
+// deadline returns when an operation on a unit which starts now should give up, or the
+// zero time if unit operations may take as long as they need.
+func (c *FleetClient) deadline() time.Time {
+	if c.unitTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(c.unitTimeout)
+}
+
+// expired reports whether a deadline returned by deadline has passed.
+func expired(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
+
+// timedOut describes a unit which did not reach the requested state in time, with the
+// last state fleet reported for it, or the last error polling fleet if it never did.
+func (c *FleetClient) timedOut(name, operation string, state *schema.UnitState, lastErr error) error {
+	last := "last state: not reported"
+	if state != nil {
+		last = "last state: " + state.SystemdActiveState + "/" + state.SystemdSubState
+	} else if lastErr != nil {
+		last = "last error: " + lastErr.Error()
+	}
+	return fmt.Errorf("%s: timed out after %v waiting to %s (%s)%s", name, c.unitTimeout, operation, last,
+		c.journalTail(state))
+}
+
+// failedJournalLines is how many lines of a unit's journal explain why it failed.
+const failedJournalLines = 10
+
+// journalTail returns the last lines of the journal of a unit, indented to follow the
+// failure it explains, or "" if fleet has not placed the unit or the journal cannot be read.
+func (c *FleetClient) journalTail(state *schema.UnitState) string {
+	if state == nil || state.MachineID == "" {
+		return ""
+	}
+	args := []string{"journalctl", "--unit", utils.ShellQuote(state.Name), "--no-pager",
+		"--lines", strconv.Itoa(failedJournalLines)}
+
+	var out bytes.Buffer
+	if exit := c.runCommand(strings.Join(args, " "), state.MachineID, &out); exit != 0 {
+		return ""
+	}
+	tail := strings.TrimSpace(out.String())
+	if tail == "" {
+		return ""
+	}
+	return "\n      " + strings.Replace(tail, "\n", "\n      ", -1)
+}
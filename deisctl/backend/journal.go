@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deis/deis/pkg/prettyprint"
+)
+
+// JournalOptions choose which journal entries Journal prints.
+type JournalOptions struct {
+	// Follow keeps printing entries as they are written, until deisctl is interrupted.
+	Follow bool
+	// Since only prints entries written since a duration ago, such as "10m", or since a
+	// time accepted by journalctl, such as "2016-01-02 15:04:05".
+	Since string
+	// Grep only prints entries whose message matches a regular expression.
+	Grep string
+	// Lines is how many of the latest entries of each unit are printed when Since is
+	// empty, or the backend's default if zero.
+	Lines int
+}
+
+// SinceDuration returns how long ago Since is, if it is a duration.
+func (o JournalOptions) SinceDuration() (time.Duration, bool) {
+	d, err := time.ParseDuration(o.Since)
+	return d, err == nil && d > 0
+}
+
+// JournalctlArgs returns the arguments of journalctl which print the entries chosen by
+// the options as JSON, for ReadJournal.
+func (o JournalOptions) JournalctlArgs(defaultLines int) []string {
+	args := []string{"--no-pager", "--output", "json"}
+	switch d, ok := o.SinceDuration(); {
+	case ok:
+		args = append(args, "--since", fmt.Sprintf("-%ds", int(d.Seconds())))
+	case o.Since != "":
+		args = append(args, "--since", o.Since)
+	case o.Lines > 0:
+		args = append(args, "-n", strconv.Itoa(o.Lines))
+	default:
+		args = append(args, "-n", strconv.Itoa(defaultLines))
+	}
+	if o.Follow {
+		args = append(args, "--follow")
+	}
+	return args
+}
+
+// JournalEntry is a line of the journal of a unit.
+type JournalEntry struct {
+	Time    time.Time
+	Unit    string
+	Message string
+}
+
+// ReadJournal sends the entries of the JSON output of journalctl to a channel until the
+// output ends. Entries are attributed to unit, or to the unit which wrote them if unit is
+// empty.
+func ReadJournal(r io.Reader, unit string, entries chan<- JournalEntry) error {
+	// drain the output even if it cannot be parsed, so that the command writing it exits
+	defer io.Copy(ioutil.Discard, r)
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		fields := struct {
+			Time    string          `json:"__REALTIME_TIMESTAMP"`
+			Unit    string          `json:"_SYSTEMD_UNIT"`
+			Message json.RawMessage `json:"MESSAGE"`
+		}{}
+		if err := json.Unmarshal(line, &fields); err != nil {
+			// lines which are not entries, such as warnings, are printed as they are
+			entries <- JournalEntry{Time: time.Now(), Unit: unit, Message: string(line)}
+			continue
+		}
+
+		entry := JournalEntry{Unit: unit, Message: journalMessage(fields.Message)}
+		if entry.Unit == "" {
+			entry.Unit = fields.Unit
+		}
+		if usec, err := strconv.ParseInt(fields.Time, 10, 64); err == nil {
+			entry.Time = time.Unix(0, usec*int64(time.Microsecond))
+		}
+		entries <- entry
+	}
+}
+
+// journalMessage decodes a MESSAGE field, which journalctl writes as an array of bytes
+// rather than a string when it is not valid UTF-8.
+func journalMessage(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if json.Unmarshal(raw, &ints) == nil {
+		for _, i := range ints {
+			b = append(b, byte(i))
+		}
+	}
+	return string(b)
+}
+
+// reorderWindow is how long a followed entry is held back, so that entries of other
+// units written at about the same time can be printed before it.
+const reorderWindow = 500 * time.Millisecond
+
+// journalColors tell the units of interleaved journals apart.
+var journalColors = []string{"Cyan", "Green", "Yellow", "Blue", "Purple", "Red"}
+
+type pendingEntry struct {
+	entry   JournalEntry
+	arrived time.Time
+}
+
+type byTime []pendingEntry
+
+func (b byTime) Len() int           { return len(b) }
+func (b byTime) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byTime) Less(i, j int) bool { return b[i].entry.Time.Before(b[j].entry.Time) }
+
+// JournalPrinter prints the journals of several units interleaved by time, prefixing each
+// entry with its unit in a color of its own.
+type JournalPrinter struct {
+	out    io.Writer
+	grep   *regexp.Regexp
+	follow bool
+	width  int
+	colors map[string]string
+}
+
+// NewJournalPrinter returns a printer for the journals of units chosen by opts.
+func NewJournalPrinter(out io.Writer, units []string, opts JournalOptions) (*JournalPrinter, error) {
+	p := &JournalPrinter{out: out, follow: opts.Follow, colors: make(map[string]string)}
+	if opts.Grep != "" {
+		grep, err := regexp.Compile(opts.Grep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --grep expression: %v", err)
+		}
+		p.grep = grep
+	}
+
+	sorted := append([]string(nil), units...)
+	sort.Strings(sorted)
+	for i, unit := range sorted {
+		p.colors[unit] = prettyprint.Colors[journalColors[i%len(journalColors)]]
+		if w := len(shortUnitName(unit)); w > p.width {
+			p.width = w
+		}
+	}
+	return p, nil
+}
+
+func shortUnitName(unit string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(unit, "deis-"), ".service")
+}
+
+// Print prints entries until the channel is closed. Unless the journals are followed,
+// every entry is read before any is printed, so that they are fully ordered.
+func (p *JournalPrinter) Print(entries <-chan JournalEntry) {
+	var buffer []pendingEntry
+
+	// flush prints the buffered entries in order, up to the last which arrived before cutoff
+	flush := func(cutoff time.Time) {
+		sort.Stable(byTime(buffer))
+		last := -1
+		for i, e := range buffer {
+			if !e.arrived.After(cutoff) {
+				last = i
+			}
+		}
+		for _, e := range buffer[:last+1] {
+			p.print(e.entry)
+		}
+		buffer = buffer[last+1:]
+	}
+
+	var tick <-chan time.Time
+	if p.follow {
+		ticker := time.NewTicker(reorderWindow / 5)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				flush(time.Now())
+				return
+			}
+			if p.grep == nil || p.grep.MatchString(entry.Message) {
+				buffer = append(buffer, pendingEntry{entry, time.Now()})
+			}
+		case now := <-tick:
+			flush(now.Add(-reorderWindow))
+		}
+	}
+}
+
+func (p *JournalPrinter) print(entry JournalEntry) {
+	color, ok := p.colors[entry.Unit]
+	if !ok {
+		color = prettyprint.Colors["Default"]
+	}
+	fmt.Fprintf(p.out, "%s%-*s |%s %s %s\n", color, p.width, shortUnitName(entry.Unit),
+		prettyprint.Colors["Default"], entry.Time.Format(time.Stamp), entry.Message)
+}
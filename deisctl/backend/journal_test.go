@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deis/deis/pkg/prettyprint"
+)
+
+func TestJournalctlArgs(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		opts     JournalOptions
+		expected string
+	}{
+		{JournalOptions{}, "--no-pager --output json -n 40"},
+		{JournalOptions{Lines: 5, Follow: true}, "--no-pager --output json -n 5 --follow"},
+		{JournalOptions{Since: "10m", Lines: 5}, "--no-pager --output json --since -600s"},
+		{JournalOptions{Since: "yesterday"}, "--no-pager --output json --since yesterday"},
+	} {
+		if args := strings.Join(test.opts.JournalctlArgs(40), " "); args != test.expected {
+			t.Error(fmt.Errorf("Expected %v, Got %v", test.expected, args))
+		}
+	}
+}
+
+func TestReadJournal(t *testing.T) {
+	t.Parallel()
+
+	output := `{"__REALTIME_TIMESTAMP":"1451747045000001","_SYSTEMD_UNIT":"deis-router@1.service","MESSAGE":"started"}
+
+{"__REALTIME_TIMESTAMP":"1451747045000002","_SYSTEMD_UNIT":"deis-router@1.service","MESSAGE":[104,105]}
+Warning: journal has been rotated since unit was started.`
+	entries := make(chan JournalEntry, 10)
+	if err := ReadJournal(strings.NewReader(output), "", entries); err != nil {
+		t.Fatal(err)
+	}
+	close(entries)
+
+	var messages []string
+	for entry := range entries {
+		messages = append(messages, entry.Unit+" "+entry.Message)
+	}
+	expected := []string{
+		"deis-router@1.service started",
+		"deis-router@1.service hi",
+		" Warning: journal has been rotated since unit was started.",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, messages))
+	}
+}
+
+func TestJournalPrinter(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	units := []string{"deis-router@1.service", "deis-controller.service"}
+	p, err := NewJournalPrinter(&b, units, JournalOptions{Grep: "GET|POST"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(usec int) time.Time { return time.Unix(1451747045, int64(usec)*1000) }
+	entries := make(chan JournalEntry, 10)
+	entries <- JournalEntry{at(3), "deis-router@1.service", "GET /v1/apps 200"}
+	entries <- JournalEntry{at(4), "deis-router@1.service", "health check"}
+	entries <- JournalEntry{at(1), "deis-controller.service", "POST /v1/apps"}
+	close(entries)
+	p.Print(entries)
+
+	// entries are ordered by time across units and filtered by --grep
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal(fmt.Errorf("Expected 2 lines, Got %q", b.String()))
+	}
+	expected := prettyprint.Colors["Cyan"] + "controller |" + prettyprint.Colors["Default"] + " " +
+		at(1).Format(time.Stamp) + " POST /v1/apps"
+	if lines[0] != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, lines[0]))
+	}
+	expected = prettyprint.Colors["Green"] + "router@1   |"
+	if !strings.HasPrefix(lines[1], expected) || !strings.HasSuffix(lines[1], "GET /v1/apps 200") {
+		t.Error(fmt.Errorf("Expected %q..., Got %q", expected, lines[1]))
+	}
+
+	if _, err := NewJournalPrinter(&b, units, JournalOptions{Grep: "("}); err == nil {
+		t.Error(fmt.Errorf("Expected an invalid --grep error, Got nil"))
+	}
+}
+
+// lockedBuffer is a bytes.Buffer which may be read while it is written.
+type lockedBuffer struct {
+	bytes.Buffer
+	sync.Mutex
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.String()
+}
+
+func TestJournalPrinterFollow(t *testing.T) {
+	t.Parallel()
+
+	var b lockedBuffer
+	p, err := NewJournalPrinter(&b, []string{"deis-router@1.service"}, JournalOptions{Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// followed entries are printed while the journals are still being read
+	entries := make(chan JournalEntry)
+	done := make(chan struct{})
+	go func() {
+		p.Print(entries)
+		close(done)
+	}()
+	entries <- JournalEntry{time.Now(), "deis-router@1.service", "started"}
+	time.Sleep(2 * reorderWindow)
+	if !strings.Contains(b.String(), "started") {
+		t.Error(fmt.Errorf("Expected the first entry to be printed, Got %q", b.String()))
+	}
+	entries <- JournalEntry{time.Now(), "deis-router@1.service", "stopped"}
+	close(entries)
+	<-done
+
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 2 ||
+		!strings.HasSuffix(lines[0], "started") {
+		t.Error(fmt.Errorf("Expected both entries in order, Got %q", b.String()))
+	}
+}
@@ -0,0 +1,260 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The subset of the Kubernetes API objects used by deisctl.
+
+type objectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Generation  int64             `json:"generation,omitempty"`
+}
+
+type deployment struct {
+	APIVersion string           `json:"apiVersion,omitempty"`
+	Kind       string           `json:"kind,omitempty"`
+	Metadata   objectMeta       `json:"metadata"`
+	Spec       deploymentSpec   `json:"spec"`
+	Status     deploymentStatus `json:"status,omitempty"`
+}
+
+type deploymentSpec struct {
+	Replicas int                    `json:"replicas"`
+	Selector map[string]interface{} `json:"selector,omitempty"`
+	Template podTemplate            `json:"template"`
+}
+
+type podTemplate struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     podSpec    `json:"spec"`
+}
+
+type podSpec struct {
+	Containers []container `json:"containers"`
+	NodeName   string      `json:"nodeName,omitempty"`
+}
+
+type container struct {
+	Name    string   `json:"name"`
+	Image   string   `json:"image"`
+	Command []string `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+}
+
+type deploymentStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	Replicas           int   `json:"replicas,omitempty"`
+	UpdatedReplicas    int   `json:"updatedReplicas,omitempty"`
+	ReadyReplicas      int   `json:"readyReplicas,omitempty"`
+	AvailableReplicas  int   `json:"availableReplicas,omitempty"`
+}
+
+type deploymentList struct {
+	Items []deployment `json:"items"`
+}
+
+type scale struct {
+	APIVersion string     `json:"apiVersion,omitempty"`
+	Kind       string     `json:"kind,omitempty"`
+	Metadata   objectMeta `json:"metadata"`
+	Spec       struct {
+		Replicas int `json:"replicas"`
+	} `json:"spec"`
+}
+
+type pod struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     podSpec    `json:"spec"`
+	Status   podStatus  `json:"status"`
+}
+
+type podStatus struct {
+	Phase             string            `json:"phase"`
+	HostIP            string            `json:"hostIP,omitempty"`
+	StartTime         *time.Time        `json:"startTime,omitempty"`
+	ContainerStatuses []containerStatus `json:"containerStatuses,omitempty"`
+}
+
+type containerStatus struct {
+	Name         string `json:"name"`
+	Ready        bool   `json:"ready"`
+	RestartCount int    `json:"restartCount"`
+	State        struct {
+		Waiting *struct {
+			Reason string `json:"reason"`
+		} `json:"waiting,omitempty"`
+	} `json:"state"`
+}
+
+type podList struct {
+	Items []pod `json:"items"`
+}
+
+// statusError is returned for API responses outside of the 2xx range.
+type statusError struct {
+	Code    int
+	Message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("kubernetes API returned %d: %s", e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	se, ok := err.(*statusError)
+	return ok && se.Code == http.StatusNotFound
+}
+
+func isConflict(err error) bool {
+	se, ok := err.(*statusError)
+	return ok && se.Code == http.StatusConflict
+}
+
+func (c *KubernetesClient) deploymentsPath() string {
+	return fmt.Sprintf("/apis/apps/v1/namespaces/%s/deployments", c.namespace)
+}
+
+func (c *KubernetesClient) deploymentPath(name string) string {
+	return c.deploymentsPath() + "/" + name
+}
+
+func (c *KubernetesClient) podsPath() string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/pods", c.namespace)
+}
+
+// do sends a request to the API server, encoding body as JSON and decoding the response into
+// out unless either is nil.
+func (c *KubernetesClient) do(method, path, contentType string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.endpoint, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newStatusError(res.StatusCode, data)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw = data
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// stream sends a GET request to the API server and returns the response body as it is
+// written, such as the log output of a pod. Unlike do, it does not time out, so that
+// followed log output is not cut off.
+func (c *KubernetesClient) stream(path string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(c.endpoint, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	client := &http.Client{Transport: c.http.Transport}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		data, _ := ioutil.ReadAll(res.Body)
+		return nil, newStatusError(res.StatusCode, data)
+	}
+	return res.Body, nil
+}
+
+// newStatusError returns the failure described by the Status object of a response.
+func newStatusError(code int, data []byte) error {
+	status := struct {
+		Message string `json:"message"`
+	}{}
+	if json.Unmarshal(data, &status) != nil || status.Message == "" {
+		status.Message = strings.TrimSpace(string(data))
+	}
+	return &statusError{Code: code, Message: status.Message}
+}
+
+func (c *KubernetesClient) getDeployment(name string) (*deployment, error) {
+	d := &deployment{}
+	if err := c.do("GET", c.deploymentPath(name), "", nil, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (c *KubernetesClient) patchDeployment(name string, patch interface{}) error {
+	return c.do("PATCH", c.deploymentPath(name), "application/merge-patch+json", patch, nil)
+}
+
+// setReplicas updates a deployment's replicas through its scale subresource.
+func (c *KubernetesClient) setReplicas(name string, replicas int) error {
+	s := &scale{}
+	if err := c.do("GET", c.deploymentPath(name)+"/scale", "", nil, s); err != nil {
+		return err
+	}
+	s.Spec.Replicas = replicas
+	return c.do("PUT", c.deploymentPath(name)+"/scale", "application/json", s, nil)
+}
+
+func (c *KubernetesClient) listDeployments() ([]deployment, error) {
+	list := &deploymentList{}
+	path := c.deploymentsPath() + "?labelSelector=" + url.QueryEscape(heritageLabel+"="+heritage)
+	if err := c.do("GET", path, "", nil, list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (c *KubernetesClient) listPods(name string) ([]pod, error) {
+	list := &podList{}
+	path := c.podsPath() + "?labelSelector=" + url.QueryEscape(appLabel+"="+name)
+	if err := c.do("GET", path, "", nil, list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
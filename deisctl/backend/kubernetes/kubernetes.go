@@ -0,0 +1,270 @@
+// Package kubernetes implements the deisctl backend on top of the Kubernetes API.
+//
+// Every Deis component is a Deployment named after its unit, such as deis-router. A
+// created component is a Deployment scaled to zero, which Start scales up to the number
+// of instances that were installed and Stop scales back down.
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/config"
+	"github.com/deis/deis/deisctl/units"
+	"github.com/deis/deis/version"
+)
+
+const (
+	appLabel      = "app"
+	heritageLabel = "heritage"
+	heritage      = "deis"
+	// instancesAnnotation records how many replicas Start brings a component up to.
+	instancesAnnotation = "deis.io/instances"
+	// restartedAnnotation changes the pod template to trigger a rolling restart.
+	restartedAnnotation = "deis.io/restartedAt"
+)
+
+// ErrNotSupported is returned for operations which need a shell on the cluster's machines.
+var ErrNotSupported = errors.New("not supported by the kubernetes backend, use kubectl exec instead")
+
+// Flags used for Kubernetes API connectivity, read from the environment by default.
+var Flags = struct {
+	Endpoint  string
+	Token     string
+	Namespace string
+}{
+	Endpoint:  os.Getenv("DEISCTL_K8S_API"),
+	Token:     os.Getenv("DEISCTL_K8S_TOKEN"),
+	Namespace: os.Getenv("DEISCTL_K8S_NAMESPACE"),
+}
+
+// KubernetesClient manages Deis components as Kubernetes Deployments
+type KubernetesClient struct {
+	endpoint      string
+	token         string
+	namespace     string
+	http          *http.Client
+	configBackend config.Backend
+
+	// directories searched for deis-<component>.json Deployment manifests
+	manifestPaths []string
+	// how often and how long to poll while waiting for Deployments
+	pollInterval time.Duration
+	timeout      time.Duration
+
+	out *tabwriter.Writer
+}
+
+// NewClient returns a client used to communicate with the Kubernetes API server
+func NewClient(cb config.Backend) (*KubernetesClient, error) {
+	endpoint := Flags.Endpoint
+	if endpoint == "" {
+		endpoint = "http://127.0.0.1:8080"
+	}
+	namespace := Flags.Namespace
+	if namespace == "" {
+		namespace = "deis"
+	}
+
+	// manifests live next to the fleet unit files
+	var manifestPaths []string
+	if units := os.Getenv("DEISCTL_UNITS"); units != "" {
+		manifestPaths = append(manifestPaths, path.Join(units, "kubernetes"))
+	}
+	manifestPaths = append(manifestPaths, path.Join(os.Getenv("HOME"), ".deis", "units", "kubernetes"),
+		"/var/lib/deis/units/kubernetes")
+
+	out := new(tabwriter.Writer)
+	out.Init(os.Stdout, 0, 8, 1, '\t', 0)
+
+	return &KubernetesClient{
+		endpoint:      endpoint,
+		token:         Flags.Token,
+		namespace:     namespace,
+		http:          &http.Client{Timeout: 30 * time.Second},
+		configBackend: cb,
+		manifestPaths: manifestPaths,
+		pollInterval:  time.Second,
+		timeout:       20 * time.Minute,
+		out:           out,
+	}, nil
+}
+
+// target is a component and, for scalable components, an instance number or "*".
+type target struct {
+	component string
+	instance  string
+}
+
+// deployment returns the name of the Deployment backing the target.
+func (t target) deployment() string {
+	return "deis-" + t.component
+}
+
+var targetRegexp = regexp.MustCompile(`^(?:deis-)?([a-z-]+)(?:@(\d+|\*))?(?:\.service)?$`)
+
+func parseTarget(s string) (target, error) {
+	match := targetRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return target{}, fmt.Errorf("Could not parse target: %v", s)
+	}
+	return target{component: match[1], instance: match[2]}, nil
+}
+
+// groupTargets parses targets and groups them by Deployment, preserving their order.
+func groupTargets(targets []string) ([]string, map[string][]target, error) {
+	var names []string
+	groups := make(map[string][]target)
+	for _, s := range targets {
+		t, err := parseTarget(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := groups[t.deployment()]; !ok {
+			names = append(names, t.deployment())
+		}
+		groups[t.deployment()] = append(groups[t.deployment()], t)
+	}
+	return names, groups, nil
+}
+
+// instances returns how many replicas a group of targets asks for: the highest instance
+// number for scalable components, otherwise one.
+func instances(group []target) int {
+	n := 1
+	for _, t := range group {
+		if i, err := strconv.Atoi(t.instance); err == nil && i > n {
+			n = i
+		}
+	}
+	return n
+}
+
+// newDeployment returns the Deployment for a component, read from a manifest if one exists
+// and otherwise running the component's image as configured for the platform. Manifests are
+// kept as generic objects so that fields deisctl does not know about are preserved.
+func (c *KubernetesClient) newDeployment(component string, replicas int) (map[string]interface{}, error) {
+	name := "deis-" + component
+	d := make(map[string]interface{})
+
+	manifest, err := c.readManifest(name)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil {
+		if err := json.Unmarshal(manifest, &d); err != nil {
+			return nil, fmt.Errorf("invalid manifest for %s: %v", component, err)
+		}
+	} else {
+		image, err := c.image(component)
+		if err != nil {
+			return nil, err
+		}
+		child(child(child(d, "spec"), "template"), "spec")["containers"] = []interface{}{
+			map[string]interface{}{"name": component, "image": image},
+		}
+	}
+
+	labels := map[string]string{appLabel: name, heritageLabel: heritage}
+
+	d["apiVersion"] = "apps/v1"
+	d["kind"] = "Deployment"
+	metadata := child(d, "metadata")
+	metadata["name"] = name
+	metadata["namespace"] = c.namespace
+	merge(child(metadata, "labels"), labels)
+	merge(child(metadata, "annotations"), map[string]string{instancesAnnotation: strconv.Itoa(replicas)})
+
+	spec := child(d, "spec")
+	// created components are started explicitly, like fleet units
+	spec["replicas"] = 0
+	spec["selector"] = map[string]interface{}{"matchLabels": map[string]interface{}{appLabel: name}}
+	merge(child(child(child(spec, "template"), "metadata"), "labels"), labels)
+
+	return d, nil
+}
+
+func (c *KubernetesClient) readManifest(name string) ([]byte, error) {
+	for _, p := range c.manifestPaths {
+		filename := path.Join(p, name+".json")
+		if _, err := os.Stat(filename); err == nil {
+			return ioutil.ReadFile(filename)
+		}
+	}
+	return nil, nil
+}
+
+// image returns the image configured for a component the same way the fleet units do:
+// /deis/<component>/image, or the component's image tagged with the platform version,
+// unless the image tag is pinned in /deis/units. The image registry of /deis/units
+// prefixes either.
+func (c *KubernetesClient) image(component string) (string, error) {
+	vars, err := units.LoadVariables(component, c.configBackend)
+	if err != nil {
+		return "", err
+	}
+	if vars.ImageTag != "" {
+		return vars.ImageRegistry + "deis/" + component + ":" + vars.ImageTag, nil
+	}
+	release, err := c.configBackend.GetWithDefault("/deis/platform/version", "v"+version.Version)
+	if err != nil {
+		return "", err
+	}
+	image, err := c.configBackend.GetWithDefault("/deis/"+component+"/image", "deis/"+component+":"+release)
+	if err != nil {
+		return "", err
+	}
+	return vars.ImageRegistry + image, nil
+}
+
+// child returns the object stored under key, creating it if necessary.
+func child(m map[string]interface{}, key string) map[string]interface{} {
+	if c, ok := m[key].(map[string]interface{}); ok {
+		return c
+	}
+	c := make(map[string]interface{})
+	m[key] = c
+	return c
+}
+
+func merge(m map[string]interface{}, values map[string]string) {
+	for k, v := range values {
+		m[k] = v
+	}
+}
+
+// SSH is not supported, since Kubernetes does not expose the cluster's machines.
+func (c *KubernetesClient) SSH(name string) error {
+	return ErrNotSupported
+}
+
+// SSHExec is not supported, since Kubernetes does not expose the cluster's machines.
+func (c *KubernetesClient) SSHExec(name, cmd string) error {
+	return ErrNotSupported
+}
+
+// Machines is not supported, since Kubernetes does not expose the cluster's machines.
+func (c *KubernetesClient) Machines() ([]backend.Machine, error) {
+	return nil, ErrNotSupported
+}
+
+// Exec is not supported, since Kubernetes does not expose the cluster's machines.
+func (c *KubernetesClient) Exec(machines []backend.Machine, cmd string, out, ew io.Writer) error {
+	return ErrNotSupported
+}
+
+// Dock is not supported, since exec requires a streaming connection to the API server.
+func (c *KubernetesClient) Dock(target string, cmd []string) error {
+	return ErrNotSupported
+}
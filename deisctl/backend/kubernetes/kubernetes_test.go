@@ -0,0 +1,505 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/config/model"
+	"github.com/deis/deis/deisctl/test/mock"
+)
+
+// fakeAPIServer is an in-memory Kubernetes API server whose pods become ready as soon as
+// a Deployment is scaled, unless the Deployment is marked as crashing.
+type fakeAPIServer struct {
+	sync.Mutex
+	deployments map[string]*deployment
+	created     map[string]map[string]interface{}
+	patches     map[string][]map[string]interface{}
+	crashing    map[string]bool
+}
+
+func newFakeAPIServer() *fakeAPIServer {
+	return &fakeAPIServer{
+		deployments: make(map[string]*deployment),
+		created:     make(map[string]map[string]interface{}),
+		patches:     make(map[string][]map[string]interface{}),
+		crashing:    make(map[string]bool),
+	}
+}
+
+func (s *fakeAPIServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/apis/apps/v1/namespaces/deis/deployments"):
+		s.serveDeployments(res, req, parts[6:])
+	case strings.HasPrefix(req.URL.Path, "/api/v1/namespaces/deis/pods"):
+		s.servePods(res, req, parts[5:])
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+func (s *fakeAPIServer) serveDeployments(res http.ResponseWriter, req *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch req.Method {
+		case "GET":
+			list := deploymentList{}
+			for _, d := range s.deployments {
+				list.Items = append(list.Items, *s.withStatus(d))
+			}
+			json.NewEncoder(res).Encode(list)
+		case "POST":
+			raw := make(map[string]interface{})
+			body, _ := ioutil.ReadAll(req.Body)
+			json.Unmarshal(body, &raw)
+			d := &deployment{}
+			json.Unmarshal(body, d)
+			if _, ok := s.deployments[d.Metadata.Name]; ok {
+				writeStatus(res, http.StatusConflict, "already exists")
+				return
+			}
+			d.Metadata.Generation = 1
+			s.deployments[d.Metadata.Name] = d
+			s.created[d.Metadata.Name] = raw
+			res.WriteHeader(http.StatusCreated)
+		}
+		return
+	}
+
+	d, ok := s.deployments[parts[0]]
+	if !ok {
+		writeStatus(res, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == "scale":
+		if req.Method == "PUT" {
+			sc := &scale{}
+			json.NewDecoder(req.Body).Decode(sc)
+			d.Spec.Replicas = sc.Spec.Replicas
+		}
+		sc := scale{}
+		sc.Metadata.Name = d.Metadata.Name
+		sc.Spec.Replicas = d.Spec.Replicas
+		json.NewEncoder(res).Encode(sc)
+	case req.Method == "GET":
+		json.NewEncoder(res).Encode(s.withStatus(d))
+	case req.Method == "PATCH":
+		patch := make(map[string]interface{})
+		json.NewDecoder(req.Body).Decode(&patch)
+		s.patches[d.Metadata.Name] = append(s.patches[d.Metadata.Name], patch)
+		if meta, ok := patch["metadata"].(map[string]interface{}); ok {
+			for k, v := range meta["annotations"].(map[string]interface{}) {
+				if d.Metadata.Annotations == nil {
+					d.Metadata.Annotations = make(map[string]string)
+				}
+				d.Metadata.Annotations[k] = v.(string)
+			}
+		}
+		if _, ok := patch["spec"]; ok {
+			d.Metadata.Generation++
+		}
+	case req.Method == "DELETE":
+		delete(s.deployments, d.Metadata.Name)
+	}
+}
+
+// withStatus reports every replica of a Deployment as ready unless it is crashing.
+func (s *fakeAPIServer) withStatus(d *deployment) *deployment {
+	d.Status = deploymentStatus{ObservedGeneration: d.Metadata.Generation, Replicas: d.Spec.Replicas,
+		UpdatedReplicas: d.Spec.Replicas}
+	if !s.crashing[d.Metadata.Name] {
+		d.Status.ReadyReplicas = d.Spec.Replicas
+		d.Status.AvailableReplicas = d.Spec.Replicas
+	}
+	return d
+}
+
+func (s *fakeAPIServer) servePods(res http.ResponseWriter, req *http.Request, parts []string) {
+	if len(parts) == 2 && parts[1] == "log" {
+		// the second pod's line was written first
+		ts := "2016-01-02T15:04:05.000000002Z"
+		if strings.HasSuffix(parts[0], "-1") {
+			ts = "2016-01-02T15:04:05.000000001Z"
+		}
+		fmt.Fprintf(res, "%s log line from %s (tail %s)\n", ts, parts[0], req.URL.Query().Get("tailLines"))
+		return
+	}
+
+	name := strings.TrimPrefix(req.URL.Query().Get("labelSelector"), appLabel+"=")
+	list := podList{}
+	if d, ok := s.deployments[name]; ok {
+		for i := 0; i < d.Spec.Replicas; i++ {
+			p := pod{Status: podStatus{Phase: "Running", HostIP: "10.0.0.1"}}
+			p.Metadata.Name = fmt.Sprintf("%s-%d", name, i)
+			p.Spec.NodeName = "node-1"
+			p.Spec.Containers = d.Spec.Template.Spec.Containers
+			cs := containerStatus{Name: name, Ready: !s.crashing[name]}
+			if s.crashing[name] {
+				cs.RestartCount = 3
+				cs.State.Waiting = &struct {
+					Reason string `json:"reason"`
+				}{Reason: "CrashLoopBackOff"}
+			}
+			p.Status.ContainerStatuses = []containerStatus{cs}
+			list.Items = append(list.Items, p)
+		}
+	}
+	json.NewEncoder(res).Encode(list)
+}
+
+// syncBuffer synchronizes writes from concurrent operations on a bytes.Buffer.
+type syncBuffer struct {
+	bytes.Buffer
+	mx sync.Mutex
+}
+
+func (s *syncBuffer) Write(b []byte) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.Buffer.Write(b)
+}
+
+func (s *syncBuffer) String() string {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.Buffer.String()
+}
+
+func writeStatus(res http.ResponseWriter, code int, message string) {
+	res.WriteHeader(code)
+	json.NewEncoder(res).Encode(map[string]interface{}{"kind": "Status", "code": code, "message": message})
+}
+
+func newTestClient(t *testing.T, api *fakeAPIServer, manifestPaths ...string) (*KubernetesClient, *bytes.Buffer, func()) {
+	server := httptest.NewServer(api)
+	var out bytes.Buffer
+	tw := new(tabwriter.Writer)
+	tw.Init(&out, 0, 8, 1, ' ', 0)
+
+	c := &KubernetesClient{
+		endpoint:      server.URL,
+		namespace:     "deis",
+		http:          http.DefaultClient,
+		configBackend: mock.ConfigBackend{Expected: []*model.ConfigNode{{Key: "/deis/platform/version", Value: "v1.11.0"}}},
+		manifestPaths: manifestPaths,
+		pollInterval:  10 * time.Millisecond,
+		timeout:       time.Second,
+		out:           tw,
+	}
+	return c, &out, server.Close
+}
+
+func TestCreateStartStopDestroy(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPIServer()
+	c, _, closeServer := newTestClient(t, api)
+	defer closeServer()
+
+	var out syncBuffer
+
+	if err := c.Create([]string{"controller", "router@1", "router@2", "router@3"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	router := api.deployments["deis-router"]
+	if router == nil || router.Spec.Replicas != 0 || router.Metadata.Annotations[instancesAnnotation] != "3" {
+		t.Fatalf("Expected deis-router to be created with 3 stopped instances, Got %+v", router)
+	}
+	expected := "deis/controller:v1.11.0"
+	if image := api.deployments["deis-controller"].Spec.Template.Spec.Containers[0].Image; image != expected {
+		t.Errorf("Expected %s, Got %s", expected, image)
+	}
+
+	if err := c.Start([]string{"controller", "router@*"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if router.Spec.Replicas != 3 || api.deployments["deis-controller"].Spec.Replicas != 1 {
+		t.Errorf("Expected 3 routers and 1 controller, Got %d and %d", router.Spec.Replicas,
+			api.deployments["deis-controller"].Spec.Replicas)
+	}
+
+	if err := c.Stop([]string{"router@*"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if router.Spec.Replicas != 0 {
+		t.Errorf("Expected 0 routers, Got %d", router.Spec.Replicas)
+	}
+
+	if err := c.Destroy([]string{"controller", "router@*"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.deployments) != 0 {
+		t.Errorf("Expected all deployments to be destroyed, Got %v", api.deployments)
+	}
+}
+
+func TestCreateFromManifest(t *testing.T) {
+	t.Parallel()
+
+	name, err := ioutil.TempDir("", "deisctl-kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"spec": {"template": {"spec": {"containers": [{"name": "builder",
+		"image": "example.com/builder:custom", "ports": [{"containerPort": 2223}]}]}}}}`
+	ioutil.WriteFile(path.Join(name, "deis-builder.json"), []byte(manifest), 0644)
+
+	api := newFakeAPIServer()
+	c, _, closeServer := newTestClient(t, api, name)
+	defer closeServer()
+
+	var out syncBuffer
+	if err := c.Create([]string{"builder"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := json.Marshal(api.created["deis-builder"])
+	for _, expected := range []string{`"containerPort":2223`, `"image":"example.com/builder:custom"`,
+		`"heritage":"deis"`, `"replicas":0`} {
+		if !strings.Contains(string(raw), expected) {
+			t.Errorf("Expected %s in %s", expected, raw)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPIServer()
+	c, _, closeServer := newTestClient(t, api)
+	defer closeServer()
+
+	var out syncBuffer
+	if err := c.Create([]string{"registry@1"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Scale("registry", 4, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := api.deployments["deis-registry"]
+	if registry.Spec.Replicas != 4 || registry.Metadata.Annotations[instancesAnnotation] != "4" {
+		t.Errorf("Expected 4 registries, Got %d (%s)", registry.Spec.Replicas,
+			registry.Metadata.Annotations[instancesAnnotation])
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPIServer()
+	c, _, closeServer := newTestClient(t, api)
+	defer closeServer()
+
+	var out syncBuffer
+	if err := c.Create([]string{"database"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	api.crashing["deis-database"] = true
+
+	err := c.Start([]string{"database"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "failed while starting: pod deis-database-0 is CrashLoopBackOff") {
+		t.Errorf("Expected failure during start. Got '%v'", err)
+	}
+}
+
+func TestRollingRestart(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPIServer()
+	c, _, closeServer := newTestClient(t, api)
+	defer closeServer()
+
+	var out syncBuffer
+	if err := c.Create([]string{"router@1", "router@2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start([]string{"router@*"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.RollingRestart("router", backend.RollingRestartOptions{}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	patches := api.patches["deis-router"]
+	raw, _ := json.Marshal(patches[len(patches)-1])
+	for _, expected := range []string{`"maxSurge":1`, `"maxUnavailable":0`, restartedAnnotation} {
+		if !strings.Contains(string(raw), expected) {
+			t.Errorf("Expected %s in %s", expected, raw)
+		}
+	}
+}
+
+func TestRollingRestartAbort(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPIServer()
+	c, _, closeServer := newTestClient(t, api)
+	defer closeServer()
+
+	var out syncBuffer
+	if err := c.Create([]string{"router@1"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start([]string{"router@*"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	// the router never publishes its health key
+	opts := backend.RollingRestartOptions{MaxUnavailable: 1, HealthKey: "/deis/router/hosts/{instance}",
+		Timeout: 50 * time.Millisecond}
+	err := c.RollingRestart("router", opts, &out)
+
+	expected := "rolling restart of deis-router aborted: /deis/router/hosts/1 was not published"
+	if err == nil || !strings.HasPrefix(err.Error(), expected) {
+		t.Errorf("Expected '%s', Got '%v'", expected, err)
+	}
+
+	patches := api.patches["deis-router"]
+	raw, _ := json.Marshal(patches[len(patches)-1])
+	if string(raw) != `{"spec":{"paused":true}}` {
+		t.Errorf("Expected the rollout to be paused, Got %s", raw)
+	}
+}
+
+func TestStatusAndListUnits(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPIServer()
+	c, out, closeServer := newTestClient(t, api)
+	defer closeServer()
+
+	var ignored syncBuffer
+	if err := c.Create([]string{"publisher"}, &ignored); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start([]string{"publisher"}, &ignored); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Status("publisher"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "deis-publisher-0 Running 1/1   0        node-1 -") {
+		t.Errorf("Unexpected status output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := c.ListUnits(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "UNIT           DESIRED CURRENT READY IMAGE\n" +
+		"deis-publisher 1       1       1     deis/publisher:v1.11.0\n"
+	if out.String() != expected {
+		t.Errorf("Expected '%s', Got '%s'", expected, out.String())
+	}
+
+	if err := c.Status("builder"); err == nil {
+		t.Error("Expected an error for a component without pods")
+	}
+}
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPIServer()
+	c, _, closeServer := newTestClient(t, api)
+	defer closeServer()
+
+	logs, err := c.stream(c.podsPath() + "/deis-router-1/log?timestamps=true&tailLines=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logs.Close()
+	entries := make(chan backend.JournalEntry, 1)
+	if err := readLogs(logs, "deis-router-1", entries); err != nil {
+		t.Fatal(err)
+	}
+	entry := <-entries
+	if entry.Message != "log line from deis-router-1 (tail 5)" || entry.Time.Nanosecond() != 1 {
+		t.Errorf("Unexpected log entry: %+v", entry)
+	}
+
+	if err := c.Journal([]string{"router"}, backend.JournalOptions{}); err == nil {
+		t.Error("Expected an error for a component without pods")
+	}
+	if err := c.Journal([]string{"router"}, backend.JournalOptions{Since: "yesterday"}); err == nil {
+		t.Error("Expected an error for a time which is not a duration")
+	}
+}
+
+func TestUnitStates(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPIServer()
+	c, _, closeServer := newTestClient(t, api)
+	defer closeServer()
+
+	var ignored syncBuffer
+	if err := c.Create([]string{"router@2", "database"}, &ignored); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start([]string{"router@*"}, &ignored); err != nil {
+		t.Fatal(err)
+	}
+	api.Lock()
+	api.deployments["deis-database"].Spec.Replicas = 1
+	api.crashing["deis-database"] = true
+	api.Unlock()
+
+	states, err := c.UnitStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actives := make(map[string][]string)
+	for _, s := range states {
+		if s.Host != "10.0.0.1" {
+			t.Errorf("Expected host 10.0.0.1, Got %s", s.Host)
+		}
+		actives[s.Name] = append(actives[s.Name], s.Active)
+	}
+	if strings.Join(actives["deis-router"], ",") != "active,active" {
+		t.Errorf("Expected two active routers, Got %v", actives["deis-router"])
+	}
+	if strings.Join(actives["deis-database"], ",") != "inactive" {
+		t.Errorf("Expected an inactive database, Got %v", actives["deis-database"])
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	t.Parallel()
+
+	c := &KubernetesClient{}
+	if err := c.SSH("controller"); err != ErrNotSupported {
+		t.Errorf("Expected '%v', Got '%v'", ErrNotSupported, err)
+	}
+	if err := c.Dock("controller", nil); err != ErrNotSupported {
+		t.Errorf("Expected '%v', Got '%v'", ErrNotSupported, err)
+	}
+	if _, err := c.Machines(); err != ErrNotSupported {
+		t.Errorf("Expected '%v', Got '%v'", ErrNotSupported, err)
+	}
+	if err := c.Rebalance("router", ioutil.Discard); err != ErrNotSupported {
+		t.Errorf("Expected '%v', Got '%v'", ErrNotSupported, err)
+	}
+}
@@ -0,0 +1,241 @@
+package kubernetes
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+)
+
+// journalLines is how many lines of each pod's log Journal prints.
+const journalLines = 100
+
+// ListUnits prints the Deis Deployments and how many of their pods are ready
+func (c *KubernetesClient) ListUnits() error {
+	deployments, err := c.listDeployments()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.out, "UNIT\tDESIRED\tCURRENT\tREADY\tIMAGE")
+	for _, d := range deployments {
+		fmt.Fprintf(c.out, "%s\t%d\t%d\t%d\t%s\n", d.Metadata.Name, d.Spec.Replicas,
+			d.Status.Replicas, d.Status.ReadyReplicas, images(d.Spec.Template.Spec))
+	}
+	c.out.Flush()
+	return nil
+}
+
+// ListUnitFiles prints the pod template of every Deis Deployment
+func (c *KubernetesClient) ListUnitFiles() error {
+	deployments, err := c.listDeployments()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.out, "UNIT\tINSTANCES\tCONTAINER\tIMAGE\tCOMMAND")
+	for _, d := range deployments {
+		for _, ct := range d.Spec.Template.Spec.Containers {
+			command := strings.Join(append(ct.Command, ct.Args...), " ")
+			if command == "" {
+				command = "-"
+			}
+			fmt.Fprintf(c.out, "%s\t%d\t%s\t%s\t%s\n", d.Metadata.Name, desiredInstances(&d),
+				ct.Name, ct.Image, command)
+		}
+	}
+	c.out.Flush()
+	return nil
+}
+
+// UnitStates returns the state of every pod of every Deis Deployment, named after its
+// Deployment. A pod is active once all of its containers are ready.
+func (c *KubernetesClient) UnitStates() ([]backend.UnitState, error) {
+	deployments, err := c.listDeployments()
+	if err != nil {
+		return nil, err
+	}
+
+	var states []backend.UnitState
+	for _, d := range deployments {
+		pods, err := c.listPods(d.Metadata.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pods {
+			state := backend.UnitState{Name: d.Metadata.Name, Active: "inactive",
+				Sub: strings.ToLower(p.Status.Phase), Host: p.Status.HostIP}
+			if p.Status.Phase == "Failed" {
+				state.Active = "failed"
+			} else if p.Status.Phase == "Running" && podReady(p) {
+				state.Active = "active"
+			}
+			if p.Status.StartTime != nil {
+				state.Since = *p.Status.StartTime
+			}
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+// ActiveSince returns the states as they are, since UnitStates reads when pods started
+// from the API server.
+func (c *KubernetesClient) ActiveSince(states []backend.UnitState) ([]backend.UnitState, error) {
+	return states, nil
+}
+
+func podReady(p pod) bool {
+	for _, cs := range p.Status.ContainerStatuses {
+		if !cs.Ready {
+			return false
+		}
+	}
+	return len(p.Status.ContainerStatuses) > 0
+}
+
+// Status prints the state of every pod of the target component
+func (c *KubernetesClient) Status(target string) error {
+	t, err := parseTarget(target)
+	if err != nil {
+		return err
+	}
+
+	pods, err := c.listPods(t.deployment())
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("could not find pods for: %s", target)
+	}
+
+	fmt.Fprintln(c.out, "POD\tPHASE\tREADY\tRESTARTS\tNODE\tREASON")
+	for _, p := range pods {
+		ready, restarts, reason := 0, 0, "-"
+		for _, cs := range p.Status.ContainerStatuses {
+			if cs.Ready {
+				ready++
+			}
+			restarts += cs.RestartCount
+			if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+				reason = cs.State.Waiting.Reason
+			}
+		}
+		node := p.Spec.NodeName
+		if node == "" {
+			node = "-"
+		}
+		fmt.Fprintf(c.out, "%s\t%s\t%d/%d\t%d\t%s\t%s\n", p.Metadata.Name, p.Status.Phase, ready,
+			len(p.Spec.Containers), restarts, node, reason)
+	}
+	c.out.Flush()
+	return nil
+}
+
+// Journal prints the log output of every pod of the target components, read
+// concurrently and interleaved by time
+func (c *KubernetesClient) Journal(targets []string, opts backend.JournalOptions) error {
+	query := url.Values{"timestamps": {"true"}}
+	switch d, ok := opts.SinceDuration(); {
+	case ok:
+		query.Set("sinceSeconds", fmt.Sprint(int(d.Seconds())))
+	case opts.Since != "":
+		return fmt.Errorf("--since must be a duration such as 10m with the kubernetes backend")
+	case opts.Lines > 0:
+		query.Set("tailLines", fmt.Sprint(opts.Lines))
+	default:
+		query.Set("tailLines", fmt.Sprint(journalLines))
+	}
+	if opts.Follow {
+		query.Set("follow", "true")
+	}
+
+	var names []string
+	for _, target := range targets {
+		t, err := parseTarget(target)
+		if err != nil {
+			return err
+		}
+		pods, err := c.listPods(t.deployment())
+		if err != nil {
+			return err
+		}
+		if len(pods) == 0 {
+			return fmt.Errorf("could not find pods for: %s", target)
+		}
+		for _, p := range pods {
+			names = append(names, p.Metadata.Name)
+		}
+	}
+
+	printer, err := backend.NewJournalPrinter(os.Stdout, names, opts)
+	if err != nil {
+		return err
+	}
+
+	errs := backend.NewErrors(os.Stderr)
+	entries := make(chan backend.JournalEntry)
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			logs, err := c.stream(c.podsPath() + "/" + name + "/log?" + query.Encode())
+			if err != nil {
+				fmt.Fprintf(errs, "Error reading the log output of %s: %v\n", name, err)
+				return
+			}
+			defer logs.Close()
+			if err := readLogs(logs, name, entries); err != nil {
+				fmt.Fprintf(errs, "Error reading the log output of %s: %v\n", name, err)
+			}
+		}(name)
+	}
+	go func() {
+		wg.Wait()
+		close(entries)
+	}()
+
+	printer.Print(entries)
+	return errs.Err()
+}
+
+// readLogs sends the lines of a pod's log output to a channel until it ends. The API
+// server precedes each line with its time when asked for timestamps.
+func readLogs(r io.Reader, pod string, entries chan<- backend.JournalEntry) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			entry := backend.JournalEntry{Time: time.Now(), Unit: pod, Message: line}
+			if i := strings.IndexByte(line, ' '); i > 0 {
+				if ts, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+					entry.Time, entry.Message = ts, line[i+1:]
+				}
+			}
+			entries <- entry
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func images(spec podSpec) string {
+	var images []string
+	for _, ct := range spec.Containers {
+		images = append(images, ct.Image)
+	}
+	if len(images) == 0 {
+		return "-"
+	}
+	return strings.Join(images, ",")
+}
@@ -0,0 +1,363 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/pkg/prettyprint"
+)
+
+var stateFmt = prettyprint.Colorize("{{.Yellow}}%v:{{.Default}} %v")
+
+// Create creates a Deployment for each component, scaled to zero until it is started.
+// Scalable components are created with as many instances as the highest one requested.
+func (c *KubernetesClient) Create(targets []string, out io.Writer) error {
+	names, groups, err := groupTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	return backend.InParallel(len(names), func(i int) error {
+		return c.doCreate(groups[names[i]], out)
+	})
+}
+
+func (c *KubernetesClient) doCreate(group []target, out io.Writer) error {
+	name := group[0].deployment()
+	replicas := instances(group)
+
+	d, err := c.newDeployment(group[0].component, replicas)
+	if err != nil {
+		return fmt.Errorf("Error creating: %s", err)
+	}
+
+	err = c.do("POST", c.deploymentsPath(), "application/json", d, nil)
+	if isConflict(err) {
+		// an existing component is kept, but may be asked for more instances
+		err = c.growInstances(name, replicas)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, stateFmt+"\n", name, "loaded")
+	return nil
+}
+
+// growInstances raises the number of instances recorded for an existing Deployment.
+func (c *KubernetesClient) growInstances(name string, replicas int) error {
+	d, err := c.getDeployment(name)
+	if err != nil {
+		return err
+	}
+	if desiredInstances(d) >= replicas {
+		return nil
+	}
+	return c.patchDeployment(name, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{instancesAnnotation: strconv.Itoa(replicas)},
+		},
+	})
+}
+
+// desiredInstances returns the number of replicas a Deployment runs once started.
+func desiredInstances(d *deployment) int {
+	if n, err := strconv.Atoi(d.Metadata.Annotations[instancesAnnotation]); err == nil {
+		return n
+	}
+	return 1
+}
+
+// Destroy deletes the Deployments for the given components, along with their pods.
+func (c *KubernetesClient) Destroy(targets []string, out io.Writer) error {
+	names, _, err := groupTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	return backend.InParallel(len(names), func(i int) error {
+		return c.doDestroy(names[i], out)
+	})
+}
+
+func (c *KubernetesClient) doDestroy(name string, out io.Writer) error {
+	opts := map[string]string{"kind": "DeleteOptions", "apiVersion": "v1", "propagationPolicy": "Foreground"}
+	if err := c.do("DELETE", c.deploymentPath(name), "application/json", opts, nil); err != nil && !isNotFound(err) {
+		return err
+	}
+
+	// wait until the Deployment and its pods are gone
+	err := c.poll(func() (bool, error) {
+		if _, err := c.getDeployment(name); !isNotFound(err) {
+			return false, err
+		}
+		pods, err := c.listPods(name)
+		return len(pods) == 0, err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, stateFmt+"\n", name, "destroyed")
+	return nil
+}
+
+// Start scales the Deployments for the given components up to their instances and waits
+// for all of their pods to be ready.
+func (c *KubernetesClient) Start(targets []string, out io.Writer) error {
+	names, groups, err := groupTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	return backend.InParallel(len(names), func(i int) error {
+		return c.doStart(groups[names[i]], out)
+	})
+}
+
+func (c *KubernetesClient) doStart(group []target, out io.Writer) error {
+	name := group[0].deployment()
+	d, err := c.getDeployment(name)
+	if err != nil {
+		return err
+	}
+
+	// starting a specific instance brings up every instance before it as well
+	replicas := desiredInstances(d)
+	if n := instances(group); n > replicas {
+		replicas = n
+	}
+
+	if err := c.scaleAndWait(name, replicas, out); err != nil {
+		return fmt.Errorf("The service '%s' failed while starting: %v", name, err)
+	}
+	return nil
+}
+
+// Stop scales the Deployments for the given components down to zero, keeping the number of
+// instances to start them with again.
+func (c *KubernetesClient) Stop(targets []string, out io.Writer) error {
+	names, _, err := groupTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	return backend.InParallel(len(names), func(i int) error {
+		return c.doStop(names[i], out)
+	})
+}
+
+func (c *KubernetesClient) doStop(name string, out io.Writer) error {
+	// a component which was never installed is already stopped
+	if _, err := c.getDeployment(name); isNotFound(err) {
+		fmt.Fprintf(out, stateFmt+"\n", name, "not installed")
+		return nil
+	}
+	if err := c.scaleAndWait(name, 0, out); err != nil {
+		return fmt.Errorf("The service '%s' failed while stopping: %v", name, err)
+	}
+	return nil
+}
+
+// Scale changes the number of instances of a component, starting or stopping pods to match.
+func (c *KubernetesClient) Scale(component string, requested int, out io.Writer) error {
+	if requested < 0 {
+		return errors.New("cannot scale below 0")
+	}
+
+	t, err := parseTarget(component)
+	if err != nil {
+		return err
+	}
+
+	// record the new size so that a later start keeps it
+	err = c.patchDeployment(t.deployment(), map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{instancesAnnotation: strconv.Itoa(requested)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return c.scaleAndWait(t.deployment(), requested, out)
+}
+
+// RollingRestart replaces the pods of a component through a Deployment rollout, which keeps
+// at most opts.MaxUnavailable pods down, or surges one pod at a time when it is zero. The
+// rollout is paused if it fails to complete or a health key is not published.
+func (c *KubernetesClient) RollingRestart(component string, opts backend.RollingRestartOptions, out io.Writer) error {
+	if opts.MaxUnavailable < 0 {
+		return errors.New("max unavailable cannot be below 0")
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = c.timeout
+	}
+
+	t, err := parseTarget(component)
+	if err != nil {
+		return err
+	}
+	name := t.deployment()
+
+	if err := c.doRollingRestart(name, opts, out); err != nil {
+		errs := backend.NewErrors(nil)
+		errs.Add(fmt.Errorf("rolling restart of %s aborted: %v", name, err))
+		// stop Kubernetes from replacing any more pods
+		errs.Add(c.patchDeployment(name, map[string]interface{}{
+			"spec": map[string]interface{}{"paused": true},
+		}))
+		return errs.Err()
+	}
+	return nil
+}
+
+func (c *KubernetesClient) doRollingRestart(name string, opts backend.RollingRestartOptions, out io.Writer) error {
+	maxSurge := 0
+	if opts.MaxUnavailable == 0 {
+		maxSurge = 1
+	}
+
+	err := c.patchDeployment(name, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"paused": false,
+			"strategy": map[string]interface{}{
+				"type": "RollingUpdate",
+				"rollingUpdate": map[string]interface{}{
+					"maxUnavailable": opts.MaxUnavailable,
+					"maxSurge":       maxSurge,
+				},
+			},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{restartedAnnotation: time.Now().UTC().Format(time.RFC3339)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var replicas int
+	err = c.pollFor(opts.Timeout, func() (bool, error) {
+		d, err := c.getDeployment(name)
+		if err != nil {
+			return false, err
+		}
+		replicas = d.Spec.Replicas
+		if err := c.checkPods(name); err != nil {
+			return false, err
+		}
+		fmt.Fprint(out, prettyprint.Overwritef(stateFmt, name,
+			fmt.Sprintf("%d/%d replaced", d.Status.UpdatedReplicas, d.Spec.Replicas)))
+		return d.Status.ObservedGeneration >= d.Metadata.Generation &&
+			d.Status.UpdatedReplicas == d.Spec.Replicas &&
+			d.Status.AvailableReplicas == d.Spec.Replicas &&
+			d.Status.Replicas == d.Spec.Replicas, nil
+	})
+	fmt.Fprintln(out)
+	if err != nil {
+		return err
+	}
+
+	if opts.HealthKey == "" {
+		return nil
+	}
+
+	// pods are not numbered, so every instance number up to the replicas must be published
+	for i := 1; i <= replicas; i++ {
+		key := strings.Replace(opts.HealthKey, "{instance}", strconv.Itoa(i), -1)
+		err := c.pollFor(opts.Timeout, func() (bool, error) {
+			_, err := c.configBackend.Get(key)
+			return err == nil, nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s was not published: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// Rebalance is not supported, since the Kubernetes scheduler places and moves pods itself.
+func (c *KubernetesClient) Rebalance(component string, out io.Writer) error {
+	return ErrNotSupported
+}
+
+// scaleAndWait sets the replicas of a Deployment and waits for exactly that many pods to be ready.
+func (c *KubernetesClient) scaleAndWait(name string, replicas int, out io.Writer) error {
+	if err := c.setReplicas(name, replicas); err != nil {
+		return err
+	}
+
+	last := ""
+	err := c.poll(func() (bool, error) {
+		d, err := c.getDeployment(name)
+		if err != nil {
+			return false, err
+		}
+		if replicas > 0 {
+			if err := c.checkPods(name); err != nil {
+				return false, err
+			}
+		}
+
+		state := fmt.Sprintf("%d/%d ready", d.Status.ReadyReplicas, replicas)
+		if state != last {
+			fmt.Fprint(out, prettyprint.Overwritef(stateFmt, name, state))
+			last = state
+		}
+		return d.Status.ReadyReplicas == replicas && d.Status.Replicas == replicas, nil
+	})
+	fmt.Fprintln(out)
+	return err
+}
+
+// checkPods returns an error if any pod of a Deployment cannot start.
+func (c *KubernetesClient) checkPods(name string) error {
+	pods, err := c.listPods(name)
+	if err != nil {
+		return err
+	}
+	for _, p := range pods {
+		if p.Status.Phase == "Failed" {
+			return fmt.Errorf("pod %s failed", p.Metadata.Name)
+		}
+		for _, cs := range p.Status.ContainerStatuses {
+			if cs.State.Waiting == nil {
+				continue
+			}
+			switch cs.State.Waiting.Reason {
+			case "CrashLoopBackOff", "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError":
+				return fmt.Errorf("pod %s is %s", p.Metadata.Name, cs.State.Waiting.Reason)
+			}
+		}
+	}
+	return nil
+}
+
+// poll calls done until it reports true, fails, or the client's timeout passes.
+func (c *KubernetesClient) poll(done func() (bool, error)) error {
+	return c.pollFor(c.timeout, done)
+}
+
+func (c *KubernetesClient) pollFor(timeout time.Duration, done func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+		time.Sleep(c.pollInterval)
+	}
+}
@@ -0,0 +1,203 @@
+// Package local implements the deisctl backend on a single machine, for development.
+//
+// Components are rendered from the same unit templates the fleet backend schedules and are
+// installed into the local systemd, which starts and stops them with systemctl. Fleet-only
+// settings in the templates are dropped, so no cluster is needed to run the platform.
+package local
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/coreos/go-systemd/unit"
+	"github.com/deis/deis/deisctl/backend/fleet"
+	"github.com/deis/deis/deisctl/config"
+	"github.com/deis/deis/deisctl/units"
+)
+
+// Flags used for the local backend, read from the environment by default.
+var Flags = struct {
+	UnitDir string
+}{
+	UnitDir: os.Getenv("DEISCTL_SYSTEMD_DIR"),
+}
+
+// LocalClient manages Deis components as units of the local systemd
+type LocalClient struct {
+	configBackend config.Backend
+	templatePaths []string
+
+	// directory the rendered units are written to
+	unitDir string
+	runner  commandRunner
+	// how often and how long to poll while waiting for units
+	pollInterval time.Duration
+	timeout      time.Duration
+
+	out *tabwriter.Writer
+}
+
+// NewClient returns a client which installs units into the local systemd
+func NewClient(cb config.Backend) (*LocalClient, error) {
+	unitDir := Flags.UnitDir
+	if unitDir == "" {
+		unitDir = "/etc/systemd/system"
+	}
+
+	out := new(tabwriter.Writer)
+	out.Init(os.Stdout, 0, 8, 1, '\t', 0)
+
+	return &LocalClient{
+		configBackend: cb,
+		templatePaths: units.TemplatePaths(),
+		unitDir:       unitDir,
+		runner:        execRunner{},
+		pollInterval:  time.Second,
+		timeout:       20 * time.Minute,
+		out:           out,
+	}, nil
+}
+
+// Units returns the names of the installed units whose names start with target, trying
+// again with the "deis-" prefix if nothing matches it as given.
+func (c *LocalClient) Units(target string) ([]string, error) {
+	all, err := c.installedUnits()
+	if err != nil {
+		return nil, err
+	}
+
+	var units []string
+	for _, prefix := range []string{target, "deis-" + strings.ToLower(target)} {
+		for _, u := range all {
+			if strings.HasPrefix(u, prefix) {
+				units = append(units, u)
+			}
+		}
+		if len(units) > 0 {
+			return units, nil
+		}
+	}
+	return nil, fmt.Errorf("could not find unit: %s", target)
+}
+
+// installedUnits returns the sorted names of the Deis units in the unit directory.
+func (c *LocalClient) installedUnits() ([]string, error) {
+	files, err := ioutil.ReadDir(c.unitDir)
+	if err != nil {
+		return nil, err
+	}
+	var units []string
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), "deis-") && strings.HasSuffix(f.Name(), ".service") {
+			units = append(units, f.Name())
+		}
+	}
+	sort.Strings(units)
+	return units, nil
+}
+
+// expandTargets returns the unit names for targets, with "@*" matching every installed instance.
+func (c *LocalClient) expandTargets(targets []string) ([]string, error) {
+	var names []string
+	for _, t := range targets {
+		if strings.HasSuffix(t, "@*") {
+			units, err := c.Units(strings.TrimSuffix(t, "@*") + "@")
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, units...)
+			continue
+		}
+		name, err := unitName(t)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+var targetRegexp = regexp.MustCompile(`^(?:deis-)?([a-z-]+)(?:@(\d+))?(?:\.service)?$`)
+
+// splitTarget returns the component and instance number of a target, which is zero for
+// components that are not scalable.
+func splitTarget(target string) (string, int, error) {
+	match := targetRegexp.FindStringSubmatch(target)
+	if match == nil {
+		return "", 0, fmt.Errorf("Could not parse target: %v", target)
+	}
+	if match[2] == "" {
+		return match[1], 0, nil
+	}
+	num, err := strconv.Atoi(match[2])
+	return match[1], num, err
+}
+
+// unitName returns the systemd unit name for a target, such as deis-router@1.service.
+func unitName(target string) (string, error) {
+	component, num, err := splitTarget(target)
+	if err != nil {
+		return "", err
+	}
+	if num == 0 {
+		return "deis-" + component + ".service", nil
+	}
+	return "deis-" + component + "@" + strconv.Itoa(num) + ".service", nil
+}
+
+// fleetUnits are dependencies of the templates which only exist on fleet machines.
+var fleetUnits = map[string]bool{"fleet.socket": true, "fleet.service": true}
+
+// render returns a component's unit template with everything that only fleet understands
+// removed: the [X-Fleet] section and dependencies on fleet itself. The environment file
+// written by CoreOS becomes optional, since a development machine may not have one.
+func (c *LocalClient) render(component string) (string, error) {
+	vars, err := units.LoadVariables(component, c.configBackend)
+	if err != nil {
+		return "", err
+	}
+	uf, err := fleet.NewUnit(component, c.templatePaths, false, vars)
+	if err != nil {
+		return "", err
+	}
+
+	var options []*unit.UnitOption
+	for _, opt := range uf.Options {
+		if opt.Section == "X-Fleet" {
+			continue
+		}
+		switch opt.Name {
+		case "Requires", "After", "Wants", "BindsTo":
+			var deps []string
+			for _, dep := range strings.Fields(opt.Value) {
+				if !fleetUnits[dep] {
+					deps = append(deps, dep)
+				}
+			}
+			if len(deps) == 0 {
+				continue
+			}
+			opt = &unit.UnitOption{Section: opt.Section, Name: opt.Name, Value: strings.Join(deps, " ")}
+		case "EnvironmentFile":
+			if !strings.HasPrefix(opt.Value, "-") {
+				opt = &unit.UnitOption{Section: opt.Section, Name: opt.Name, Value: "-" + opt.Value}
+			}
+		}
+		options = append(options, opt)
+	}
+	rendered, err := ioutil.ReadAll(unit.Serialize(options))
+	return string(rendered), err
+}
+
+// unitPath returns where a unit's file is installed.
+func (c *LocalClient) unitPath(name string) string {
+	return path.Join(c.unitDir, name)
+}
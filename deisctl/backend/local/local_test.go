@@ -0,0 +1,421 @@
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/config/model"
+	"github.com/deis/deis/deisctl/test/mock"
+)
+
+const routerTemplate = `[Unit]
+Description=deis-router
+Requires=fleet.socket docker.service
+After=fleet.socket
+
+[Service]
+EnvironmentFile=/etc/environment
+ExecStart=/bin/sh -c "docker run --name deis-router deis/router"
+
+[X-Fleet]
+Conflicts=deis-router@*.service
+`
+
+// fakeSystemd records the commands it is asked to run and tracks which units are active.
+type fakeSystemd struct {
+	sync.Mutex
+	commands []string
+	active   map[string]bool
+	failing  map[string]bool
+}
+
+func newFakeSystemd() *fakeSystemd {
+	return &fakeSystemd{active: make(map[string]bool), failing: make(map[string]bool)}
+}
+
+func (f *fakeSystemd) Run(out io.Writer, name string, args ...string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.commands = append(f.commands, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+	if name != "systemctl" || len(args) == 0 {
+		return nil
+	}
+	switch args[0] {
+	case "start", "restart":
+		for _, unit := range args[1:] {
+			f.active[unit] = !f.failing[unit]
+		}
+	case "stop":
+		for _, unit := range args[1:] {
+			f.active[unit] = false
+		}
+	case "show":
+		unit := args[len(args)-1]
+		switch {
+		case f.active[unit]:
+			fmt.Fprint(out, "LoadState=loaded\nActiveState=active\nSubState=running\n")
+		case f.failing[unit]:
+			fmt.Fprint(out, "LoadState=loaded\nActiveState=failed\nSubState=failed\n")
+		default:
+			fmt.Fprint(out, "LoadState=loaded\nActiveState=inactive\nSubState=dead\n")
+		}
+	}
+	return nil
+}
+
+func (f *fakeSystemd) Interactive(name string, args ...string) error {
+	return f.Run(ioutil.Discard, name, args...)
+}
+
+// ran returns the commands run other than queries of unit state.
+func (f *fakeSystemd) ran() []string {
+	f.Lock()
+	defer f.Unlock()
+
+	var commands []string
+	for _, c := range f.commands {
+		if !strings.HasPrefix(c, "systemctl show") {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+type syncBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func newTestClient(t *testing.T, expected []*model.ConfigNode) (*LocalClient, *fakeSystemd, *bytes.Buffer, func()) {
+	templates, err := ioutil.TempDir("", "deisctl-units")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unitDir, err := ioutil.TempDir("", "deisctl-systemd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(templates, "deis-router.service"), []byte(routerTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	out := new(tabwriter.Writer)
+	out.Init(&b, 0, 8, 1, ' ', 0)
+
+	fake := newFakeSystemd()
+	c := &LocalClient{
+		configBackend: &mock.ConfigBackend{Expected: expected},
+		templatePaths: []string{templates},
+		unitDir:       unitDir,
+		runner:        fake,
+		pollInterval:  time.Millisecond,
+		timeout:       100 * time.Millisecond,
+		out:           out,
+	}
+	return c, fake, &b, func() {
+		os.RemoveAll(templates)
+		os.RemoveAll(unitDir)
+	}
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	c, _, _, cleanup := newTestClient(t, nil)
+	defer cleanup()
+
+	rendered, err := c.render("router")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[Unit]
+Description=deis-router
+Requires=docker.service
+
+[Service]
+EnvironmentFile=-/etc/environment
+ExecStart=/bin/sh -c "docker run --name deis-router deis/router"
+`
+	if rendered != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, rendered))
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	t.Parallel()
+
+	c, fake, _, cleanup := newTestClient(t, nil)
+	defer cleanup()
+
+	var out syncBuffer
+
+	if err := c.Create([]string{"router@1", "router@2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"deis-router@1.service", "deis-router@2.service"} {
+		if _, err := os.Stat(path.Join(c.unitDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := c.Start([]string{"router@*"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Stop([]string{"router@2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Destroy([]string{"router@*"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if units, _ := c.installedUnits(); len(units) != 0 {
+		t.Error(fmt.Errorf("Expected no units, Got %v", units))
+	}
+
+	ran := fake.ran()
+	expected := []string{
+		"systemctl daemon-reload",
+		"systemctl start deis-router@1.service",
+		"systemctl start deis-router@2.service",
+		"systemctl stop deis-router@2.service",
+		"systemctl stop deis-router@1.service",
+		"systemctl stop deis-router@2.service",
+		"systemctl daemon-reload",
+	}
+	if len(ran) != len(expected) {
+		t.Fatal(fmt.Errorf("Expected %v, Got %v", expected, ran))
+	}
+	// units are started concurrently
+	if ran[1] > ran[2] {
+		ran[1], ran[2] = ran[2], ran[1]
+	}
+	for i := range expected {
+		if ran[i] != expected[i] {
+			t.Error(fmt.Errorf("Expected %v, Got %v", expected, ran))
+			break
+		}
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	t.Parallel()
+
+	c, fake, _, cleanup := newTestClient(t, nil)
+	defer cleanup()
+	fake.failing["deis-router@1.service"] = true
+
+	var out syncBuffer
+
+	if err := c.Create([]string{"router@1"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Start([]string{"router@1"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "failed while starting: unit is failed (failed)") {
+		t.Error(fmt.Errorf("Expected a start failure, Got %v", err))
+	}
+}
+
+func TestScale(t *testing.T) {
+	t.Parallel()
+
+	c, _, _, cleanup := newTestClient(t, nil)
+	defer cleanup()
+
+	var out syncBuffer
+
+	if err := c.Scale("router", 3, &out); err != nil {
+		t.Fatal(err)
+	}
+	units, _ := c.installedUnits()
+	if len(units) != 3 {
+		t.Error(fmt.Errorf("Expected 3 units, Got %v", units))
+	}
+
+	// a gap left by destroying an instance is filled first
+	if err := c.Destroy([]string{"router@1", "router@2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Scale("router", 2, &out); err != nil {
+		t.Fatal(err)
+	}
+	units, _ = c.installedUnits()
+	if expected := []string{"deis-router@1.service", "deis-router@3.service"}; !reflect.DeepEqual(units, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, units))
+	}
+
+	if err := c.Scale("router", 1, &out); err != nil {
+		t.Fatal(err)
+	}
+	units, _ = c.installedUnits()
+	if len(units) != 1 || units[0] != "deis-router@1.service" {
+		t.Error(fmt.Errorf("Expected [deis-router@1.service], Got %v", units))
+	}
+}
+
+func TestRollingRestart(t *testing.T) {
+	t.Parallel()
+
+	c, fake, _, cleanup := newTestClient(t, []*model.ConfigNode{
+		{Key: "/deis/router/hosts/1", Value: "up"},
+		{Key: "/deis/router/hosts/2", Value: "up"},
+		{Key: "/deis/router/hosts/3", Value: "up"},
+	})
+	defer cleanup()
+
+	var out syncBuffer
+
+	if err := c.Scale("router", 3, &out); err != nil {
+		t.Fatal(err)
+	}
+	fake.commands = nil
+
+	opts := backend.RollingRestartOptions{MaxUnavailable: 2, HealthKey: "/deis/router/hosts/{instance}"}
+	if err := c.RollingRestart("router", opts, &out); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"systemctl restart deis-router@1.service deis-router@2.service",
+		"systemctl restart deis-router@3.service",
+	}
+	ran := fake.ran()
+	if strings.Join(ran, ",") != strings.Join(expected, ",") {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, ran))
+	}
+}
+
+func TestRollingRestartAborts(t *testing.T) {
+	t.Parallel()
+
+	c, fake, _, cleanup := newTestClient(t, []*model.ConfigNode{
+		{Key: "/deis/router/hosts/1", Value: "up"},
+	})
+	defer cleanup()
+
+	var out syncBuffer
+
+	if err := c.Scale("router", 2, &out); err != nil {
+		t.Fatal(err)
+	}
+	fake.commands = nil
+
+	// the second instance never publishes its health key
+	opts := backend.RollingRestartOptions{MaxUnavailable: 1, HealthKey: "/deis/router/hosts/{instance}"}
+	err := c.RollingRestart("router", opts, &out)
+	if err == nil || !strings.Contains(err.Error(), "rolling restart of router aborted: /deis/router/hosts/2 was not published") {
+		t.Error(fmt.Errorf("Expected an aborted restart, Got %v", err))
+	}
+
+	// surging needs a second container of the same name
+	err = c.RollingRestart("router", backend.RollingRestartOptions{}, &out)
+	if err == nil || !strings.Contains(err.Error(), "router cannot be surged on a single machine") {
+		t.Error(fmt.Errorf("Expected a surge error, Got %v", err))
+	}
+}
+
+func TestListUnits(t *testing.T) {
+	t.Parallel()
+
+	c, _, b, cleanup := newTestClient(t, nil)
+	defer cleanup()
+
+	var out syncBuffer
+
+	if err := c.Create([]string{"router@1", "router@2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start([]string{"router@1"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ListUnits(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `UNIT                  LOAD   ACTIVE   SUB
+deis-router@1.service loaded active   running
+deis-router@2.service loaded inactive dead
+`
+	if b.String() != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, b.String()))
+	}
+}
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+
+	c, fake, _, cleanup := newTestClient(t, nil)
+	defer cleanup()
+
+	var out syncBuffer
+
+	if err := c.Create([]string{"router@1"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Journal([]string{"router"}, backend.JournalOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Journal([]string{"builder"}, backend.JournalOptions{}); err == nil {
+		t.Error("Expected an error for a unit which is not installed")
+	}
+
+	ran := fake.ran()
+	expected := "journalctl --unit deis-router@1.service --no-pager --output json -n 40"
+	if ran[len(ran)-1] != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, ran[len(ran)-1]))
+	}
+
+	// @* stands for every instance
+	if err := c.Journal([]string{"router@*"}, backend.JournalOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ran = fake.ran()
+	if ran[len(ran)-1] != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, ran[len(ran)-1]))
+	}
+}
+
+func TestUnitStates(t *testing.T) {
+	t.Parallel()
+
+	c, _, _, cleanup := newTestClient(t, nil)
+	defer cleanup()
+
+	var out syncBuffer
+
+	if err := c.Create([]string{"router@1", "router@2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start([]string{"router@2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err := c.UnitStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, _ := os.Hostname()
+	if len(states) != 2 || states[0].Active != "inactive" || states[1].Active != "active" ||
+		states[1].Host != host {
+		t.Error(fmt.Errorf("Expected router@1 inactive and router@2 active on %s, Got %v", host, states))
+	}
+}
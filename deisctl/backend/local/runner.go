@@ -0,0 +1,76 @@
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// commandRunner runs the systemctl, journalctl and docker commands that drive the local
+// machine, so that tests can record them instead.
+type commandRunner interface {
+	// Run runs a command, writing its output to out. Failures include what it printed
+	// to stderr.
+	Run(out io.Writer, name string, args ...string) error
+	// Interactive runs a command attached to the terminal.
+	Interactive(name string, args ...string) error
+}
+
+type execRunner struct{}
+
+func (execRunner) Run(out io.Writer, name string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, msg)
+		}
+		return fmt.Errorf("%s %s: %v", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func (execRunner) Interactive(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// systemctl runs systemctl and returns its output.
+func (c *LocalClient) systemctl(args ...string) (string, error) {
+	var out bytes.Buffer
+	err := c.runner.Run(&out, "systemctl", args...)
+	return out.String(), err
+}
+
+// unitState returns the systemd load, active and sub states of a unit.
+func (c *LocalClient) unitState(name string) (load, active, sub string, err error) {
+	props, err := c.unitProperties(name, "LoadState", "ActiveState", "SubState")
+	if err != nil {
+		return
+	}
+	return props["LoadState"], props["ActiveState"], props["SubState"], nil
+}
+
+// unitProperties returns the requested properties of a unit as reported by systemctl show.
+func (c *LocalClient) unitProperties(name string, properties ...string) (map[string]string, error) {
+	out, err := c.systemctl("show", "--property="+strings.Join(properties, ","), name)
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) == 2 {
+			props[parts[0]] = parts[1]
+		}
+	}
+	return props, nil
+}
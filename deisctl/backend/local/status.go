@@ -0,0 +1,198 @@
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+)
+
+// journalLines is how many lines of each unit's journal Journal prints.
+const journalLines = 40
+
+// ListUnits prints the systemd state of every installed unit
+func (c *LocalClient) ListUnits() error {
+	units, err := c.installedUnits()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.out, "UNIT\tLOAD\tACTIVE\tSUB")
+	for _, name := range units {
+		load, active, sub, err := c.unitState(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "%s\t%s\t%s\t%s\n", name, load, active, sub)
+	}
+	c.out.Flush()
+	return nil
+}
+
+// timestampLayout is how systemctl show formats timestamps.
+const timestampLayout = "Mon 2006-01-02 15:04:05 MST"
+
+// UnitStates returns the state of every installed unit, all of which run on this machine
+func (c *LocalClient) UnitStates() ([]backend.UnitState, error) {
+	units, err := c.installedUnits()
+	if err != nil {
+		return nil, err
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	var states []backend.UnitState
+	for _, name := range units {
+		props, err := c.unitProperties(name, "ActiveState", "SubState", "ActiveEnterTimestamp")
+		if err != nil {
+			return nil, err
+		}
+		state := backend.UnitState{Name: name, Active: props["ActiveState"], Sub: props["SubState"], Host: host}
+		if props["ActiveState"] == "active" {
+			if since, err := time.Parse(timestampLayout, props["ActiveEnterTimestamp"]); err == nil {
+				state.Since = since
+			}
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// ActiveSince returns the states as they are, since UnitStates reads when units became
+// active from systemd on this machine.
+func (c *LocalClient) ActiveSince(states []backend.UnitState) ([]backend.UnitState, error) {
+	return states, nil
+}
+
+// ListUnitFiles prints every installed unit file and its description
+func (c *LocalClient) ListUnitFiles() error {
+	units, err := c.installedUnits()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.out, "UNIT\tPATH\tDESCRIPTION")
+	for _, name := range units {
+		out, err := c.systemctl("show", "--property=Description", name)
+		if err != nil {
+			return err
+		}
+		desc := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(out), "Description="))
+		if desc == "" {
+			desc = "-"
+		}
+		fmt.Fprintf(c.out, "%s\t%s\t%s\n", name, c.unitPath(name), desc)
+	}
+	c.out.Flush()
+	return nil
+}
+
+// Status prints the systemctl status of the target unit(s)
+func (c *LocalClient) Status(target string) error {
+	units, err := c.Units(target)
+	if err != nil {
+		return err
+	}
+	// systemctl status exits non-zero for units which are not running, which is not a
+	// failure to report their status
+	args := append([]string{"status", "--no-pager", "--full"}, units...)
+	c.runner.Run(os.Stdout, "systemctl", args...)
+	return nil
+}
+
+// Journal prints the systemd journals of the target units, interleaved by time. A target
+// ending in @* stands for every instance of a component.
+func (c *LocalClient) Journal(targets []string, opts backend.JournalOptions) error {
+	var names []string
+	for _, target := range targets {
+		units, err := c.Units(strings.TrimSuffix(target, "*"))
+		if err != nil {
+			return err
+		}
+		names = append(names, units...)
+	}
+	printer, err := backend.NewJournalPrinter(os.Stdout, names, opts)
+	if err != nil {
+		return err
+	}
+
+	// a single journalctl interleaves the journals of every unit
+	var args []string
+	for _, name := range names {
+		args = append(args, "--unit", name)
+	}
+	args = append(args, opts.JournalctlArgs(journalLines)...)
+
+	r, w := io.Pipe()
+	entries := make(chan backend.JournalEntry)
+	done := make(chan error, 1)
+	go func() {
+		err := c.runner.Run(w, "journalctl", args...)
+		w.Close()
+		done <- err
+	}()
+	go func() {
+		backend.ReadJournal(r, "", entries)
+		close(entries)
+	}()
+	printer.Print(entries)
+	return <-done
+}
+
+// SSH opens a shell on this machine, which runs every unit
+func (c *LocalClient) SSH(name string) error {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	return c.runner.Interactive(shell)
+}
+
+// Machines returns this machine, which runs every unit
+func (c *LocalClient) Machines() ([]backend.Machine, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return []backend.Machine{{ID: host, Host: host}}, nil
+}
+
+// Exec runs a command on this machine, the only one Machines returns
+func (c *LocalClient) Exec(machines []backend.Machine, cmd string, out, ew io.Writer) error {
+	for _, m := range machines {
+		var output bytes.Buffer
+		err := c.runner.Run(&output, "/bin/sh", "-c", "("+cmd+") 2>&1")
+		fmt.Fprintf(out, "==> %s <==\n", m.Host)
+		out.Write(output.Bytes())
+		if err != nil {
+			return fmt.Errorf("%s: %v", m.Host, err)
+		}
+	}
+	return nil
+}
+
+// SSHExec runs a command on this machine, which runs every unit
+func (c *LocalClient) SSHExec(name, cmd string) error {
+	fmt.Printf("Executing '%s' locally\n", cmd)
+	return c.runner.Interactive("/bin/sh", "-c", cmd)
+}
+
+// Dock runs 'docker exec -it' in the container of the target unit.
+func (c *LocalClient) Dock(target string, cmd []string) error {
+	units, err := c.Units(target)
+	if err != nil {
+		return err
+	}
+	container := strings.TrimSuffix(units[0], ".service")
+
+	if len(cmd) == 0 {
+		cmd = []string{"sh"}
+	}
+	return c.runner.Interactive("docker", append([]string{"exec", "-it", container}, cmd...)...)
+}
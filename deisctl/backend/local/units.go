@@ -0,0 +1,305 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/pkg/prettyprint"
+)
+
+var stateFmt = prettyprint.Colorize("{{.Yellow}}%v:{{.Default}} %v")
+
+// Create renders the unit files for the given components into the unit directory and
+// reloads systemd. Units that are already installed are left alone.
+func (c *LocalClient) Create(targets []string, out io.Writer) error {
+	type unitFile struct {
+		name     string
+		contents string
+	}
+
+	var files []unitFile
+	for _, target := range targets {
+		component, _, err := splitTarget(target)
+		if err != nil {
+			return fmt.Errorf("Error creating: %s", err)
+		}
+		name, err := unitName(target)
+		if err != nil {
+			return fmt.Errorf("Error creating: %s", err)
+		}
+		contents, err := c.render(component)
+		if err != nil {
+			return fmt.Errorf("Error creating: %s", err)
+		}
+		files = append(files, unitFile{name, contents})
+	}
+
+	for _, f := range files {
+		if _, err := os.Stat(c.unitPath(f.name)); err == nil {
+			continue
+		}
+		if err := ioutil.WriteFile(c.unitPath(f.name), []byte(f.contents), 0644); err != nil {
+			return err
+		}
+	}
+	// systemd only notices new unit files once it is reloaded
+	if _, err := c.systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Fprintf(out, stateFmt+"\n", f.name, "loaded")
+	}
+	return nil
+}
+
+// Destroy stops the units for the given targets and removes their unit files.
+func (c *LocalClient) Destroy(targets []string, out io.Writer) error {
+	names, err := c.expandTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	errs := backend.NewErrors(nil)
+	var destroyed []string
+	for _, name := range names {
+		// a unit which was never installed is already gone
+		if !c.installed(name) {
+			fmt.Fprintf(out, stateFmt+"\n", name, "not installed")
+			continue
+		}
+		if _, err := c.systemctl("stop", name); err != nil {
+			errs.Add(err)
+			continue
+		}
+		if err := os.Remove(c.unitPath(name)); err != nil && !os.IsNotExist(err) {
+			errs.Add(err)
+			continue
+		}
+		destroyed = append(destroyed, name)
+	}
+	if _, err := c.systemctl("daemon-reload"); err != nil {
+		errs.Add(err)
+		return errs.Err()
+	}
+	for _, name := range destroyed {
+		fmt.Fprintf(out, stateFmt+"\n", name, "destroyed")
+	}
+	return errs.Err()
+}
+
+// Start starts the units for the given targets and reports the state they settle in.
+func (c *LocalClient) Start(targets []string, out io.Writer) error {
+	names, err := c.expandTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	return backend.InParallel(len(names), func(i int) error {
+		name := names[i]
+		// systemctl start returns once the unit's start-up commands have finished
+		if err := c.start(name); err != nil {
+			return fmt.Errorf("The service '%s' failed while starting: %v", name, err)
+		}
+		fmt.Fprintf(out, stateFmt+"\n", name, "running")
+		return nil
+	})
+}
+
+// start starts a unit and checks that it is still running afterwards.
+func (c *LocalClient) start(name string) error {
+	if _, err := c.systemctl("start", name); err != nil {
+		return err
+	}
+	_, active, sub, err := c.unitState(name)
+	if err != nil {
+		return err
+	}
+	if active != "active" {
+		return fmt.Errorf("unit is %s (%s)", active, sub)
+	}
+	return nil
+}
+
+// Stop stops the units for the given targets, leaving their unit files installed.
+func (c *LocalClient) Stop(targets []string, out io.Writer) error {
+	names, err := c.expandTargets(targets)
+	if err != nil {
+		return err
+	}
+
+	return backend.InParallel(len(names), func(i int) error {
+		name := names[i]
+		// a unit which was never installed is already stopped
+		if !c.installed(name) {
+			fmt.Fprintf(out, stateFmt+"\n", name, "not installed")
+			return nil
+		}
+		if _, err := c.systemctl("stop", name); err != nil {
+			return fmt.Errorf("The service '%s' failed while stopping: %v", name, err)
+		}
+		fmt.Fprintf(out, stateFmt+"\n", name, "dead")
+		return nil
+	})
+}
+
+// installed reports whether the unit file of a unit is in the unit directory.
+func (c *LocalClient) installed(name string) bool {
+	_, err := os.Stat(c.unitPath(name))
+	return err == nil
+}
+
+// Scale creates and starts or destroys instances of a component to match the requested number
+func (c *LocalClient) Scale(component string, requested int, out io.Writer) error {
+	if requested < 0 {
+		return errors.New("cannot scale below 0")
+	}
+	// check how many currently exist
+	units, err := c.Units(component + "@")
+	if err != nil && !strings.Contains(err.Error(), "could not find unit") {
+		return err
+	}
+
+	var existing []int
+	for _, u := range units {
+		_, num, err := splitTarget(u)
+		if err != nil {
+			return err
+		}
+		existing = append(existing, num)
+	}
+	sort.Ints(existing)
+
+	// new instances take the lowest free numbers, and the highest numbers are removed
+	switch {
+	case requested > len(existing):
+		var targets []string
+		for i, j := 1, 0; len(existing)+len(targets) < requested; i++ {
+			if j < len(existing) && existing[j] == i {
+				j++
+				continue
+			}
+			targets = append(targets, component+"@"+strconv.Itoa(i))
+		}
+		if err := c.Create(targets, out); err != nil {
+			return err
+		}
+		return c.Start(targets, out)
+	case requested < len(existing):
+		var targets []string
+		for i := len(existing) - 1; i >= requested; i-- {
+			targets = append(targets, component+"@"+strconv.Itoa(existing[i]))
+		}
+		return c.Destroy(targets, out)
+	}
+	return nil
+}
+
+// RollingRestart restarts the instances of a component opts.MaxUnavailable at a time,
+// waiting for each batch to be healthy before moving on. Surging is not possible, since
+// every instance of a component runs a container of the same name on this machine.
+func (c *LocalClient) RollingRestart(component string, opts backend.RollingRestartOptions, out io.Writer) error {
+	if opts.MaxUnavailable < 0 {
+		return errors.New("max unavailable cannot be below 0")
+	}
+	if opts.MaxUnavailable == 0 {
+		return fmt.Errorf("%s cannot be surged on a single machine, use --max-unavailable=1", component)
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = c.timeout
+	}
+
+	units, err := c.Units(component)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(units); i += opts.MaxUnavailable {
+		end := i + opts.MaxUnavailable
+		if end > len(units) {
+			end = len(units)
+		}
+		if err := c.restart(units[i:end], opts, out); err != nil {
+			return fmt.Errorf("rolling restart of %s aborted: %v", component, err)
+		}
+	}
+	return nil
+}
+
+// Rebalance has nothing to move, since every instance runs on this machine.
+func (c *LocalClient) Rebalance(component string, out io.Writer) error {
+	fmt.Fprintf(out, "%s is already balanced across 1 machine\n", component)
+	return nil
+}
+
+// restart restarts a batch of units together and waits for each of them to be healthy.
+func (c *LocalClient) restart(names []string, opts backend.RollingRestartOptions, out io.Writer) error {
+	if _, err := c.systemctl(append([]string{"restart"}, names...)...); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := c.waitForHealthy(name, opts); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, stateFmt+"\n", name, "restarted")
+	}
+	return nil
+}
+
+// waitForHealthy waits for a unit to be running and, if asked, to publish its health key.
+func (c *LocalClient) waitForHealthy(name string, opts backend.RollingRestartOptions) error {
+	err := c.pollFor(opts.Timeout, func() (bool, error) {
+		_, active, sub, err := c.unitState(name)
+		if err != nil {
+			return false, err
+		}
+		if active == "failed" {
+			return false, fmt.Errorf("%s failed (%s)", name, sub)
+		}
+		return active == "active", nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.HealthKey != "" {
+		_, num, err := splitTarget(name)
+		if err != nil {
+			return err
+		}
+		key := strings.Replace(opts.HealthKey, "{instance}", strconv.Itoa(num), -1)
+		err = c.pollFor(opts.Timeout, func() (bool, error) {
+			_, err := c.configBackend.Get(key)
+			return err == nil, nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s was not published: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// pollFor calls done until it reports true, fails, or the timeout passes.
+func (c *LocalClient) pollFor(timeout time.Duration, done func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+		time.Sleep(c.pollInterval)
+	}
+}
@@ -0,0 +1,75 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Machine is a host of the cluster which runs units.
+type Machine struct {
+	ID string
+	// Host is the address of the machine, as in UnitState.Host.
+	Host     string
+	Metadata map[string]string
+}
+
+// MetadataFilter selects machines by their metadata as fleet's MachineMetadata does: a
+// machine matches if it has one of the values given for each key.
+type MetadataFilter map[string][]string
+
+// ParseMetadataFilter parses a filter such as "role=control,region=us-east-1". A key may
+// be repeated to allow any of its values.
+func ParseMetadataFilter(s string) (MetadataFilter, error) {
+	f := make(MetadataFilter)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid machine metadata %q, expected key=value", pair)
+		}
+		f[kv[0]] = append(f[kv[0]], kv[1])
+	}
+	return f, nil
+}
+
+// Match returns true if the metadata of a machine satisfies the filter.
+func (f MetadataFilter) Match(metadata map[string]string) bool {
+	for key, values := range f {
+		found := false
+		for _, v := range values {
+			if metadata[key] == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (f MetadataFilter) String() string {
+	var pairs []string
+	for key, values := range f {
+		for _, v := range values {
+			pairs = append(pairs, key+"="+v)
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// FilterMachines returns the machines which match a filter.
+func FilterMachines(machines []Machine, f MetadataFilter) []Machine {
+	var matched []Machine
+	for _, m := range machines {
+		if f.Match(m.Metadata) {
+			matched = append(matched, m)
+		}
+	}
+	return matched
+}
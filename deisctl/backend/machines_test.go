@@ -0,0 +1,38 @@
+package backend
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMetadataFilter(t *testing.T) {
+	t.Parallel()
+
+	f, err := ParseMetadataFilter("role=control, role=dataPlane,region=us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := f.String(); s != "region=us-east-1,role=control,role=dataPlane" {
+		t.Error(fmt.Errorf("Expected region=us-east-1,role=control,role=dataPlane, Got %s", s))
+	}
+
+	machines := []Machine{
+		{ID: "a", Metadata: map[string]string{"role": "control", "region": "us-east-1"}},
+		{ID: "b", Metadata: map[string]string{"role": "router", "region": "us-east-1"}},
+		{ID: "c", Metadata: map[string]string{"role": "dataPlane"}},
+		{ID: "d", Metadata: map[string]string{"role": "dataPlane", "region": "us-east-1"}},
+	}
+	matched := FilterMachines(machines, f)
+	if len(matched) != 2 || matched[0].ID != "a" || matched[1].ID != "d" {
+		t.Error(fmt.Errorf("Expected machines a and d, Got %v", matched))
+	}
+
+	// an empty filter matches every machine
+	if f, _ := ParseMetadataFilter(""); len(FilterMachines(machines, f)) != len(machines) {
+		t.Error(fmt.Errorf("Expected every machine to match an empty filter"))
+	}
+
+	if _, err := ParseMetadataFilter("role"); err == nil {
+		t.Error(fmt.Errorf("Expected an invalid metadata error, Got nil"))
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/deis/deis/deisctl/backend"
 	"github.com/deis/deis/deisctl/backend/fleet"
+	"github.com/deis/deis/deisctl/backend/kubernetes"
+	"github.com/deis/deis/deisctl/backend/local"
 	"github.com/deis/deis/deisctl/cmd"
 	"github.com/deis/deis/deisctl/config"
 	"github.com/deis/deis/deisctl/config/etcd"
@@ -18,11 +22,18 @@ import (
 
 // DeisCtlClient manages Deis components, configuration, and related tasks.
 type DeisCtlClient interface {
+	Backup(argv []string) error
 	Config(argv []string) error
+	Doctor(argv []string) error
+	Exec(argv []string) error
+	Health(argv []string) error
 	Install(argv []string) error
 	Journal(argv []string) error
 	List(argv []string) error
+	Machines(argv []string) error
+	Rebalance(argv []string) error
 	RefreshUnits(argv []string) error
+	Render(argv []string) error
 	Restart(argv []string) error
 	Scale(argv []string) error
 	SSH(argv []string) error
@@ -30,6 +41,7 @@ type DeisCtlClient interface {
 	Status(argv []string) error
 	Stop(argv []string) error
 	Uninstall(argv []string) error
+	Upgrade(argv []string) error
 	UpgradePrep(argv []string) error
 	UpgradeTakeover(argv []string) error
 	RollingRestart(argv []string) error
@@ -39,10 +51,12 @@ type DeisCtlClient interface {
 type Client struct {
 	Backend       backend.Backend
 	configBackend config.Backend
+	// manifestErr is why the custom components could not be read, if they could not
+	manifestErr error
 }
 
 // NewClient returns a Client using the requested backend.
-// The only backend currently supported is "fleet".
+// The backends currently supported are "fleet", "kubernetes" and "local".
 func NewClient(requestedBackend string) (*Client, error) {
 	var backend backend.Backend
 
@@ -51,6 +65,16 @@ func NewClient(requestedBackend string) (*Client, error) {
 		return nil, err
 	}
 
+	// custom components declared next to the unit templates are part of the platform. A
+	// malformed manifest only fails the commands which manage the platform's components.
+	custom, manifestErr := units.Custom(units.TemplatePaths())
+	if manifestErr == nil {
+		manifestErr = cmd.RegisterComponents(custom)
+	}
+	if manifestErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: skipping custom components: %v\n", manifestErr)
+	}
+
 	if requestedBackend == "" {
 		requestedBackend = "fleet"
 	}
@@ -62,11 +86,70 @@ func NewClient(requestedBackend string) (*Client, error) {
 			return nil, err
 		}
 		backend = b
+	case "kubernetes":
+		b, err := kubernetes.NewClient(cb)
+		if err != nil {
+			return nil, err
+		}
+		backend = b
+	case "local":
+		b, err := local.NewClient(cb)
+		if err != nil {
+			return nil, err
+		}
+		backend = b
 	default:
 		return nil, errors.New("invalid backend")
 	}
 
-	return &Client{Backend: backend, configBackend: cb}, nil
+	return &Client{Backend: backend, configBackend: cb, manifestErr: manifestErr}, nil
+}
+
+// checkComponents returns an error if the custom components could not be read, for the
+// commands which would otherwise leave them out of the platform.
+func (c *Client) checkComponents() error {
+	if c.manifestErr != nil {
+		return fmt.Errorf("custom components: %v", c.manifestErr)
+	}
+	return nil
+}
+
+// Upgrade gracefully upgrades the platform to another release
+func (c *Client) Upgrade(argv []string) error {
+	usage := `Gracefully upgrades the platform to another release.
+
+Preflight checks make sure that every etcd member is healthy, that the required
+configuration is set and that the unit files of the release can be downloaded,
+before anything is changed. The upgrade then installs the unit files, stops the
+control plane while the routers and publisher keep serving applications, pins the
+platform version, republishes the services, restarts the routers one at a time and
+starts the platform again.
+
+Progress is recorded in etcd under /deis/upgrade. If the upgrade is interrupted,
+running the same command again resumes it after the last completed step. The
+services are republished again before the routers are restarted by a resumed
+upgrade, in case they expired in the meantime.
+
+Usage:
+  deisctl upgrade --to=<version> [options]
+
+Options:
+  --to=<version>        release to upgrade to, such as 1.10.0
+  -p --path=<target>    where to save unit files [default: $HOME/.deis/units]
+
+Examples:
+  deisctl upgrade --to=1.10.0
+`
+	args, err := docopt.Parse(usage, argv, true, "", false)
+	if err != nil {
+		return err
+	}
+
+	if err := c.checkComponents(); err != nil {
+		return err
+	}
+	return cmd.Upgrade(args["--to"].(string), args["--path"].(string), units.URL, c.Backend,
+		c.configBackend, cmd.CheckRequiredKeys)
 }
 
 // UpgradePrep prepares a running cluster to be upgraded
@@ -80,6 +163,9 @@ Usage:
 		return err
 	}
 
+	if err := c.checkComponents(); err != nil {
+		return err
+	}
 	return cmd.UpgradePrep(c.Backend)
 }
 
@@ -94,22 +180,129 @@ Usage:
 		return err
 	}
 
+	if err := c.checkComponents(); err != nil {
+		return err
+	}
 	return cmd.UpgradeTakeover(c.Backend, c.configBackend)
 }
 
-// RollingRestart attempts a rolling restart of an instance unit
+// RollingRestart replaces the instances of a component without taking it down
 func (c *Client) RollingRestart(argv []string) error {
-	usage := `Perform a rolling restart of an instance unit.
+	usage := `Perform a rolling restart of a component.
+
+Instances are replaced a few at a time. Each replacement must be running, and
+must have published the health key if one is given, before the next batch is
+restarted. The restart is aborted as soon as a replacement fails or times out.
+
+With --max-unavailable=0, the component is surged instead: a temporary
+instance is started under the next free instance number, serves while an
+existing instance restarts in place, and is removed once that instance is
+healthy. This is repeated for each instance, so the instances keep their
+numbers. A singleton component is surged through a temporary copy on another
+machine in the same way.
 
 Usage:
-  deisctl rolling-restart <target>
+  deisctl rolling-restart <target> [options]
+
+Options:
+  --max-unavailable=<num>   instances which may be down at once [default: 1]
+  --health-key=<key>        key which must exist before an instance is healthy,
+                            with {instance} replaced by the instance number
+  --timeout=<secs>          seconds to wait for each replacement [default: 1200]
+
+Examples:
+  deisctl rolling-restart router
+  deisctl rolling-restart registry --max-unavailable=0
+  deisctl rolling-restart store-gateway --health-key=/deis/store/gateway/{instance}/healthy
 `
 	args, err := docopt.Parse(usage, argv, true, "", false)
 	if err != nil {
 		return err
 	}
 
-	return cmd.RollingRestart(args["<target>"].(string), c.Backend)
+	maxUnavailable, err := strconv.Atoi(args["--max-unavailable"].(string))
+	if err != nil {
+		return fmt.Errorf("invalid --max-unavailable: %v", err)
+	}
+	timeout, err := strconv.Atoi(args["--timeout"].(string))
+	if err != nil {
+		return fmt.Errorf("invalid --timeout: %v", err)
+	}
+
+	opts := backend.RollingRestartOptions{
+		MaxUnavailable: maxUnavailable,
+		Timeout:        time.Duration(timeout) * time.Second,
+	}
+	if key, ok := args["--health-key"].(string); ok {
+		opts.HealthKey = key
+	}
+
+	return cmd.RollingRestart(args["<target>"].(string), opts, c.Backend)
+}
+
+// Rebalance spreads the instances of a component evenly across the machines they may run on
+func (c *Client) Rebalance(argv []string) error {
+	usage := `Spreads the instances of a component evenly across the machines it may run on.
+
+The machines are those matching the MachineMetadata of the component's unit, as set
+by its decorator or "deisctl config units set machineMetadata". Instances on other
+machines are moved first, then instances are moved one at a time from the busiest
+machine to the idlest until no machine runs more than one instance above another.
+
+Each instance is moved by stopping, destroying, creating and starting it with fleet
+Conflicts options on its siblings running on other machines, so fleet places it on a
+machine as idle as the one planned. Instances are not pinned to a machine, so fleet
+can still reschedule them if that machine fails. Each moved instance must be running
+before the next is moved.
+
+Usage:
+  deisctl rebalance <component>
+
+Examples:
+  deisctl rebalance router
+`
+	// parse command-line arguments
+	args, err := docopt.Parse(usage, argv, true, "", false)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Rebalance(args["<component>"].(string), c.Backend)
+}
+
+// Backup creates, lists or restores backups of the database and the platform configuration.
+func (c *Client) Backup(argv []string) error {
+	usage := `Backs up or restores the database and the configuration of the platform.
+
+"deisctl backup create" pushes a base backup of the database to the store with wal-e,
+as the database does every few hours, and saves the configuration of the platform as
+"deisctl config export" would. Both are recorded in etcd under /deis/backups.
+
+"deisctl backup restore" returns the platform to a backup. The controller and builder
+are stopped, then the database. The configuration is restored, and the database
+restores its base backup, replaying the write-ahead log up to the time the backup
+finished, before the controller and builder are started again. The routers keep
+serving applications throughout.
+
+Usage:
+  deisctl backup create
+  deisctl backup list
+  deisctl backup restore <id>
+`
+	// parse command-line arguments
+	args, err := docopt.Parse(usage, argv, true, "", false)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args["create"] == true:
+		return cmd.BackupCreate(c.Backend, c.configBackend)
+	case args["restore"] == true:
+		return cmd.BackupRestore(args["<id>"].(string), c.Backend, c.configBackend)
+	default:
+		return cmd.BackupList(c.configBackend)
+	}
 }
 
 // Config gets or sets a configuration value from the cluster.
@@ -128,16 +321,45 @@ enabling TLS for the routers.
 Note: "deisctl config platform set sshPrivateKey=" expects a path
 to a private key.
 
+Values of the settings known for the platform, controller, cache and router
+are checked before being saved, and setting a key these components do not
+read prints a warning. "deisctl config <target> list" shows every known
+setting with its current and default values.
+
+"deisctl config export" writes the configuration of the platform as YAML, which
+"deisctl config import" restores from a file or from standard input. Keys
+published with a TTL by running components, and application state managed by
+the controller, are not exported. Secrets are exported as they are stored, so
+keep exports safe.
+
+Options:
+  --components=<components>  comma-separated components to export [default: all].
+  --dry-run                  print the changes an import would make without making them.
+
+"deisctl config router whitelist" manages the IPv4 addresses and CIDR blocks
+allowed to reach the controller through the routers. Addresses are validated
+before being saved, and removing the last address removes the whitelist.
+
 Usage:
   deisctl config <target> get [<key>...]
   deisctl config <target> set <key=val>...
   deisctl config <target> rm [<key>...]
+  deisctl config <target> list
+  deisctl config export [--components=<components>]
+  deisctl config import [--dry-run] [<file>]
+  deisctl config router whitelist [list]
+  deisctl config router whitelist add <address>...
+  deisctl config router whitelist remove <address>...
 
 Examples:
   deisctl config platform set domain=mydomain.com
   deisctl config platform set sshPrivateKey=$HOME/.ssh/deis
   deisctl config controller get webEnabled
   deisctl config controller rm webEnabled
+  deisctl config router list
+  deisctl config export --components=platform,router > deis-config.yaml
+  deisctl config import --dry-run deis-config.yaml
+  deisctl config router whitelist add 10.0.0.0/8:vpn 192.168.1.10
 `
 	// parse command-line arguments
 	args, err := docopt.Parse(usage, argv, true, "", false)
@@ -145,6 +367,31 @@ Examples:
 		return err
 	}
 
+	if args["export"] == true {
+		var components []string
+		if list := args["--components"].(string); list != "all" {
+			components = strings.Split(list, ",")
+		}
+		return cmd.ConfigExport(components, c.configBackend)
+	}
+
+	if args["import"] == true {
+		file, _ := args["<file>"].(string)
+		return cmd.ConfigImport(file, args["--dry-run"].(bool), c.configBackend)
+	}
+
+	if args["whitelist"] == true {
+		action := "list"
+		switch {
+		case args["add"] == true:
+			action = "add"
+		case args["remove"] == true:
+			action = "remove"
+		}
+		addresses, _ := args["<address>"].([]string)
+		return cmd.ControllerWhitelist(action, addresses, c.configBackend)
+	}
+
 	var action string
 	var key []string
 
@@ -155,6 +402,8 @@ Examples:
 	case args["rm"] == true:
 		action = "rm"
 		key = args["<key>"].([]string)
+	case args["list"] == true:
+		action = "list"
 	default:
 		action = "get"
 		key = args["<key>"].([]string)
@@ -195,6 +444,9 @@ Options:
 	}
 	cmd.RouterMeshSize = uint8(parsedValue)
 
+	if err := c.checkComponents(); err != nil {
+		return err
+	}
 	return cmd.Install(args["<target>"].([]string), c.Backend, c.configBackend, cmd.CheckRequiredKeys)
 }
 
@@ -202,8 +454,19 @@ Options:
 func (c *Client) Journal(argv []string) error {
 	usage := `Prints log output for the specified components.
 
+The log output of every matching unit is read concurrently and interleaved by time, with
+each line prefixed by its unit.
+
 Usage:
   deisctl journal [<target>...] [options]
+
+Options:
+  -f --follow        keep printing log output as it is written, until interrupted
+  --since=<since>    only print log output written since a duration ago, such as 10m, or
+                     since a time, such as "2016-01-02 15:04:05"
+  --grep=<regexp>    only print lines matching a regular expression
+  -n --lines=<n>     how many of the latest lines of each unit to print, unless --since
+                     is given
 `
 	// parse command-line arguments
 	args, err := docopt.Parse(usage, argv, true, "", false)
@@ -211,7 +474,16 @@ Usage:
 		return err
 	}
 
-	return cmd.Journal(args["<target>"].([]string), c.Backend)
+	opts := backend.JournalOptions{Follow: args["--follow"].(bool)}
+	opts.Since, _ = args["--since"].(string)
+	opts.Grep, _ = args["--grep"].(string)
+	if lines, ok := args["--lines"].(string); ok {
+		if opts.Lines, err = strconv.Atoi(lines); err != nil || opts.Lines < 1 {
+			return fmt.Errorf("--lines must be a positive number, got %s", lines)
+		}
+	}
+
+	return cmd.Journal(args["<target>"].([]string), opts, c.Backend)
 }
 
 // List prints a summary of installed components.
@@ -228,12 +500,41 @@ Usage:
 	return cmd.ListUnits(c.Backend)
 }
 
+// Render prints the unit files of components with their variables filled in.
+func (c *Client) Render(argv []string) error {
+	usage := `Prints the unit files of components as "deisctl install" would load them.
+
+Unit templates may refer to variables set with "deisctl config units set", such as
+imageRegistry, imageTag, memoryLimit, dockerArgs and machineMetadata. A variable set
+for one component, such as router/memoryLimit, overrides the one set for every component.
+
+Usage:
+  deisctl render <target>... [options]
+`
+	// parse command-line arguments
+	args, err := docopt.Parse(usage, argv, true, "", false)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Render(args["<target>"].([]string), c.configBackend)
+}
+
 // RefreshUnits overwrites local unit files with those requested.
 func (c *Client) RefreshUnits(argv []string) error {
 	usage := `Overwrites local unit files with those requested.
 
-Downloading from the Deis project GitHub URL by tag or SHA is the only mechanism
-currently supported.
+Unit files are downloaded from the Deis project GitHub URL by tag or SHA, unless --source
+names a directory, a tar archive (optionally gzipped) or the URL of a mirror which holds
+them as laid out in deisctl/units. Nothing is overwritten until every file has been read
+and verified. The target directory is then switched over to the refreshed files at once
+through a symlink, so files the source no longer holds do not remain. Files which belong
+to no component are carried over.
+
+Files from a --source are verified against the SHA256SUMS manifest it holds, in the format
+written by sha256sum, or against the manifest given with --checksums. Files from GitHub
+are only verified when --checksums is given. --signature also verifies a detached gpg
+signature of the manifest.
 
 "deisctl install" looks for unit files in these directories, in this order:
 - the $DEISCTL_UNITS environment variable, if set
@@ -241,12 +542,18 @@ currently supported.
 - /var/lib/deis/units
 
 Usage:
-  deisctl refresh-units [-p <target>] [-t <tag>]
+  deisctl refresh-units [-p <target>] [-t <tag>] [-s <source>] [--checksums=<manifest>]
+                        [--signature=<signature> [--keyring=<keyring>]] [--no-verify]
 
 Options:
-  -p --path=<target>   where to save unit files [default: $HOME/.deis/units]
-  -t --tag=<tag>       git tag, branch, or SHA to use when downloading unit files
-                       [default: master]
+  -p --path=<target>         where to save unit files [default: $HOME/.deis/units]
+  -t --tag=<tag>             git tag, branch, or SHA to use when downloading unit files
+                             [default: master]
+  -s --source=<source>       directory, tar archive or mirror URL to read unit files from
+  --checksums=<manifest>     path or URL of a SHA256SUMS manifest to verify the files against
+  --signature=<signature>    path or URL of a detached gpg signature of the manifest
+  --keyring=<keyring>        gpg keyring holding the signing key, instead of the default one
+  --no-verify                refresh from a source without verifying the files
 `
 	// parse command-line arguments
 	args, err := docopt.Parse(usage, argv, true, "", false)
@@ -255,7 +562,17 @@ Options:
 		os.Exit(2)
 	}
 
-	return cmd.RefreshUnits(args["--path"].(string), args["--tag"].(string), units.URL)
+	opts := cmd.RefreshOptions{
+		Tag:      args["--tag"].(string),
+		RootURL:  units.URL,
+		NoVerify: args["--no-verify"].(bool),
+	}
+	opts.Source, _ = args["--source"].(string)
+	opts.Checksums, _ = args["--checksums"].(string)
+	opts.Signature, _ = args["--signature"].(string)
+	opts.Keyring, _ = args["--keyring"].(string)
+
+	return cmd.RefreshUnitsFrom(args["--path"].(string), opts)
 }
 
 // Restart stops and then starts components.
@@ -271,6 +588,9 @@ Usage:
 		return err
 	}
 
+	if err := c.checkComponents(); err != nil {
+		return err
+	}
 	return cmd.Restart(args["<target>"].([]string), c.Backend)
 }
 
@@ -278,10 +598,20 @@ Usage:
 func (c *Client) Scale(argv []string) error {
 	usage := `Grows or shrinks the number of running components.
 
-Currently "router", "registry" and "store-gateway" are the only types that can be scaled.
+A component can be scaled when its unit template is named deis-<component>@.service,
+as those of the "router", "registry", "store-gateway" and "logspout" are, or when an
+older deis-<component>.service template conflicts with deis-<component>@*.service. A number
+prefixed with + or - adds or removes instances. Instances are stopped before they are
+destroyed, and with fleet, scaling up fails if too few machines match the unit's
+placement constraints. A component which runs on every machine, such as "logspout", is
+replaced by instances which each run on a machine of their own.
 
 Usage:
   deisctl scale [<target>...] [options]
+
+Examples:
+  deisctl scale router=3
+  deisctl scale router=+2 registry=-1
 `
 	// parse command-line arguments
 	args, err := docopt.Parse(usage, argv, true, "", false)
@@ -322,6 +652,49 @@ Usage:
 	return cmd.SSH(target, vargs, c.Backend)
 }
 
+// Exec runs a command on every machine in the cluster, or on those matching metadata.
+func (c *Client) Exec(argv []string) error {
+	usage := `Runs a command in parallel on the machines of the cluster.
+
+The output of each machine is printed under a header once the command has finished on
+every machine. deisctl exits non-zero if the command failed on any of them. Put the
+command after "--" if it has options of its own. Each argument is quoted for the remote
+shell, so run pipelines and other shell syntax with sh -c '<script>'.
+
+Usage:
+  deisctl exec [--machines=<metadata>] [--] <command>...
+
+Options:
+  --machines=<metadata>  only run on machines with this fleet metadata, such as
+                         role=dataPlane. Separate pairs with commas; a repeated key
+                         matches any of its values [default: ]
+`
+	// parse command-line arguments
+	args, err := docopt.Parse(usage, argv, true, "", false)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Exec(args["--machines"].(string), args["<command>"].([]string), c.Backend)
+}
+
+// Machines prints the machines of the cluster with their metadata and units.
+func (c *Client) Machines(argv []string) error {
+	usage := `Prints the machines of the cluster with their metadata and the units on each.
+
+Units which are not scheduled on any machine are listed last. Use "deisctl rebalance"
+to spread the instances of a component which ended up on too few machines.
+
+Usage:
+  deisctl machines
+`
+	// parse command-line arguments
+	if _, err := docopt.Parse(usage, argv, true, "", false); err != nil {
+		return err
+	}
+	return cmd.Machines(c.Backend)
+}
+
 func (c *Client) Dock(argv []string) error {
 	usage := `Connect to the named docker container and run commands on it.
 
@@ -364,6 +737,9 @@ Usage:
 		return err
 	}
 
+	if err := c.checkComponents(); err != nil {
+		return err
+	}
 	return cmd.Start(args["<target>"].([]string), c.Backend)
 }
 
@@ -383,6 +759,55 @@ Usage:
 	return cmd.Status(args["<target>"].([]string), c.Backend)
 }
 
+// Health prints the state of every installed component and the keys it publishes.
+func (c *Client) Health(argv []string) error {
+	usage := `Prints the state of every installed component, the host it runs on and its uptime,
+and checks that the keys each component publishes in etcd are present and fresh.
+
+Exits non-zero if any component is not running or any of its keys is missing or stale.
+
+Usage:
+  deisctl health [options]
+`
+	// parse command-line arguments
+	if _, err := docopt.Parse(usage, argv, true, "", false); err != nil {
+		return err
+	}
+
+	if err := c.checkComponents(); err != nil {
+		return err
+	}
+	return cmd.Health(c.Backend, c.configBackend)
+}
+
+// Doctor checks that the cluster is ready for the platform to be installed.
+func (c *Client) Doctor(argv []string) error {
+	usage := `Checks that the cluster is ready for the platform to be installed.
+
+Each check prints PASS, WARN or FAIL, with a hint on how to fix what did not pass:
+
+  placement  enough machines match the MachineMetadata of each component
+  etcd       every etcd member is healthy and runs etcd 2
+  ssh        every machine can be reached to run the checks below
+  clock      the clocks of the machines agree within a few seconds
+  docker     Docker is running and recent enough on every machine
+  ports      the ports of components not yet installed are free
+  domain     the platform domain is set and deis.<domain> resolves
+  ssl        the router certificate matches its key and covers the domain
+
+Exits non-zero if any check fails.
+
+Usage:
+  deisctl doctor
+`
+	// parse command-line arguments
+	if _, err := docopt.Parse(usage, argv, true, "", false); err != nil {
+		return err
+	}
+
+	return cmd.Doctor(c.Backend, c.configBackend)
+}
+
 // Stop deactivates the specified components.
 func (c *Client) Stop(argv []string) error {
 	usage := `Deactivates the specified components.
@@ -396,6 +821,9 @@ Usage:
 		return err
 	}
 
+	if err := c.checkComponents(); err != nil {
+		return err
+	}
 	return cmd.Stop(args["<target>"].([]string), c.Backend)
 }
 
@@ -415,5 +843,8 @@ Usage:
 		return err
 	}
 
+	if err := c.checkComponents(); err != nil {
+		return err
+	}
 	return cmd.Uninstall(args["<target>"].([]string), c.Backend)
 }
@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/config"
+)
+
+// backupKey is where `deisctl backup create` records the backups it makes, each under
+// /deis/backups/<id>.
+const backupKey = "/deis/backups"
+
+// restoreKey is read by the database component when it boots without a usable data
+// directory, to restore a base backup up to a point in time rather than the latest state.
+const restoreKey = "/deis/database/restore"
+
+// backupIDLayout names backups after the time they were made.
+const backupIDLayout = "20060102T150405Z"
+
+// walE runs wal-e in the database container as the database component does, with the
+// credentials of the store written to /etc/wal-e.d/env.
+const walE = "sudo -u postgres envdir /etc/wal-e.d/env wal-e"
+
+// backupCommand pushes a base backup of the database, then prints the time the backup
+// finished on the database's clock and the name wal-e gave it.
+const backupCommand = `docker exec deis-database sh -c '` + walE + ` backup-push /var/lib/postgresql/9.3/main && ` +
+	`date -u "+finished=%Y-%m-%d %H:%M:%S+00" && ` + walE + ` --terse backup-list LATEST'`
+
+// DatabaseTimeout bounds how long a restored database has to come back up.
+var DatabaseTimeout = 20 * time.Minute
+
+// backup describes a backup recorded in etcd.
+type backup struct {
+	ID string
+	// Database is the name wal-e gave the base backup of the database.
+	Database string
+	// Finished is when the base backup finished, on the database's clock, as PostgreSQL
+	// parses it in recovery_target_time.
+	Finished string
+	// Version is the platform version the backup was made with.
+	Version string
+	// Config is the configuration of the platform, as written by `deisctl config export`.
+	Config string
+}
+
+// BackupCreate pushes a base backup of the database to the store, snapshots the
+// configuration of the platform and records both in etcd.
+func BackupCreate(b backend.Backend, cb config.Backend) error {
+	return createBackup(b, cb, Stdout, Stderr)
+}
+
+func createBackup(b backend.Backend, cb config.Backend, out, ew io.Writer) error {
+	m, err := databaseMachine(b)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Pushing a base backup of the database to the store...")
+	var output bytes.Buffer
+	if err := b.Exec([]backend.Machine{m}, backupCommand, &output, ew); err != nil {
+		ew.Write(output.Bytes())
+		return fmt.Errorf("the database backup failed: %v", err)
+	}
+	bk := backup{ID: time.Now().UTC().Format(backupIDLayout)}
+	if bk.Database, bk.Finished, err = parseBackupOutput(output.String()); err != nil {
+		ew.Write(output.Bytes())
+		return err
+	}
+
+	fmt.Fprintln(out, "Saving the platform configuration...")
+	var snapshot bytes.Buffer
+	if err := config.ExportTo(nil, cb, &snapshot); err != nil {
+		return err
+	}
+	bk.Config = snapshot.String()
+	if bk.Version, err = cb.GetWithDefault("/deis/platform/version", ""); err != nil {
+		return err
+	}
+
+	for key, value := range map[string]string{
+		"database": bk.Database,
+		"finished": bk.Finished,
+		"version":  bk.Version,
+		"config":   bk.Config,
+	} {
+		if _, err := cb.Set(backupKey+"/"+bk.ID+"/"+key, value); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "Backup %s created from database backup %s.\n", bk.ID, bk.Database)
+	return nil
+}
+
+// parseBackupOutput returns the name of the base backup and the time it finished from the
+// output of backupCommand.
+func parseBackupOutput(output string) (name, finished string, err error) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "finished="):
+			finished = strings.TrimPrefix(line, "finished=")
+		case strings.HasPrefix(line, "base_"):
+			name = strings.Fields(line)[0]
+		}
+	}
+	if name == "" || finished == "" {
+		return "", "", fmt.Errorf("could not find the name of the database backup in the output of wal-e")
+	}
+	return name, finished, nil
+}
+
+// databaseMachine returns the machine running the database component.
+func databaseMachine(b backend.Backend) (backend.Machine, error) {
+	states, err := b.UnitStates()
+	if err != nil {
+		return backend.Machine{}, err
+	}
+	machines, err := b.Machines()
+	if err != nil {
+		return backend.Machine{}, err
+	}
+	for _, s := range states {
+		if s.Name != "deis-database.service" {
+			continue
+		}
+		if s.Active != "active" {
+			return backend.Machine{}, fmt.Errorf("the database is %s, start it with `deisctl start database`", s.Active)
+		}
+		for _, m := range machines {
+			if m.Host == s.Host {
+				return m, nil
+			}
+		}
+	}
+	return backend.Machine{}, fmt.Errorf("could not find the machine running the database")
+}
+
+// BackupList prints the backups recorded in etcd, oldest first.
+func BackupList(cb config.Backend) error {
+	backups, err := listBackups(cb)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Fprintln(Stdout, "No backups have been made, use `deisctl backup create`.")
+		return nil
+	}
+
+	out := new(tabwriter.Writer)
+	out.Init(Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(out, "ID\tFINISHED\tVERSION\tDATABASE")
+	for _, bk := range backups {
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", bk.ID, bk.Finished, orDash(bk.Version), bk.Database)
+	}
+	out.Flush()
+	return nil
+}
+
+func listBackups(cb config.Backend) ([]backup, error) {
+	nodes, err := cb.GetRecursive(backupKey)
+	if err != nil {
+		// etcd reports a missing directory as an error
+		if strings.Contains(err.Error(), "Key not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	byID := make(map[string]*backup)
+	for _, node := range nodes {
+		parts := strings.Split(strings.TrimPrefix(node.Key, backupKey+"/"), "/")
+		if len(parts) != 2 {
+			continue
+		}
+		bk, ok := byID[parts[0]]
+		if !ok {
+			bk = &backup{ID: parts[0]}
+			byID[parts[0]] = bk
+		}
+		switch parts[1] {
+		case "database":
+			bk.Database = node.Value
+		case "finished":
+			bk.Finished = node.Value
+		case "version":
+			bk.Version = node.Value
+		case "config":
+			bk.Config = node.Value
+		}
+	}
+
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	backups := make([]backup, len(ids))
+	for i, id := range ids {
+		backups[i] = *byID[id]
+	}
+	return backups, nil
+}
+
+// BackupRestore returns the platform to the state of a backup. The components using the
+// database are stopped, then the database, before the configuration is restored and the
+// database is told to restore its base backup when it starts again. The database must be
+// up before the components using it are started.
+func BackupRestore(id string, b backend.Backend, cb config.Backend) error {
+	return restoreBackup(id, b, cb, Stdout, Stderr)
+}
+
+func restoreBackup(id string, b backend.Backend, cb config.Backend, out, ew io.Writer) error {
+	backups, err := listBackups(cb)
+	if err != nil {
+		return err
+	}
+	var bk *backup
+	for i := range backups {
+		if backups[i].ID == id {
+			bk = &backups[i]
+		}
+	}
+	if bk == nil {
+		return fmt.Errorf("there is no backup %s, see `deisctl backup list`", id)
+	}
+	if bk.Database == "" || bk.Config == "" {
+		return fmt.Errorf("backup %s is incomplete", id)
+	}
+	if current, _ := cb.GetWithDefault("/deis/platform/version", ""); bk.Version != current {
+		fmt.Fprintf(ew, "Warning: backup %s was made with platform version %s, but %s is installed\n",
+			bk.ID, orDash(bk.Version), orDash(current))
+	}
+
+	dependents := databaseDependents()
+	c, _ := lookupComponent("database")
+	database := graph{c}
+
+	fmt.Fprintln(out, "Stopping the components using the database...")
+	if err := stopComponents(b, dependents, out); err != nil {
+		return err
+	}
+	if err := stopComponents(b, database, out); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Restoring the platform configuration...")
+	if err := config.ImportFrom(strings.NewReader(bk.Config), cb, out); err != nil {
+		return err
+	}
+
+	// a new initialization ID makes the database discard its data directory on boot
+	for key, value := range map[string]string{
+		restoreKey + "/backup":     bk.Database,
+		restoreKey + "/targetTime": bk.Finished,
+		"/deis/database/initId":    "restore-" + bk.ID,
+	} {
+		if _, err := cb.Set(key, value); err != nil {
+			return err
+		}
+	}
+	// the host is published again once the restored database accepts connections
+	cb.Delete("/deis/database/host")
+
+	fmt.Fprintf(out, "Restoring database backup %s...\n", bk.Database)
+	if err := startComponents(b, database, out); err != nil {
+		return err
+	}
+	if err := waitForKey(cb, "/deis/database/host", DatabaseTimeout); err != nil {
+		return fmt.Errorf("the database did not come back up: %v", err)
+	}
+
+	fmt.Fprintln(out, "Starting the components using the database...")
+	if err := startComponents(b, dependents, out); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "The platform has been restored to backup %s.\n", bk.ID)
+	return nil
+}
+
+// databaseDependents returns the components which require the database, directly or not,
+// except for the routers, which keep serving applications during a restore.
+func databaseDependents() graph {
+	dependent := map[string]bool{"database": true}
+	// components are declared after the components they require
+	for _, c := range platformGraph {
+		for _, name := range c.Requires {
+			if dependent[name] {
+				dependent[c.Name] = true
+			}
+		}
+	}
+	return platformGraph.without(func(c component) bool {
+		return !dependent[c.Name] || c.Name == "database" || c.Name == "router"
+	})
+}
+
+// waitForKey polls etcd until a key exists.
+func waitForKey(cb config.Backend, key string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := cb.Get(key); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s was not published within %v", key, timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
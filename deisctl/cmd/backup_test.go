@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deis/deis/deisctl/backend"
+)
+
+// databaseStub is a backend whose database answers wal-e as the database component does,
+// and publishes its host once started.
+type databaseStub struct {
+	backendStub
+	cb       configStore
+	commands []string
+}
+
+func (d *databaseStub) Exec(machines []backend.Machine, command string, out, ew io.Writer) error {
+	d.commands = append(d.commands, machines[0].Host+": "+command)
+	fmt.Fprintf(out, `==> %s (a) <==
+wal_e.worker.upload INFO     MSG: beginning volume compression
+finished=2016-01-02 15:04:05+00
+name	last_modified	expanded_size_bytes	wal_segment_backup_start
+base_000000010000000000000004_00000040	2016-01-02T15:04:05.000Z		000000010000000000000004
+`, machines[0].Host)
+	return nil
+}
+
+func (d *databaseStub) Start(targets []string, out io.Writer) error {
+	d.backendStub.Start(targets, out)
+	for _, target := range targets {
+		if target == "database" {
+			d.cb.Set("/deis/database/host", "10.0.0.2")
+		}
+	}
+	return nil
+}
+
+func newDatabaseStub() *databaseStub {
+	return &databaseStub{
+		backendStub: backendStub{unitStates: []backend.UnitState{
+			{Name: "deis-database.service", Active: "active", Sub: "running", Host: "10.0.0.2"},
+		}},
+		cb: configStore{
+			"/deis/platform/domain":  "example.com",
+			"/deis/platform/version": "v1.13.0",
+			"/deis/database/initId":  "2b1b1a52",
+		},
+	}
+}
+
+func TestBackupCreate(t *testing.T) {
+	t.Parallel()
+
+	b := newDatabaseStub()
+	if err := createBackup(b, b.cb, ioutil.Discard, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.commands) != 1 || !strings.HasPrefix(b.commands[0], "10.0.0.2: docker exec deis-database") {
+		t.Error(fmt.Errorf("Expected wal-e to run in the database container, Got %v", b.commands))
+	}
+
+	backups, err := listBackups(b.cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatal(fmt.Errorf("Expected 1 backup, Got %v", backups))
+	}
+	bk := backups[0]
+	if bk.Database != "base_000000010000000000000004_00000040" || bk.Finished != "2016-01-02 15:04:05+00" ||
+		bk.Version != "v1.13.0" {
+		t.Error(fmt.Errorf("Expected the backup to be recorded, Got %+v", bk))
+	}
+	if !strings.Contains(bk.Config, "domain: example.com") {
+		t.Error(fmt.Errorf("Expected the configuration to be saved, Got %q", bk.Config))
+	}
+}
+
+func TestBackupCreateDatabaseStopped(t *testing.T) {
+	t.Parallel()
+
+	b := newDatabaseStub()
+	b.unitStates[0].Active = "inactive"
+	err := createBackup(b, b.cb, ioutil.Discard, ioutil.Discard)
+	expected := "the database is inactive, start it with `deisctl start database`"
+	if err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, err))
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	t.Parallel()
+
+	b := newDatabaseStub()
+	b.cb["/deis/backups/20160102T150405Z/database"] = "base_000000010000000000000004_00000040"
+	b.cb["/deis/backups/20160102T150405Z/finished"] = "2016-01-02 15:04:05+00"
+	b.cb["/deis/backups/20160102T150405Z/version"] = "v1.13.0"
+	b.cb["/deis/backups/20160102T150405Z/config"] = "platform:\n  domain: example.org\n"
+
+	var ew bytes.Buffer
+	if err := restoreBackup("20160102T150405Z", b, b.cb, ioutil.Discard, &ew); err != nil {
+		t.Fatal(err)
+	}
+	if ew.String() != "" {
+		t.Error(fmt.Errorf("Expected no warnings, Got %q", ew.String()))
+	}
+
+	// the routers keep running, and the database is stopped last and started first
+	expected := []string{"builder", "controller", "database"}
+	if !reflect.DeepEqual(b.stoppedUnits, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, b.stoppedUnits))
+	}
+	expected = []string{"database", "controller", "builder"}
+	if !reflect.DeepEqual(b.startedUnits, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, b.startedUnits))
+	}
+
+	for key, value := range map[string]string{
+		"/deis/platform/domain":             "example.org",
+		"/deis/database/restore/backup":     "base_000000010000000000000004_00000040",
+		"/deis/database/restore/targetTime": "2016-01-02 15:04:05+00",
+		"/deis/database/initId":             "restore-20160102T150405Z",
+	} {
+		if b.cb[key] != value {
+			t.Error(fmt.Errorf("Expected %s to be %v, Got %v", key, value, b.cb[key]))
+		}
+	}
+
+	err := restoreBackup("20150101T000000Z", b, b.cb, ioutil.Discard, ioutil.Discard)
+	if err == nil || !strings.HasPrefix(err.Error(), "there is no backup 20150101T000000Z") {
+		t.Error(fmt.Errorf("Expected an unknown backup error, Got %v", err))
+	}
+}
@@ -1,21 +1,17 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/deis/deis/deisctl/backend"
 	"github.com/deis/deis/deisctl/config"
 	"github.com/deis/deis/deisctl/units"
 	"github.com/deis/deis/deisctl/utils"
-	"github.com/deis/deis/deisctl/utils/net"
 )
 
 const (
@@ -50,25 +46,55 @@ var Stderr io.Writer = os.Stderr
 var RouterMeshSize = DefaultRouterMeshSize
 
 // Scale grows or shrinks the number of running components.
-// Currently "router", "registry" and "store-gateway" are the only types that can be scaled.
+// Components whose unit template runs numbered instances can be scaled, such as the
+// "router", "registry", "store-gateway" and "logspout".
 func Scale(targets []string, b backend.Backend) error {
-	var wg sync.WaitGroup
-
 	for _, target := range targets {
-		component, num, err := splitScaleTarget(target)
+		component, num, relative, err := splitScaleTarget(target)
+		if err != nil {
+			return err
+		}
+		// components scale when their unit template runs numbered instances
+		scalable, err := units.Scalable(component, units.TemplatePaths())
 		if err != nil {
 			return err
 		}
-		// the router, registry, and store-gateway are the only component that can scale at the moment
-		if !strings.Contains(component, "router") && !strings.Contains(component, "registry") && !strings.Contains(component, "store-gateway") {
+		if !scalable {
 			return fmt.Errorf("cannot scale %s component", component)
 		}
-		b.Scale(component, num, &wg, Stdout, Stderr)
-		wg.Wait()
+		if relative {
+			current, err := instanceCount(b, component)
+			if err != nil {
+				return err
+			}
+			num += current
+		}
+		if num < 0 {
+			return fmt.Errorf("cannot scale %s below 0", component)
+		}
+		if err := b.Scale(component, num, Stdout); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// instanceCount returns how many instances of a scalable component are installed,
+// including those the scheduler has not placed yet.
+func instanceCount(b backend.Backend, component string) (int, error) {
+	states, err := b.UnitStates()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range states {
+		if strings.HasPrefix(s.Name, "deis-"+component+"@") {
+			n++
+		}
+	}
+	return n, nil
+}
+
 // Start activates the specified components.
 func Start(targets []string, b backend.Backend) error {
 
@@ -87,22 +113,13 @@ func Start(targets []string, b backend.Backend) error {
 			return StartK8s(b)
 		}
 	}
-	var wg sync.WaitGroup
-
-	b.Start(targets, &wg, Stdout, Stderr)
-	wg.Wait()
-
-	return nil
+	return b.Start(targets, Stdout)
 }
 
-// RollingRestart restart instance unit in a rolling manner
-func RollingRestart(target string, b backend.Backend) error {
-	var wg sync.WaitGroup
-
-	b.RollingRestart(target, &wg, Stdout, Stderr)
-	wg.Wait()
-
-	return nil
+// RollingRestart replaces the instances of a component a few at a time, waiting for
+// each replacement to become healthy before moving on.
+func RollingRestart(target string, opts backend.RollingRestartOptions, b backend.Backend) error {
+	return b.RollingRestart(target, opts, Stdout)
 }
 
 // CheckRequiredKeys exist in config backend
@@ -120,68 +137,6 @@ deisctl config platform set sshPrivateKey=<path-to-key>
 	return nil
 }
 
-func startDefaultServices(b backend.Backend, stateless bool, wg *sync.WaitGroup, out, err io.Writer) {
-
-	// Wait for groups to come up.
-	// If we're running in stateless mode, we start only a subset of services.
-	if !stateless {
-		fmt.Fprintln(out, "Storage subsystem...")
-		b.Start([]string{"store-monitor"}, wg, out, err)
-		wg.Wait()
-		b.Start([]string{"store-daemon"}, wg, out, err)
-		wg.Wait()
-		b.Start([]string{"store-metadata"}, wg, out, err)
-		wg.Wait()
-
-		// we start gateway first to give metadata time to come up for volume
-		b.Start([]string{"store-gateway@*"}, wg, out, err)
-		wg.Wait()
-		b.Start([]string{"store-volume"}, wg, out, err)
-		wg.Wait()
-	}
-
-	// start logging subsystem first to collect logs from other components
-	fmt.Fprintln(out, "Logging subsystem...")
-	if !stateless {
-		b.Start([]string{"logger"}, wg, out, err)
-		wg.Wait()
-	}
-	b.Start([]string{"logspout"}, wg, out, err)
-	wg.Wait()
-
-	// Start these in parallel. This section can probably be removed now.
-	var bgwg sync.WaitGroup
-	var trash bytes.Buffer
-	batch := []string{
-		"database", "registry@*", "controller", "builder",
-		"publisher", "router@*",
-	}
-	if stateless {
-		batch = []string{"registry@*", "controller", "builder", "publisher", "router@*"}
-	}
-	b.Start(batch, &bgwg, &trash, &trash)
-	// End background stuff.
-
-	fmt.Fprintln(Stdout, "Control plane...")
-	batch = []string{"database", "registry@*", "controller"}
-	if stateless {
-		batch = []string{"registry@*", "controller"}
-	}
-	b.Start(batch, wg, out, err)
-	wg.Wait()
-
-	b.Start([]string{"builder"}, wg, out, err)
-	wg.Wait()
-
-	fmt.Fprintln(out, "Data plane...")
-	b.Start([]string{"publisher"}, wg, out, err)
-	wg.Wait()
-
-	fmt.Fprintln(out, "Router mesh...")
-	b.Start([]string{"router@*"}, wg, out, err)
-	wg.Wait()
-}
-
 // Stop deactivates the specified components.
 func Stop(targets []string, b backend.Backend) error {
 
@@ -201,52 +156,7 @@ func Stop(targets []string, b backend.Backend) error {
 		}
 	}
 
-	var wg sync.WaitGroup
-
-	b.Stop(targets, &wg, Stdout, Stderr)
-	wg.Wait()
-
-	return nil
-}
-
-func stopDefaultServices(b backend.Backend, stateless bool, wg *sync.WaitGroup, out, err io.Writer) {
-
-	fmt.Fprintln(out, "Router mesh...")
-	b.Stop([]string{"router@*"}, wg, out, err)
-	wg.Wait()
-
-	fmt.Fprintln(out, "Data plane...")
-	b.Stop([]string{"publisher"}, wg, out, err)
-	wg.Wait()
-
-	fmt.Fprintln(out, "Control plane...")
-	if stateless {
-		b.Stop([]string{"controller", "builder", "registry@*"}, wg, out, err)
-	} else {
-		b.Stop([]string{"controller", "builder", "database", "registry@*"}, wg, out, err)
-	}
-	wg.Wait()
-
-	fmt.Fprintln(out, "Logging subsystem...")
-	if stateless {
-		b.Stop([]string{"logspout"}, wg, out, err)
-	} else {
-		b.Stop([]string{"logger", "logspout"}, wg, out, err)
-	}
-	wg.Wait()
-
-	if !stateless {
-		fmt.Fprintln(out, "Storage subsystem...")
-		b.Stop([]string{"store-volume", "store-gateway@*"}, wg, out, err)
-		wg.Wait()
-		b.Stop([]string{"store-metadata"}, wg, out, err)
-		wg.Wait()
-		b.Stop([]string{"store-daemon"}, wg, out, err)
-		wg.Wait()
-		b.Stop([]string{"store-monitor"}, wg, out, err)
-		wg.Wait()
-	}
-
+	return b.Stop(targets, Stdout)
 }
 
 // Restart stops and then starts the specified components.
@@ -271,15 +181,9 @@ func Status(targets []string, b backend.Backend) error {
 	return nil
 }
 
-// Journal prints log output for the specified components.
-func Journal(targets []string, b backend.Backend) error {
-
-	for _, target := range targets {
-		if err := b.Journal(target); err != nil {
-			return err
-		}
-	}
-	return nil
+// Journal prints log output for the specified components, interleaved by time.
+func Journal(targets []string, opts backend.JournalOptions, b backend.Backend) error {
+	return b.Journal(targets, opts)
 }
 
 // Install loads the definitions of components from local unit files.
@@ -301,55 +205,8 @@ func Install(targets []string, b backend.Backend, cb config.Backend, checkKeys f
 			return InstallK8s(b)
 		}
 	}
-	var wg sync.WaitGroup
-
 	// otherwise create the specific targets
-	b.Create(targets, &wg, Stdout, Stderr)
-	wg.Wait()
-
-	return nil
-}
-
-func installDefaultServices(b backend.Backend, stateless bool, wg *sync.WaitGroup, out, err io.Writer) {
-
-	if !stateless {
-		fmt.Fprintln(out, "Storage subsystem...")
-		b.Create([]string{"store-daemon", "store-monitor", "store-metadata", "store-volume", "store-gateway@1"}, wg, out, err)
-		wg.Wait()
-	}
-
-	fmt.Fprintln(out, "Logging subsystem...")
-	if stateless {
-		b.Create([]string{"logspout"}, wg, out, err)
-	} else {
-		b.Create([]string{"logger", "logspout"}, wg, out, err)
-	}
-	wg.Wait()
-
-	fmt.Fprintln(out, "Control plane...")
-	if stateless {
-		b.Create([]string{"registry@1", "controller", "builder"}, wg, out, err)
-	} else {
-		b.Create([]string{"database", "registry@1", "controller", "builder"}, wg, out, err)
-	}
-	wg.Wait()
-
-	fmt.Fprintln(out, "Data plane...")
-	b.Create([]string{"publisher"}, wg, out, err)
-	wg.Wait()
-
-	fmt.Fprintln(out, "Router mesh...")
-	b.Create(getRouters(), wg, out, err)
-	wg.Wait()
-
-}
-
-func getRouters() []string {
-	routers := make([]string, RouterMeshSize)
-	for i := uint8(0); i < RouterMeshSize; i++ {
-		routers[i] = fmt.Sprintf("router@%d", i+1)
-	}
-	return routers
+	return b.Create(targets, Stdout)
 }
 
 // Uninstall unloads the definitions of the specified components.
@@ -370,68 +227,28 @@ func Uninstall(targets []string, b backend.Backend) error {
 		}
 	}
 
-	var wg sync.WaitGroup
-
 	// uninstall the specific target
-	b.Destroy(targets, &wg, Stdout, Stderr)
-	wg.Wait()
-
-	return nil
-}
-
-func uninstallAllServices(b backend.Backend, stateless bool, wg *sync.WaitGroup, out, err io.Writer) error {
-
-	fmt.Fprintln(out, "Router mesh...")
-	b.Destroy([]string{"router@*"}, wg, out, err)
-	wg.Wait()
-
-	fmt.Fprintln(out, "Data plane...")
-	b.Destroy([]string{"publisher"}, wg, out, err)
-	wg.Wait()
-
-	fmt.Fprintln(out, "Control plane...")
-	if stateless {
-		b.Destroy([]string{"controller", "builder", "registry@*"}, wg, out, err)
-	} else {
-		b.Destroy([]string{"controller", "builder", "database", "registry@*"}, wg, out, err)
-	}
-	wg.Wait()
-
-	fmt.Fprintln(out, "Logging subsystem...")
-	if stateless {
-		b.Destroy([]string{"logspout"}, wg, out, err)
-	} else {
-		b.Destroy([]string{"logger", "logspout"}, wg, out, err)
-	}
-	wg.Wait()
-
-	if !stateless {
-		fmt.Fprintln(out, "Storage subsystem...")
-		b.Destroy([]string{"store-volume", "store-gateway@*"}, wg, out, err)
-		wg.Wait()
-		b.Destroy([]string{"store-metadata"}, wg, out, err)
-		wg.Wait()
-		b.Destroy([]string{"store-daemon"}, wg, out, err)
-		wg.Wait()
-		b.Destroy([]string{"store-monitor"}, wg, out, err)
-		wg.Wait()
-	}
-
-	return nil
+	return b.Destroy(targets, Stdout)
 }
 
-func splitScaleTarget(target string) (c string, num int, err error) {
-	r := regexp.MustCompile(`([a-z-]+)=([\d]+)`)
+// splitScaleTarget parses component=num, or component=+num and component=-num to scale
+// relative to the number of instances installed.
+func splitScaleTarget(target string) (c string, num int, relative bool, err error) {
+	r := regexp.MustCompile(`^(?:deis-)?([a-z-]+)=([+-]?)([\d]+)$`)
 	match := r.FindStringSubmatch(target)
 	if len(match) == 0 {
 		err = fmt.Errorf("Could not parse: %v", target)
 		return
 	}
 	c = match[1]
-	num, err = strconv.Atoi(match[2])
+	num, err = strconv.Atoi(match[3])
 	if err != nil {
 		return
 	}
+	relative = match[2] != ""
+	if match[2] == "-" {
+		num = -num
+	}
 	return
 }
 
@@ -447,39 +264,72 @@ func Config(target string, action string, key []string, cb config.Backend) error
 	return nil
 }
 
-// RefreshUnits overwrites local unit files with those requested.
-// Downloading from the Deis project GitHub URL by tag or SHA is the only mechanism
-// currently supported.
-func RefreshUnits(unitDir, tag, rootURL string) error {
-	unitDir = utils.ResolvePath(unitDir)
-	decoratorDir := filepath.Join(unitDir, "decorators")
-	// create the target dir if necessary
-	if err := os.MkdirAll(decoratorDir, 0755); err != nil {
+// ControllerWhitelist lists, adds or removes addresses allowed to connect to the
+// controller through the router.
+func ControllerWhitelist(action string, addresses []string, cb config.Backend) error {
+	return config.Whitelist(action, addresses, cb)
+}
+
+// ConfigExport writes the configuration of the given components, or of the whole platform,
+// as YAML.
+func ConfigExport(components []string, cb config.Backend) error {
+	return config.Export(components, cb)
+}
+
+// ConfigImport sets the configuration read from an export, or only prints the changes
+// it would make when dryRun is set.
+func ConfigImport(path string, dryRun bool, cb config.Backend) error {
+	return config.Import(path, dryRun, cb)
+}
+
+// Render prints the unit files of components as they would be installed, with the
+// variables of their templates filled in from etcd.
+func Render(targets []string, cb config.Backend) error {
+	return render(targets, cb, Stdout)
+}
+
+func render(targets []string, cb config.Backend, out io.Writer) error {
+	decorate, err := cb.GetWithDefault("/deis/platform/enablePlacementOptions", "false")
+	if err != nil {
 		return err
 	}
-	// download and save the unit files to the specified path
-	for _, unit := range units.Names {
-		unitSrc := rootURL + tag + "/deisctl/units/" + unit + ".service"
-		unitDest := filepath.Join(unitDir, unit+".service")
-		if err := net.Download(unitSrc, unitDest); err != nil {
+
+	for i, target := range targets {
+		component := renderTargetRegexp.ReplaceAllString(target, "$1")
+		vars, err := units.LoadVariables(component, cb)
+		if err != nil {
 			return err
 		}
-		fmt.Printf("Refreshed %s unit from %s\n", unit, tag)
-		decoratorSrc := rootURL + tag + "/deisctl/units/decorators/" + unit + ".service.decorator"
-		decoratorDest := filepath.Join(decoratorDir, unit+".service.decorator")
-		if err := net.Download(decoratorSrc, decoratorDest); err != nil {
-			if err.Error() == "404 Not Found" {
-				fmt.Printf("Decorator for %s not found in %s\n", unit, tag)
-			} else {
-				return err
+		contents, err := units.Render(component, units.TemplatePaths(), decorate == "true", vars)
+		if err != nil {
+			return err
+		}
+		if len(targets) > 1 {
+			if i > 0 {
+				fmt.Fprintln(out)
 			}
-		} else {
-			fmt.Printf("Refreshed %s decorator from %s\n", unit, tag)
+			fmt.Fprintf(out, "# deis-%s.service\n", component)
 		}
+		fmt.Fprint(out, contents)
 	}
 	return nil
 }
 
+// renderTargetRegexp reduces a target such as deis-router@1.service to its component.
+var renderTargetRegexp = regexp.MustCompile(`^(?:deis-)?([a-z-]+?)(?:@\d*)?(?:\.service)?$`)
+
+// RefreshUnits overwrites local unit files with those of a git tag, branch or SHA of the
+// Deis project on GitHub.
+func RefreshUnits(unitDir, tag, rootURL string) error {
+	return RefreshUnitsFrom(unitDir, RefreshOptions{Tag: tag, RootURL: rootURL})
+}
+
+// RefreshUnitsFrom overwrites local unit files with those read from the source chosen by
+// opts, once every file has been read and verified.
+func RefreshUnitsFrom(unitDir string, opts RefreshOptions) error {
+	return refreshUnits(unitDir, opts, Stdout)
+}
+
 // SSH opens an interactive shell on a machine in the cluster
 func SSH(target string, cmd []string, b backend.Backend) error {
 
@@ -490,6 +340,33 @@ func SSH(target string, cmd []string, b backend.Backend) error {
 	return b.SSH(target)
 }
 
+// Exec runs a command in parallel on every machine whose metadata matches a filter, such
+// as "role=dataPlane", or on every machine if the filter is empty.
+func Exec(filter string, command []string, b backend.Backend) error {
+	return runOnMachines(filter, command, b, Stdout, Stderr)
+}
+
+func runOnMachines(filter string, command []string, b backend.Backend, out, ew io.Writer) error {
+	f, err := backend.ParseMetadataFilter(filter)
+	if err != nil {
+		return err
+	}
+	machines, err := b.Machines()
+	if err != nil {
+		return err
+	}
+	machines = backend.FilterMachines(machines, f)
+	if len(machines) == 0 {
+		return fmt.Errorf("no machines match %s", f)
+	}
+	// the arguments reach the remote shell as they were given
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = utils.ShellQuote(arg)
+	}
+	return b.Exec(machines, strings.Join(args, " "), out, ew)
+}
+
 // Dock connects to the appropriate host and runs 'docker exec -it'.
 func Dock(target string, cmd []string, b backend.Backend) error {
 	return b.Dock(target, cmd)
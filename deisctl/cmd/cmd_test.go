@@ -1,15 +1,17 @@
 package cmd
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
-	"sync"
 	"testing"
 
 	"github.com/deis/deis/deisctl/backend"
@@ -19,28 +21,39 @@ import (
 	"github.com/deis/deis/deisctl/units"
 )
 
+func init() {
+	// scalability is read from the unit templates shipped with deisctl
+	os.Setenv("DEISCTL_UNITS", "../units")
+}
+
 type backendStub struct {
 	startedUnits     []string
 	stoppedUnits     []string
 	installedUnits   []string
 	uninstalledUnits []string
 	restartedUnits   []string
+	rebalancedUnits  []string
+	unitStates       []backend.UnitState
 	expected         bool
 }
 
-func (backend *backendStub) Create(targets []string, wg *sync.WaitGroup, out, ew io.Writer) {
+func (backend *backendStub) Create(targets []string, out io.Writer) error {
 	backend.installedUnits = append(backend.installedUnits, targets...)
+	return nil
 }
-func (backend *backendStub) Destroy(targets []string, wg *sync.WaitGroup, out, ew io.Writer) {
+func (backend *backendStub) Destroy(targets []string, out io.Writer) error {
 	backend.uninstalledUnits = append(backend.uninstalledUnits, targets...)
+	return nil
 }
-func (backend *backendStub) Start(targets []string, wg *sync.WaitGroup, out, ew io.Writer) {
+func (backend *backendStub) Start(targets []string, out io.Writer) error {
 	backend.startedUnits = append(backend.startedUnits, targets...)
+	return nil
 }
-func (backend *backendStub) Stop(targets []string, wg *sync.WaitGroup, out, ew io.Writer) {
+func (backend *backendStub) Stop(targets []string, out io.Writer) error {
 	backend.stoppedUnits = append(backend.stoppedUnits, targets...)
+	return nil
 }
-func (backend *backendStub) Scale(component string, num int, wg *sync.WaitGroup, out, ew io.Writer) {
+func (backend *backendStub) Scale(component string, num int, out io.Writer) error {
 	switch {
 	case component == "router" && num == 3:
 		backend.expected = true
@@ -49,9 +62,16 @@ func (backend *backendStub) Scale(component string, num int, wg *sync.WaitGroup,
 	default:
 		backend.expected = false
 	}
+	return nil
+}
+func (stub *backendStub) RollingRestart(target string, opts backend.RollingRestartOptions, out io.Writer) error {
+	stub.restartedUnits = append(stub.restartedUnits, target)
+	return nil
 }
-func (backend *backendStub) RollingRestart(target string, wg *sync.WaitGroup, out, ew io.Writer) {
-	backend.restartedUnits = append(backend.restartedUnits, target)
+
+func (stub *backendStub) Rebalance(component string, out io.Writer) error {
+	stub.rebalancedUnits = append(stub.rebalancedUnits, component)
+	return nil
 }
 
 func (backend *backendStub) ListUnits() error {
@@ -60,17 +80,25 @@ func (backend *backendStub) ListUnits() error {
 func (backend *backendStub) ListUnitFiles() error {
 	return nil
 }
+func (stub *backendStub) UnitStates() ([]backend.UnitState, error) {
+	return stub.unitStates, nil
+}
+func (stub *backendStub) ActiveSince(states []backend.UnitState) ([]backend.UnitState, error) {
+	return states, nil
+}
 func (backend *backendStub) Status(target string) error {
 	if target == "controller" || target == "builder" {
 		return nil
 	}
 	return errors.New("Test Error")
 }
-func (backend *backendStub) Journal(target string) error {
-	if target == "controller" || target == "builder" {
-		return nil
+func (stub *backendStub) Journal(targets []string, opts backend.JournalOptions) error {
+	for _, target := range targets {
+		if target != "controller" && target != "builder" {
+			return errors.New("Test Error")
+		}
 	}
-	return errors.New("Test Error")
+	return nil
 }
 func (backend *backendStub) SSH(target string) error {
 	if target == "controller" {
@@ -85,6 +113,21 @@ func (backend *backendStub) SSHExec(target, command string) error {
 	return errors.New("Error")
 }
 
+func (stub *backendStub) Machines() ([]backend.Machine, error) {
+	return []backend.Machine{
+		{ID: "a", Host: "10.0.0.1", Metadata: map[string]string{"role": "control"}},
+		{ID: "b", Host: "10.0.0.2", Metadata: map[string]string{"role": "dataPlane"}},
+		{ID: "c", Host: "10.0.0.3", Metadata: map[string]string{"role": "dataPlane"}},
+	}, nil
+}
+
+func (stub *backendStub) Exec(machines []backend.Machine, command string, out, ew io.Writer) error {
+	for _, m := range machines {
+		fmt.Fprintf(out, "%s: %s\n", m.Host, command)
+	}
+	return nil
+}
+
 func (backend *backendStub) Dock(target string, command []string) error {
 	return nil
 }
@@ -109,11 +152,13 @@ func (fakeHTTPServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 func TestRefreshUnits(t *testing.T) {
 	t.Parallel()
 
-	name, err := ioutil.TempDir("", "deisctl")
+	dir, err := ioutil.TempDir("", "deisctl")
 
 	if err != nil {
 		t.Error(err)
 	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "units")
 
 	handler := fakeHTTPServer{}
 	server := httptest.NewServer(handler)
@@ -172,6 +217,32 @@ func TestRefreshUnitsError(t *testing.T) {
 	}
 }
 
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	cb := mock.ConfigBackend{Expected: []*model.ConfigNode{
+		{Key: "/deis/units/imageRegistry", Value: "mirror.local:5000/"},
+		{Key: "/deis/units/router/machineMetadata", Value: "role=edge"},
+	}}
+	var b bytes.Buffer
+
+	if err := render([]string{"deis-router@1.service"}, cb, &b); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"IMAGE=mirror.local:5000/`/run/deis/bin/get_image /deis/router`",
+		"MachineMetadata=role=edge",
+	} {
+		if !strings.Contains(b.String(), expected) {
+			t.Error(fmt.Errorf("Expected %s in %s", expected, b.String()))
+		}
+	}
+
+	if err := render([]string{"foo"}, cb, &b); err == nil {
+		t.Error("Expected an error rendering an unknown component")
+	}
+}
+
 func TestListUnits(t *testing.T) {
 	t.Parallel()
 
@@ -217,6 +288,28 @@ func TestScalingNonScalableComponent(t *testing.T) {
 	}
 }
 
+func TestScalingRelative(t *testing.T) {
+	t.Parallel()
+
+	b := backendStub{unitStates: []backend.UnitState{
+		{Name: "deis-router@1.service"},
+		{Name: "deis-router@2.service"},
+		{Name: "deis-registry@1.service"},
+	}}
+
+	if err := Scale([]string{"router=+1"}, &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.expected == false {
+		t.Error("b.Scale called with unexpected arguements")
+	}
+
+	expected := "cannot scale router below 0"
+	if err := Scale([]string{"router=-3"}, &b); err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected '%v', Got '%v'", expected, err))
+	}
+}
+
 func TestScalingInvalidFormat(t *testing.T) {
 	t.Parallel()
 
@@ -246,10 +339,9 @@ func TestStartPlatform(t *testing.T) {
 	t.Parallel()
 
 	b := backendStub{}
-	expected := []string{"store-monitor", "store-daemon", "store-metadata", "store-gateway@*",
-		"store-volume", "logger", "logspout", "database", "registry@*", "controller",
-		"builder", "publisher", "router@*", "database", "registry@*", "controller",
-		"builder", "publisher", "router@*"}
+	expected := []string{"store-monitor", "store-daemon", "store-metadata", "store-gateway@*", "store-volume",
+		"logger", "logspout", "database", "registry@*", "publisher", "controller", "builder",
+		"router@*"}
 
 	Start([]string{"platform"}, &b)
 
@@ -258,13 +350,47 @@ func TestStartPlatform(t *testing.T) {
 	}
 }
 
+// failingStart is a backend on which the database fails to start.
+type failingStart struct {
+	backendStub
+}
+
+func (f *failingStart) Start(targets []string, out io.Writer) error {
+	f.backendStub.Start(targets, out)
+	for _, target := range targets {
+		if target == "database" {
+			return fmt.Errorf("deis-%s.service: timed out after 1s waiting to start (last state: activating/start-pre)", target)
+		}
+	}
+	return nil
+}
+
+func TestStartPlatformFailure(t *testing.T) {
+	t.Parallel()
+
+	b := failingStart{}
+	expected := []string{"store-monitor", "store-daemon", "store-metadata", "store-gateway@*", "store-volume",
+		"logger", "logspout", "database", "registry@*", "publisher"}
+
+	err := Start([]string{"platform"}, &b)
+	if err == nil || !strings.Contains(err.Error(), "deis-database.service: timed out") {
+		t.Error(fmt.Errorf("Expected the database to time out, Got %v", err))
+	}
+	// nothing which requires the database is started
+	if !reflect.DeepEqual(b.startedUnits, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, b.startedUnits))
+	}
+
+	if err := Start([]string{"database", "logger"}, &b); err == nil {
+		t.Error("Expected starting the database to fail")
+	}
+}
+
 func TestStartStatelessPlatform(t *testing.T) {
 	t.Parallel()
 
 	b := backendStub{}
-	expected := []string{"logspout", "registry@*", "controller",
-		"builder", "publisher", "router@*", "registry@*", "controller",
-		"builder", "publisher", "router@*"}
+	expected := []string{"logspout", "registry@*", "publisher", "controller", "builder", "router@*"}
 
 	Start([]string{"stateless-platform"}, &b)
 
@@ -292,7 +418,7 @@ func TestRollingRestart(t *testing.T) {
 	b := backendStub{}
 	expected := []string{"router"}
 
-	RollingRestart("router", &b)
+	RollingRestart("router", backend.RollingRestartOptions{MaxUnavailable: 1}, &b)
 
 	if !reflect.DeepEqual(b.restartedUnits, expected) {
 		t.Error(fmt.Errorf("Expected %v, Got %v", expected, b.restartedUnits))
@@ -303,8 +429,8 @@ func TestUpgradePrep(t *testing.T) {
 	t.Parallel()
 
 	b := backendStub{}
-	expected := []string{"database", "registry@*", "controller", "builder", "logger", "logspout", "store-volume",
-		"store-gateway@*", "store-metadata", "store-daemon", "store-monitor"}
+	expected := []string{"builder", "controller", "database", "registry@*", "logspout", "logger",
+		"store-gateway@*", "store-volume", "store-metadata", "store-daemon", "store-monitor"}
 
 	UpgradePrep(&b)
 
@@ -320,10 +446,9 @@ func TestUpgradeTakeover(t *testing.T) {
 
 	b := backendStub{}
 	expectedRestarted := []string{"router"}
-	expectedStarted := []string{"publisher", "store-monitor", "store-daemon", "store-metadata",
-		"store-gateway@*", "store-volume", "logger", "logspout", "database", "registry@*",
-		"controller", "builder", "publisher", "router@*", "database", "registry@*",
-		"controller", "builder", "publisher", "router@*"}
+	expectedStarted := []string{"publisher", "store-monitor", "store-daemon", "store-metadata", "store-gateway@*",
+		"store-volume", "logger", "logspout", "database", "registry@*", "publisher",
+		"controller", "builder", "router@*"}
 
 	if err := doUpgradeTakeOver(&b, testMock); err != nil {
 		t.Error(fmt.Errorf("Takeover failed: %v", err))
@@ -353,9 +478,9 @@ func TestStopPlatform(t *testing.T) {
 	t.Parallel()
 
 	b := backendStub{}
-	expected := []string{"router@*", "publisher", "controller", "builder", "database",
-		"registry@*", "logger", "logspout", "store-volume", "store-gateway@*",
-		"store-metadata", "store-daemon", "store-monitor"}
+	expected := []string{"builder", "router@*", "controller", "database", "registry@*", "publisher", "logspout",
+		"logger", "store-gateway@*", "store-volume", "store-metadata", "store-daemon",
+		"store-monitor"}
 	Stop([]string{"platform"}, &b)
 
 	if !reflect.DeepEqual(b.stoppedUnits, expected) {
@@ -363,12 +488,44 @@ func TestStopPlatform(t *testing.T) {
 	}
 }
 
+// failingStop is a backend on which the controller fails to stop.
+type failingStop struct {
+	backendStub
+}
+
+func (f *failingStop) Stop(targets []string, out io.Writer) error {
+	f.backendStub.Stop(targets, out)
+	for _, target := range targets {
+		if target == "controller" {
+			return fmt.Errorf("The service 'deis-%s.service' failed while stopping.", target)
+		}
+	}
+	return nil
+}
+
+func TestStopPlatformFailure(t *testing.T) {
+	t.Parallel()
+
+	b := failingStop{}
+	expected := []string{"builder", "router@*", "controller", "database", "registry@*", "publisher", "logspout",
+		"logger", "store-gateway@*", "store-volume", "store-metadata", "store-daemon",
+		"store-monitor"}
+
+	err := Stop([]string{"platform"}, &b)
+	if err == nil || !strings.Contains(err.Error(), "deis-controller.service' failed while stopping") {
+		t.Error(fmt.Errorf("Expected the controller to fail, Got %v", err))
+	}
+	// the rest of the platform is stopped all the same
+	if !reflect.DeepEqual(b.stoppedUnits, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, b.stoppedUnits))
+	}
+}
+
 func TestStopStatelessPlatform(t *testing.T) {
 	t.Parallel()
 
 	b := backendStub{}
-	expected := []string{"router@*", "publisher", "controller", "builder",
-		"registry@*", "logspout"}
+	expected := []string{"builder", "router@*", "controller", "registry@*", "publisher", "logspout"}
 	Stop([]string{"stateless-platform"}, &b)
 
 	if !reflect.DeepEqual(b.stoppedUnits, expected) {
@@ -425,6 +582,26 @@ func TestSSHExec(t *testing.T) {
 	}
 }
 
+func TestExec(t *testing.T) {
+	t.Parallel()
+
+	b := backendStub{}
+	var out bytes.Buffer
+	command := []string{"docker", "ps", "--format", "{{.Names}} {{.Status}}"}
+	if err := runOnMachines("role=dataPlane", command, &b, &out, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	expected := "10.0.0.2: docker ps --format '{{.Names}} {{.Status}}'\n10.0.0.3: docker ps --format '{{.Names}} {{.Status}}'\n"
+	if out.String() != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+	}
+
+	err := runOnMachines("role=router", []string{"true"}, &b, &out, ioutil.Discard)
+	if err == nil || err.Error() != "no machines match role=router" {
+		t.Error(fmt.Errorf("Expected no machines to match, Got %v", err))
+	}
+}
+
 func TestSSHError(t *testing.T) {
 	t.Parallel()
 
@@ -464,7 +641,7 @@ func TestJournal(t *testing.T) {
 
 	b := backendStub{}
 
-	if Journal([]string{"controller", "builder"}, &b) != nil {
+	if Journal([]string{"controller", "builder"}, backend.JournalOptions{}, &b) != nil {
 		t.Error("Unexpected Error")
 	}
 }
@@ -475,7 +652,7 @@ func TestJournalError(t *testing.T) {
 	b := backendStub{}
 
 	expected := "Test Error"
-	err := Journal([]string{"blah"}, &b).Error()
+	err := Journal([]string{"blah"}, backend.JournalOptions{}, &b).Error()
 
 	if err != expected {
 		t.Error(fmt.Errorf("Expected '%v', Got '%v'", expected, err))
@@ -503,9 +680,9 @@ func TestInstallPlatform(t *testing.T) {
 	b := backendStub{}
 	cb := mock.ConfigBackend{}
 
-	expected := []string{"store-daemon", "store-monitor", "store-metadata", "store-volume",
-		"store-gateway@1", "logger", "logspout", "database", "registry@1",
-		"controller", "builder", "publisher", "router@1", "router@2", "router@3"}
+	expected := []string{"store-monitor", "store-daemon", "store-metadata", "store-gateway@1", "store-volume",
+		"logger", "logspout", "database", "registry@1", "publisher", "controller", "builder",
+		"router@1", "router@2", "router@3"}
 
 	Install([]string{"platform"}, &b, &cb, fakeCheckKeys)
 
@@ -520,9 +697,9 @@ func TestInstallPlatformWithCustomRouterMeshSize(t *testing.T) {
 	b := backendStub{}
 	cb := mock.ConfigBackend{}
 
-	expected := []string{"store-daemon", "store-monitor", "store-metadata", "store-volume",
-		"store-gateway@1", "logger", "logspout", "database", "registry@1",
-		"controller", "builder", "publisher", "router@1", "router@2", "router@3", "router@4", "router@5"}
+	expected := []string{"store-monitor", "store-daemon", "store-metadata", "store-gateway@1", "store-volume",
+		"logger", "logspout", "database", "registry@1", "publisher", "controller", "builder",
+		"router@1", "router@2", "router@3", "router@4", "router@5"}
 	RouterMeshSize = 5
 
 	Install([]string{"platform"}, &b, &cb, fakeCheckKeys)
@@ -539,8 +716,8 @@ func TestInstallStatelessPlatform(t *testing.T) {
 	b := backendStub{}
 	cb := mock.ConfigBackend{}
 
-	expected := []string{"logspout", "registry@1",
-		"controller", "builder", "publisher", "router@1", "router@2", "router@3"}
+	expected := []string{"logspout", "registry@1", "publisher", "controller", "builder", "router@1", "router@2",
+		"router@3"}
 
 	Install([]string{"stateless-platform"}, &b, &cb, fakeCheckKeys)
 
@@ -581,9 +758,9 @@ func TestUninstallPlatform(t *testing.T) {
 	t.Parallel()
 
 	b := backendStub{}
-	expected := []string{"router@*", "publisher", "controller", "builder", "database",
-		"registry@*", "logger", "logspout", "store-volume", "store-gateway@*",
-		"store-metadata", "store-daemon", "store-monitor"}
+	expected := []string{"builder", "router@*", "controller", "database", "registry@*", "publisher", "logspout",
+		"logger", "store-gateway@*", "store-volume", "store-metadata", "store-daemon",
+		"store-monitor"}
 
 	Uninstall([]string{"platform"}, &b)
 
@@ -596,8 +773,7 @@ func TestUninstallStatelessPlatform(t *testing.T) {
 	t.Parallel()
 
 	b := backendStub{}
-	expected := []string{"router@*", "publisher", "controller", "builder",
-		"registry@*", "logspout"}
+	expected := []string{"builder", "router@*", "controller", "registry@*", "publisher", "logspout"}
 
 	Uninstall([]string{"stateless-platform"}, &b)
 
@@ -618,3 +794,184 @@ func TestUninstallSwarm(t *testing.T) {
 		t.Error(fmt.Errorf("Expected %v, Got %v", expected, b.uninstalledUnits))
 	}
 }
+
+// configStore is a config backend which keeps the values set in memory.
+type configStore map[string]string
+
+func (s configStore) Get(key string) (string, error) {
+	if v, ok := s[key]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("%s does not exist", key)
+}
+func (s configStore) GetWithDefault(key, defaultValue string) (string, error) {
+	if v, ok := s[key]; ok {
+		return v, nil
+	}
+	return defaultValue, nil
+}
+func (s configStore) Set(key, value string) (string, error) {
+	s[key] = value
+	return value, nil
+}
+func (s configStore) SetWithTTL(key, value string, ttl uint64) (string, error) {
+	return s.Set(key, value)
+}
+func (s configStore) Delete(key string) error {
+	delete(s, key)
+	return nil
+}
+func (s configStore) GetRecursive(key string) ([]*model.ConfigNode, error) {
+	var nodes []*model.ConfigNode
+	for k, v := range s {
+		if strings.HasPrefix(k, key+"/") {
+			nodes = append(nodes, &model.ConfigNode{Key: k, Value: v})
+		}
+	}
+	return nodes, nil
+}
+
+// failingRestart is a backend whose rolling restarts fail.
+type failingRestart struct {
+	backendStub
+}
+
+func (f *failingRestart) RollingRestart(target string, opts backend.RollingRestartOptions, out io.Writer) error {
+	return fmt.Errorf("rolling restart of %s aborted", target)
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "deisctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// refreshes link the unit directory to one staged next to it
+	unitDir := filepath.Join(dir, "units")
+	server := httptest.NewServer(fakeHTTPServer{})
+	defer server.Close()
+
+	b := backendStub{}
+	cb := configStore{"/deis/platform/version": "v1.7.1"}
+
+	if err := Upgrade("1.7.2", unitDir, server.URL+"/", &b, cb, fakeCheckKeys); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := cb["/deis/platform/version"]; v != "v1.7.2" {
+		t.Error(fmt.Errorf("Expected v1.7.2, Got %v", v))
+	}
+	for _, key := range []string{"version", "from", "step"} {
+		if v, ok := cb[upgradeKey+"/"+key]; ok {
+			t.Error(fmt.Errorf("Expected %s to be cleared, Got %v", key, v))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(unitDir, "deis-router.service")); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(b.restartedUnits, []string{"router"}) {
+		t.Error(fmt.Errorf("Expected [router], Got %v", b.restartedUnits))
+	}
+	if b.stoppedUnits[len(b.stoppedUnits)-1] != "publisher" {
+		t.Error(fmt.Errorf("Expected the publisher to be stopped last, Got %v", b.stoppedUnits))
+	}
+
+	// the platform now runs the release, so there is nothing left to do
+	b = backendStub{}
+	if err := Upgrade("v1.7.2", unitDir, server.URL+"/", &b, cb, fakeCheckKeys); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.stoppedUnits) != 0 {
+		t.Error(fmt.Errorf("Expected nothing to be stopped, Got %v", b.stoppedUnits))
+	}
+}
+
+func TestUpgradeResume(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "deisctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// refreshes link the unit directory to one staged next to it
+	unitDir := filepath.Join(dir, "units")
+	server := httptest.NewServer(fakeHTTPServer{})
+	defer server.Close()
+
+	b := failingRestart{}
+	service := "deis/services/app/app_v2.web.1"
+	cb := configStore{"/deis/platform/version": "v1.7.1", service: "10.0.0.1:49153"}
+
+	err = Upgrade("1.7.2", unitDir, server.URL+"/", &b, cb, fakeCheckKeys)
+	if err == nil || !strings.Contains(err.Error(), `stopped at step "routers"`) {
+		t.Fatal(fmt.Errorf("Expected the upgrade to stop at the routers, Got %v", err))
+	}
+	if v := cb[upgradeKey+"/step"]; v != "republish" {
+		t.Error(fmt.Errorf("Expected republish, Got %v", v))
+	}
+	// the republished service expires while the upgrade is interrupted
+	delete(cb, service)
+
+	// a different release can't be started until the upgrade is finished
+	if err := Upgrade("1.7.3", unitDir, server.URL+"/", &b, cb, fakeCheckKeys); err == nil {
+		t.Error("Expected an upgrade to another release to be refused")
+	}
+
+	resumed := backendStub{}
+	if err := Upgrade("1.7.2", unitDir, server.URL+"/", &resumed, cb, fakeCheckKeys); err != nil {
+		t.Fatal(err)
+	}
+	if len(resumed.stoppedUnits) != 0 {
+		t.Error(fmt.Errorf("Expected completed steps to be skipped, Got %v", resumed.stoppedUnits))
+	}
+	if !reflect.DeepEqual(resumed.restartedUnits, []string{"router"}) {
+		t.Error(fmt.Errorf("Expected [router], Got %v", resumed.restartedUnits))
+	}
+	if v := cb[service]; v != "10.0.0.1:49153" {
+		t.Error(fmt.Errorf("Expected the service to be republished, Got %q", v))
+	}
+	for _, key := range []string{"step", "services"} {
+		if _, ok := cb[upgradeKey+"/"+key]; ok {
+			t.Error(fmt.Errorf("Expected %s to be cleared", key))
+		}
+	}
+}
+
+func TestUpgradePreflight(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(fakeHTTPServer{})
+	defer server.Close()
+
+	b := backendStub{}
+	cb := configStore{"/deis/platform/version": "v1.7.1"}
+
+	err := Upgrade("2.0.0", os.TempDir(), server.URL+"/", &b, cb, fakeCheckKeys)
+	if err == nil || !strings.HasPrefix(err.Error(), "preflight: unit files for v2.0.0 are not available") {
+		t.Error(fmt.Errorf("Expected the preflight checks to fail, Got %v", err))
+	}
+
+	err = Upgrade("1.7.2", os.TempDir(), server.URL+"/", &b, cb, func(config.Backend) error {
+		return errors.New("Missing platform domain")
+	})
+	if err == nil {
+		t.Error("Expected missing keys to fail the preflight checks")
+	}
+
+	if len(cb) != 1 || len(b.stoppedUnits) != 0 {
+		t.Error(fmt.Errorf("Expected the preflight checks to change nothing, Got %v, %v", cb, b.stoppedUnits))
+	}
+
+	unhealthy := etcdStub{configStore: cb, members: []*model.EtcdMember{
+		{Endpoint: "http://10.0.0.1:2379", Version: "2.2.0"},
+		{Endpoint: "http://10.0.0.2:2379", Error: "connection refused"},
+	}}
+	err = Upgrade("1.7.2", os.TempDir(), server.URL+"/", &b, unhealthy, fakeCheckKeys)
+	expected := "preflight: etcd is not healthy: http://10.0.0.2:2379: connection refused"
+	if err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, err))
+	}
+}
@@ -0,0 +1,580 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/config"
+	"github.com/deis/deis/deisctl/config/model"
+	"github.com/deis/deis/deisctl/units"
+)
+
+// minDockerVersion is the oldest Docker the platform runs on, as shipped with CoreOS 766.3.0.
+const minDockerVersion = "1.7.1"
+
+// Clock skew between machines confuses etcd leases and the TTLs components publish with.
+const (
+	warnClockSkew = 2 * time.Second
+	failClockSkew = 10 * time.Second
+)
+
+// certExpiryWarning is how long before the router's certificate expires doctor warns.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// probeCommand prints what doctor needs to know about a machine as key=value lines: its
+// clock, the version of Docker if the daemon is up, and the ports already listening.
+const probeCommand = `echo "clock=$(date -u +%s)"; ` +
+	`echo "docker=$(docker info >/dev/null 2>&1 && docker -v | sed -n 's/^Docker version \([^,]*\),.*/\1/p')"; ` +
+	`echo "tcp=$(ss -ltn | awk 'NR>1 {n=split($4,a,":"); print a[n]}' | tr '\n' ' ')"; ` +
+	`echo "udp=$(ss -lun | awk 'NR>1 {n=split($4,a,":"); print a[n]}' | tr '\n' ' ')"`
+
+// requiredPorts are the ports components publish on their machines, which must be free
+// before they are installed.
+var requiredPorts = map[string][]string{
+	"router":        {"80/tcp", "443/tcp", "2222/tcp", "9090/tcp"},
+	"builder":       {"2223/tcp"},
+	"registry":      {"5000/tcp"},
+	"database":      {"5432/tcp"},
+	"controller":    {"8000/tcp"},
+	"store-gateway": {"8888/tcp"},
+	"logger":        {"514/udp"},
+}
+
+// etcdCluster is implemented by config backends which can report on the etcd cluster.
+type etcdCluster interface {
+	Members() ([]*model.EtcdMember, error)
+}
+
+// Outcomes of a check.
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+// checkResult is the outcome of one check, with a hint on how to fix it if it did not pass.
+type checkResult struct {
+	Status  string
+	Check   string
+	Message string
+	Hint    string
+}
+
+// probe is what a machine reported in answer to probeCommand.
+type probe struct {
+	// Skew is how far the machine's clock is ahead of the local one.
+	Skew time.Duration
+	// Docker is the version of Docker, or empty if the daemon is not running.
+	Docker string
+	// Listening holds the ports in use, such as "80/tcp".
+	Listening map[string]bool
+	Err       error
+}
+
+// doctor checks that a cluster is ready for the platform to be installed.
+type doctor struct {
+	b          backend.Backend
+	cb         config.Backend
+	lookupHost func(string) ([]string, error)
+	now        func() time.Time
+
+	machines    []backend.Machine
+	machinesErr error
+	probes      map[string]*probe
+	// eligible are the machines each component may be scheduled on.
+	eligible map[string][]backend.Machine
+}
+
+// Doctor checks that a cluster is ready for the platform: that there are enough machines
+// for each component, that etcd is healthy, that the machines agree on the time, run a
+// recent Docker and have the platform's ports free, and that the domain and certificate
+// of the routers are usable. It returns an error if any check fails.
+func Doctor(b backend.Backend, cb config.Backend) error {
+	d := &doctor{b: b, cb: cb, lookupHost: net.LookupHost, now: time.Now}
+	return d.run(Stdout)
+}
+
+func (d *doctor) run(out io.Writer) error {
+	var results []checkResult
+	for _, check := range []func() []checkResult{
+		d.checkPlacement,
+		d.checkEtcd,
+		d.checkMachines,
+		d.checkClocks,
+		d.checkDocker,
+		d.checkPorts,
+		d.checkDomain,
+		d.checkSSL,
+	} {
+		results = append(results, check()...)
+	}
+
+	failed := 0
+	for _, r := range results {
+		fmt.Fprintf(out, "[%s] %s: %s\n", r.Status, r.Check, r.Message)
+		if r.Hint != "" && r.Status != checkPass {
+			fmt.Fprintf(out, "       %s\n", r.Hint)
+		}
+		if r.Status == checkFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	fmt.Fprintln(out, "\nThe cluster is ready for the platform.")
+	return nil
+}
+
+// checkPlacement verifies that each component of the platform has enough machines
+// matching its MachineMetadata, counting one machine per instance for components whose
+// instances conflict with each other.
+func (d *doctor) checkPlacement() []checkResult {
+	machines, err := d.b.Machines()
+	if err != nil {
+		d.machinesErr = err
+		return []checkResult{{checkWarn, "placement", "skipped: " + err.Error(), ""}}
+	}
+	d.machines = machines
+	d.eligible = make(map[string][]backend.Machine)
+
+	decorate, err := d.cb.GetWithDefault("/deis/platform/enablePlacementOptions", "false")
+	if err != nil {
+		return []checkResult{{checkFail, "placement", err.Error(), ""}}
+	}
+
+	var results []checkResult
+	for _, c := range platformGraph {
+		vars, err := units.LoadVariables(c.Name, d.cb)
+		if err != nil {
+			return append(results, checkResult{checkFail, "placement", err.Error(), ""})
+		}
+		unit, err := units.Render(c.Name, units.TemplatePaths(), decorate == "true", vars)
+		if err != nil {
+			results = append(results, checkResult{checkFail, "placement", err.Error(),
+				"run `deisctl refresh-units` to fetch the unit templates"})
+			continue
+		}
+		options := fleetOptions(unit)
+		f, err := backend.ParseMetadataFilter(strings.Join(options["MachineMetadata"], ","))
+		if err != nil {
+			results = append(results, checkResult{checkFail, "placement", c.Name + ": " + err.Error(), ""})
+			continue
+		}
+		eligible := backend.FilterMachines(machines, f)
+		d.eligible[c.Name] = eligible
+
+		need := 1
+		for _, conflict := range options["Conflicts"] {
+			if conflict == "deis-"+c.Name+"@*.service" && c.Instances != nil {
+				need = c.Instances()
+			}
+		}
+		where := "any machine"
+		if len(f) > 0 {
+			where = "machines with " + f.String()
+		}
+		r := checkResult{checkPass, "placement",
+			fmt.Sprintf("%s needs %d of %s, found %d", c.Name, need, where, len(eligible)), ""}
+		if len(eligible) < need {
+			r.Status = checkFail
+			r.Hint = fmt.Sprintf("add machines with %s, or use `deisctl config units set %s/machineMetadata=<key>=<value>`",
+				f, c.Name)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// fleetOptions returns the options of the [X-Fleet] section of a unit, with multiple values
+// on one line split apart and unquoted.
+func fleetOptions(unit string) map[string][]string {
+	options := make(map[string][]string)
+	section := ""
+	scanner := bufio.NewScanner(strings.NewReader(unit))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if section != "[X-Fleet]" || len(kv) != 2 {
+			continue
+		}
+		for _, v := range strings.Fields(kv[1]) {
+			options[kv[0]] = append(options[kv[0]], strings.Trim(v, `"`))
+		}
+	}
+	return options
+}
+
+// checkEtcd verifies that every member of the etcd cluster is healthy and runs etcd 2.
+func (d *doctor) checkEtcd() []checkResult {
+	cluster, ok := d.cb.(etcdCluster)
+	if !ok {
+		return []checkResult{{checkWarn, "etcd", "skipped: the configuration backend is not etcd", ""}}
+	}
+	members, err := cluster.Members()
+	if err != nil {
+		return []checkResult{{checkFail, "etcd", err.Error(),
+			"check that etcd is running, or set DEISCTL_TUNNEL to a machine of the cluster"}}
+	}
+
+	var results []checkResult
+	versions := make(map[string]bool)
+	for _, m := range members {
+		r := checkResult{checkPass, "etcd", fmt.Sprintf("%s is healthy, version %s", m.Endpoint, orDash(m.Version)), ""}
+		switch {
+		case m.Error != "":
+			r.Status = checkFail
+			r.Message = fmt.Sprintf("%s: %s", m.Endpoint, m.Error)
+			r.Hint = "check `journalctl -u etcd2` on the machine"
+		case m.Version != "" && versionLess(m.Version, "2.0.0"):
+			r.Status = checkFail
+			r.Message = fmt.Sprintf("%s runs etcd %s, but the platform requires etcd 2", m.Endpoint, orDash(m.Version))
+			r.Hint = "upgrade CoreOS, which ships etcd2"
+		default:
+			versions[m.Version] = true
+		}
+		results = append(results, r)
+	}
+	if len(versions) > 1 {
+		results = append(results, checkResult{checkWarn, "etcd",
+			fmt.Sprintf("members run different versions: %s", strings.Join(sortedKeys(versions), ", ")),
+			"finish upgrading CoreOS on every machine"})
+	}
+	return results
+}
+
+// checkMachines runs probeCommand on every machine at once, and verifies each answered.
+func (d *doctor) checkMachines() []checkResult {
+	if d.machinesErr != nil {
+		return []checkResult{{checkWarn, "ssh", "skipped: " + d.machinesErr.Error(),
+			"clock skew, Docker and free ports are only checked on fleet and local clusters"}}
+	}
+	d.probes = make(map[string]*probe, len(d.machines))
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, m := range d.machines {
+		wg.Add(1)
+		go func(m backend.Machine) {
+			defer wg.Done()
+			p := d.probe(m)
+			mutex.Lock()
+			d.probes[m.ID] = p
+			mutex.Unlock()
+		}(m)
+	}
+	wg.Wait()
+
+	var results []checkResult
+	for _, m := range d.machines {
+		p := d.probes[m.ID]
+		if p.Err != nil {
+			results = append(results, checkResult{checkFail, "ssh", fmt.Sprintf("%s: %v", m.Host, p.Err),
+				"check that the key of the cluster is loaded with `ssh-add`"})
+			continue
+		}
+		results = append(results, checkResult{checkPass, "ssh", m.Host + " is reachable", ""})
+	}
+	return results
+}
+
+func (d *doctor) probe(m backend.Machine) *probe {
+	var output, errors bytes.Buffer
+	start := d.now()
+	err := d.b.Exec([]backend.Machine{m}, probeCommand, &output, &errors)
+	// the remote clock was read somewhere during the round trip
+	local := start.Add(d.now().Sub(start) / 2)
+	if err != nil {
+		return &probe{Err: err}
+	}
+
+	p := &probe{Listening: make(map[string]bool)}
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "clock":
+			seconds, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return &probe{Err: fmt.Errorf("could not read the clock: %q", kv[1])}
+			}
+			p.Skew = time.Unix(seconds, 0).Sub(local)
+		case "docker":
+			p.Docker = kv[1]
+		case "tcp", "udp":
+			for _, port := range strings.Fields(kv[1]) {
+				p.Listening[port+"/"+kv[0]] = true
+			}
+		}
+	}
+	return p
+}
+
+// reachable returns the machines which answered the probe, in order.
+func (d *doctor) reachable() []backend.Machine {
+	var machines []backend.Machine
+	for _, m := range d.machines {
+		if p := d.probes[m.ID]; p != nil && p.Err == nil {
+			machines = append(machines, m)
+		}
+	}
+	return machines
+}
+
+// checkClocks verifies that the clocks of the machines agree, comparing each one to the
+// local clock so that a skewed workstation does not fail the check.
+func (d *doctor) checkClocks() []checkResult {
+	machines := d.reachable()
+	if len(machines) == 0 {
+		return nil
+	}
+	earliest, latest := machines[0], machines[0]
+	for _, m := range machines {
+		if d.probes[m.ID].Skew < d.probes[earliest.ID].Skew {
+			earliest = m
+		}
+		if d.probes[m.ID].Skew > d.probes[latest.ID].Skew {
+			latest = m
+		}
+	}
+	skew := d.probes[latest.ID].Skew - d.probes[earliest.ID].Skew
+	r := checkResult{checkPass, "clock", fmt.Sprintf("machines agree within %v", maxDuration(skew, time.Second)), ""}
+	if skew > warnClockSkew {
+		r.Status = checkWarn
+		if skew > failClockSkew {
+			r.Status = checkFail
+		}
+		r.Message = fmt.Sprintf("%s is %v ahead of %s", latest.Host, skew, earliest.Host)
+		r.Hint = "enable NTP with `sudo timedatectl set-ntp true` on every machine"
+	}
+	return []checkResult{r}
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// checkDocker verifies that Docker is running and recent enough on every machine.
+func (d *doctor) checkDocker() []checkResult {
+	var results []checkResult
+	versions := make(map[string]bool)
+	for _, m := range d.reachable() {
+		p := d.probes[m.ID]
+		r := checkResult{checkPass, "docker", fmt.Sprintf("%s runs Docker %s", m.Host, p.Docker), ""}
+		switch {
+		case p.Docker == "":
+			r.Status = checkFail
+			r.Message = m.Host + ": Docker is not running"
+			r.Hint = "start it with `sudo systemctl start docker`"
+		case versionLess(p.Docker, minDockerVersion):
+			r.Status = checkFail
+			r.Message = fmt.Sprintf("%s runs Docker %s, but the platform requires %s", m.Host, p.Docker, minDockerVersion)
+			r.Hint = "upgrade CoreOS on the machine"
+		default:
+			versions[p.Docker] = true
+		}
+		results = append(results, r)
+	}
+	if len(versions) > 1 {
+		results = append(results, checkResult{checkWarn, "docker",
+			fmt.Sprintf("machines run different versions: %s", strings.Join(sortedKeys(versions), ", ")),
+			"finish upgrading CoreOS on every machine"})
+	}
+	return results
+}
+
+// checkPorts verifies that the ports of components which are not installed yet are free
+// on the machines they may be scheduled on.
+func (d *doctor) checkPorts() []checkResult {
+	machines := d.reachable()
+	if len(machines) == 0 {
+		return nil
+	}
+	states, err := d.b.UnitStates()
+	if err != nil {
+		return []checkResult{{checkFail, "ports", err.Error(), ""}}
+	}
+	installed := make(map[string]bool)
+	for _, s := range states {
+		if match := unitNameRegexp.FindStringSubmatch(s.Name); match != nil {
+			installed[match[1]] = true
+		}
+	}
+
+	var results []checkResult
+	for _, c := range platformGraph {
+		ports := requiredPorts[c.Name]
+		if len(ports) == 0 || installed[c.Name] {
+			continue
+		}
+		for _, m := range d.eligible[c.Name] {
+			p := d.probes[m.ID]
+			if p == nil || p.Err != nil {
+				continue
+			}
+			for _, port := range ports {
+				if p.Listening[port] {
+					results = append(results, checkResult{checkFail, "ports",
+						fmt.Sprintf("%s: %s is in use, but %s needs it", m.Host, port, c.Name),
+						fmt.Sprintf("stop what listens there, or keep %s off the machine with `deisctl config units set %s/machineMetadata=<key>=<value>`",
+							c.Name, c.Name)})
+				}
+			}
+		}
+	}
+	if len(results) == 0 {
+		results = append(results, checkResult{checkPass, "ports", "the ports of the platform are free", ""})
+	}
+	return results
+}
+
+// checkDomain verifies that the platform domain is set and resolves to the routers, and
+// warns if applications under it will not resolve.
+func (d *doctor) checkDomain() []checkResult {
+	domain, err := d.cb.GetWithDefault("/deis/platform/domain", "")
+	if err != nil {
+		return []checkResult{{checkFail, "domain", err.Error(), ""}}
+	}
+	if domain == "" {
+		return []checkResult{{checkFail, "domain", "the platform domain is not set",
+			"use `deisctl config platform set domain=<your-domain>`"}}
+	}
+
+	controller := "deis." + domain
+	addrs, err := d.lookupHost(controller)
+	if err != nil || len(addrs) == 0 {
+		return []checkResult{{checkFail, "domain", fmt.Sprintf("%s does not resolve", controller),
+			fmt.Sprintf("add a wildcard DNS record for *.%s pointing to the routers or their load balancer", domain)}}
+	}
+	results := []checkResult{{checkPass, "domain", fmt.Sprintf("%s resolves to %s", controller, strings.Join(addrs, ", ")), ""}}
+
+	// an application name nobody would choose tells a wildcard record from one for deis alone
+	if addrs, err := d.lookupHost("deisctl-doctor." + domain); err != nil || len(addrs) == 0 {
+		results = append(results, checkResult{checkWarn, "domain",
+			fmt.Sprintf("*.%s does not resolve, so applications will not be reachable by name", domain),
+			fmt.Sprintf("add a wildcard DNS record for *.%s", domain)})
+	}
+	return results
+}
+
+// checkSSL verifies that the router's certificate matches its key, is current and covers
+// the platform domain.
+func (d *doctor) checkSSL() []checkResult {
+	cert, err := d.cb.GetWithDefault("/deis/router/sslCert", "")
+	if err != nil {
+		return []checkResult{{checkFail, "ssl", err.Error(), ""}}
+	}
+	key, err := d.cb.GetWithDefault("/deis/router/sslKey", "")
+	if err != nil {
+		return []checkResult{{checkFail, "ssl", err.Error(), ""}}
+	}
+	const hint = "use `deisctl config router set sslCert=<path-to-cert> sslKey=<path-to-key>`"
+	switch {
+	case cert == "" && key == "":
+		return []checkResult{{checkWarn, "ssl", "no certificate is set, so the routers will only serve HTTP", hint}}
+	case cert == "" || key == "":
+		return []checkResult{{checkFail, "ssl", "only one of sslCert and sslKey is set", hint}}
+	}
+
+	pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+	if err != nil {
+		return []checkResult{{checkFail, "ssl", fmt.Sprintf("the certificate and key do not form a pair: %v", err), hint}}
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return []checkResult{{checkFail, "ssl", err.Error(), hint}}
+	}
+
+	now := d.now()
+	var results []checkResult
+	switch {
+	case now.After(leaf.NotAfter):
+		results = append(results, checkResult{checkFail, "ssl",
+			fmt.Sprintf("the certificate expired on %s", leaf.NotAfter.Format("2006-01-02")), hint})
+	case now.Add(certExpiryWarning).After(leaf.NotAfter):
+		results = append(results, checkResult{checkWarn, "ssl",
+			fmt.Sprintf("the certificate expires on %s", leaf.NotAfter.Format("2006-01-02")), hint})
+	case now.Before(leaf.NotBefore):
+		results = append(results, checkResult{checkFail, "ssl",
+			fmt.Sprintf("the certificate is not valid until %s", leaf.NotBefore.Format("2006-01-02")), hint})
+	}
+
+	if domain, _ := d.cb.GetWithDefault("/deis/platform/domain", ""); domain != "" {
+		if err := leaf.VerifyHostname("deis." + domain); err != nil {
+			results = append(results, checkResult{checkFail, "ssl", err.Error(),
+				fmt.Sprintf("use a certificate for *.%s", domain)})
+		}
+	}
+	if len(results) == 0 {
+		results = append(results, checkResult{checkPass, "ssl",
+			fmt.Sprintf("the certificate is valid until %s", leaf.NotAfter.Format("2006-01-02")), ""})
+	}
+	return results
+}
+
+// versionLess compares dotted version numbers such as "1.7.1", ignoring any suffix such
+// as "-rc1". An unreadable version counts as older than any other.
+func versionLess(a, b string) bool {
+	pa, pb := versionParts(a), versionParts(b)
+	if pa == nil || pb == nil {
+		return pa == nil && pb != nil
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
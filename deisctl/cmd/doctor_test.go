@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/config/model"
+)
+
+// probeStub is a backend whose machines answer the probe of doctor.
+type probeStub struct {
+	backendStub
+	// answers maps the host of a machine to its answer, or to "" if it cannot be reached.
+	answers map[string]string
+}
+
+func (p *probeStub) Exec(machines []backend.Machine, command string, out, ew io.Writer) error {
+	answer := p.answers[machines[0].Host]
+	if answer == "" {
+		return fmt.Errorf("ssh: handshake failed")
+	}
+	fmt.Fprintf(out, "==> %s <==\n%s\n", machines[0].Host, answer)
+	return nil
+}
+
+// etcdStub is a config backend reporting on the members of an etcd cluster.
+type etcdStub struct {
+	configStore
+	members []*model.EtcdMember
+}
+
+func (e etcdStub) Members() ([]*model.EtcdMember, error) {
+	return e.members, nil
+}
+
+var doctorNow = time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)
+
+func answer(skew time.Duration, docker, tcp string) string {
+	return fmt.Sprintf("clock=%d\ndocker=%s\ntcp=%s\nudp=68 ", doctorNow.Add(skew).Unix(), docker, tcp)
+}
+
+func newDoctor(b backend.Backend, cb etcdStub) *doctor {
+	return &doctor{b: b, cb: cb, now: func() time.Time { return doctorNow },
+		lookupHost: func(host string) ([]string, error) {
+			if strings.HasSuffix(host, ".example.com") {
+				return []string{"10.0.0.2", "10.0.0.3"}, nil
+			}
+			return nil, fmt.Errorf("no such host")
+		}}
+}
+
+func TestDoctor(t *testing.T) {
+	t.Parallel()
+
+	b := &probeStub{answers: map[string]string{
+		"10.0.0.1": answer(0, "1.8.3", "22 2379 4001 "),
+		"10.0.0.2": answer(time.Second, "1.8.3", "22 2379 4001 "),
+		"10.0.0.3": answer(0, "1.8.3", "22 2379 4001 "),
+	}}
+	cb := etcdStub{configStore: configStore{"/deis/platform/domain": "example.com"},
+		members: []*model.EtcdMember{{Endpoint: "http://10.0.0.1:2379", Version: "2.2.0"}}}
+
+	var out bytes.Buffer
+	if err := newDoctor(b, cb).run(&out); err != nil {
+		t.Fatal(fmt.Errorf("%v\n%s", err, out.String()))
+	}
+	for _, expected := range []string{
+		"[PASS] placement: router needs 3 of any machine, found 3",
+		"[PASS] etcd: http://10.0.0.1:2379 is healthy, version 2.2.0",
+		"[PASS] ssh: 10.0.0.3 is reachable",
+		"[PASS] clock: machines agree within 1s",
+		"[PASS] docker: 10.0.0.1 runs Docker 1.8.3",
+		"[PASS] ports: the ports of the platform are free",
+		"[PASS] domain: deis.example.com resolves to 10.0.0.2, 10.0.0.3",
+		"[WARN] ssl: no certificate is set, so the routers will only serve HTTP",
+	} {
+		if !strings.Contains(out.String(), expected+"\n") {
+			t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+		}
+	}
+}
+
+func TestDoctorFailures(t *testing.T) {
+	t.Parallel()
+
+	b := &probeStub{answers: map[string]string{
+		"10.0.0.1": answer(0, "1.8.3", "22 5000 "),
+		"10.0.0.2": answer(30*time.Second, "1.6.2", "22 80 "),
+	}}
+	// the router is installed, so its ports are expected to be in use
+	b.unitStates = []backend.UnitState{{Name: "deis-router@1.service", Host: "10.0.0.2"}}
+	cb := etcdStub{
+		configStore: configStore{
+			"/deis/platform/domain":                 "example.org",
+			"/deis/platform/enablePlacementOptions": "true",
+		},
+		members: []*model.EtcdMember{
+			{Endpoint: "http://10.0.0.1:2379", Version: "2.2.0"},
+			{Endpoint: "http://10.0.0.2:2379", Error: "reports itself unhealthy"},
+		}}
+
+	var out bytes.Buffer
+	err := newDoctor(b, cb).run(&out)
+	if err == nil || !strings.HasSuffix(err.Error(), "checks failed") {
+		t.Error(fmt.Errorf("Expected checks to fail, Got %v", err))
+	}
+	for _, expected := range []string{
+		"[FAIL] placement: router needs 3 of machines with routerMesh=true, found 0\n" +
+			"       add machines with routerMesh=true, or use `deisctl config units set router/machineMetadata=<key>=<value>`",
+		"[FAIL] etcd: http://10.0.0.2:2379: reports itself unhealthy",
+		"[FAIL] ssh: 10.0.0.3: ssh: handshake failed",
+		"[FAIL] clock: 10.0.0.2 is 30s ahead of 10.0.0.1",
+		"[FAIL] docker: 10.0.0.2 runs Docker 1.6.2, but the platform requires 1.7.1",
+		"[FAIL] domain: deis.example.org does not resolve",
+	} {
+		if !strings.Contains(out.String(), expected+"\n") {
+			t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+		}
+	}
+	// no machine is labeled for the control plane, so the registry may not run on 10.0.0.1
+	if strings.Contains(out.String(), "[FAIL] ports") {
+		t.Error(fmt.Errorf("Expected no ports to be reported, Got %q", out.String()))
+	}
+}
+
+func TestDoctorPorts(t *testing.T) {
+	t.Parallel()
+
+	b := &probeStub{answers: map[string]string{
+		"10.0.0.1": answer(0, "1.8.3", "22 5000 "),
+		"10.0.0.2": answer(0, "1.8.3", "22 80 "),
+		"10.0.0.3": answer(0, "1.8.3", "22 "),
+	}}
+	b.unitStates = []backend.UnitState{{Name: "deis-router@1.service", Host: "10.0.0.2"}}
+	d := newDoctor(b, etcdStub{configStore: configStore{}})
+	d.checkPlacement()
+	d.checkMachines()
+
+	results := d.checkPorts()
+	expected := "10.0.0.1: 5000/tcp is in use, but registry needs it"
+	if len(results) != 1 || results[0].Message != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, results))
+	}
+}
+
+func TestDoctorSSL(t *testing.T) {
+	t.Parallel()
+
+	cert, key := selfSigned(t, "*.example.com", doctorNow.Add(10*24*time.Hour))
+	otherCert, _ := selfSigned(t, "*.example.com", doctorNow.Add(365*24*time.Hour))
+	wrongCert, wrongKey := selfSigned(t, "*.example.org", doctorNow.Add(365*24*time.Hour))
+
+	for _, test := range []struct {
+		cert, key string
+		expected  checkResult
+	}{
+		{cert, key, checkResult{Status: checkWarn, Message: "the certificate expires on 2016-01-12"}},
+		{otherCert, key, checkResult{Status: checkFail,
+			Message: "the certificate and key do not form a pair: tls: private key does not match public key"}},
+		{cert, "", checkResult{Status: checkFail, Message: "only one of sslCert and sslKey is set"}},
+		{wrongCert, wrongKey, checkResult{Status: checkFail,
+			Message: "x509: certificate is valid for *.example.org, not deis.example.com"}},
+	} {
+		cb := etcdStub{configStore: configStore{
+			"/deis/platform/domain": "example.com",
+			"/deis/router/sslCert":  test.cert,
+			"/deis/router/sslKey":   test.key,
+		}}
+		results := newDoctor(&backendStub{}, cb).checkSSL()
+		if len(results) != 1 || results[0].Status != test.expected.Status || results[0].Message != test.expected.Message {
+			t.Error(fmt.Errorf("Expected %+v, Got %+v", test.expected, results))
+		}
+	}
+}
+
+// selfSigned returns a PEM encoded certificate for a host and its key.
+func selfSigned(t *testing.T, host string, notAfter time.Time) (string, string) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    doctorNow.Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestVersionLess(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		a, b     string
+		expected bool
+	}{
+		{"1.6.2", "1.7.1", true},
+		{"1.10.0", "1.7.1", false},
+		{"1.7.1", "1.7.1", false},
+		{"1.7", "1.7.1", true},
+		{"2.0.0-rc1", "2.0.0", false},
+		{"unknown", "1.7.1", true},
+	} {
+		if versionLess(test.a, test.b) != test.expected {
+			t.Error(fmt.Errorf("Expected versionLess(%s, %s) to be %v", test.a, test.b, test.expected))
+		}
+	}
+}
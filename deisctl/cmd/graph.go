@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/units"
+)
+
+// component is a node in a dependency graph of units managed together, such as the
+// platform. A component is only started once everything it requires has started, and
+// is stopped before anything it requires is stopped.
+type component struct {
+	Name      string
+	Subsystem string
+	Requires  []string
+	// Stateful components are left out of the stateless platform.
+	Stateful bool
+	// Scalable components run as templated name@N units.
+	Scalable bool
+	// Instances returns how many units of a scalable component to install, defaulting to 1.
+	Instances func() int
+	// Publishes lists the keys a running component keeps refreshed in etcd. A key ending
+	// in "/*" is a directory in which each instance publishes its own key.
+	Publishes []string
+}
+
+// graph is a list of components in declaration order, which is also the order used for
+// components that can be acted upon at the same time.
+type graph []component
+
+// platformGraph declares the Deis platform components and their dependencies.
+var platformGraph = graph{
+	{Name: "store-monitor", Subsystem: "Storage subsystem", Stateful: true},
+	{Name: "store-daemon", Subsystem: "Storage subsystem", Stateful: true,
+		Requires: []string{"store-monitor"}},
+	{Name: "store-metadata", Subsystem: "Storage subsystem", Stateful: true,
+		Requires: []string{"store-daemon"}},
+	{Name: "store-gateway", Subsystem: "Storage subsystem", Stateful: true, Scalable: true,
+		Requires:  []string{"store-metadata"},
+		Publishes: []string{"/deis/store/gateway/host", "/deis/store/gateway/port"}},
+	{Name: "store-volume", Subsystem: "Storage subsystem", Stateful: true,
+		Requires: []string{"store-metadata"}},
+	{Name: "logger", Subsystem: "Logging subsystem", Stateful: true,
+		Requires:  []string{"store-volume"},
+		Publishes: []string{"/deis/logs/host", "/deis/logs/port"}},
+	// logging comes up first to collect logs from the other components
+	{Name: "logspout", Subsystem: "Logging subsystem",
+		Requires: []string{"logger"}},
+	{Name: "database", Subsystem: "Control plane", Stateful: true,
+		Requires:  []string{"logspout", "store-gateway"},
+		Publishes: []string{"/deis/database/host", "/deis/database/port"}},
+	{Name: "registry", Subsystem: "Control plane", Scalable: true,
+		Requires:  []string{"logspout", "store-gateway"},
+		Publishes: []string{"/deis/registry/host", "/deis/registry/port"}},
+	{Name: "controller", Subsystem: "Control plane",
+		Requires:  []string{"database", "registry"},
+		Publishes: []string{"/deis/controller/host", "/deis/controller/port"}},
+	{Name: "builder", Subsystem: "Control plane",
+		Requires:  []string{"controller", "registry"},
+		Publishes: []string{"/deis/builder/host", "/deis/builder/port"}},
+	{Name: "publisher", Subsystem: "Data plane",
+		Requires: []string{"logspout"}},
+	{Name: "router", Subsystem: "Router mesh", Scalable: true,
+		Instances: func() int { return int(RouterMeshSize) },
+		Requires:  []string{"controller"},
+		Publishes: []string{"/deis/router/hosts/*"}},
+}
+
+var mesosGraph = graph{
+	{Name: "zookeeper", Subsystem: "Mesos/Marathon control plane"},
+	{Name: "mesos-master", Subsystem: "Mesos/Marathon control plane",
+		Requires: []string{"zookeeper"}},
+	{Name: "mesos-marathon", Subsystem: "Mesos/Marathon control plane",
+		Requires: []string{"mesos-master"}},
+	{Name: "mesos-slave", Subsystem: "Mesos/Marathon data plane",
+		Requires: []string{"mesos-marathon"}},
+}
+
+var swarmGraph = graph{
+	{Name: "swarm-manager", Subsystem: "Swarm control plane"},
+	{Name: "swarm-node", Subsystem: "Swarm data plane",
+		Requires: []string{"swarm-manager"}},
+}
+
+var k8sGraph = graph{
+	{Name: "kube-apiserver", Subsystem: "K8s control plane"},
+	{Name: "kube-controller-manager", Subsystem: "K8s control plane",
+		Requires: []string{"kube-apiserver"}},
+	{Name: "kube-scheduler", Subsystem: "K8s control plane",
+		Requires: []string{"kube-apiserver"}},
+	{Name: "kube-kubelet", Subsystem: "K8s data plane",
+		Requires: []string{"kube-controller-manager", "kube-scheduler"}},
+	{Name: "kube-proxy", Subsystem: "K8s router mesh",
+		Requires: []string{"kube-kubelet"}},
+}
+
+// platform returns the platform graph, without stateful components if stateless is set.
+func platform(stateless bool) graph {
+	if !stateless {
+		return platformGraph
+	}
+	return platformGraph.without(func(c component) bool { return c.Stateful })
+}
+
+// without returns the graph minus the components matching exclude. Dependencies on
+// excluded components are dropped, so the remaining components keep their ordering.
+func (g graph) without(exclude func(component) bool) graph {
+	var kept graph
+	for _, c := range g {
+		if !exclude(c) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// layers sorts the graph topologically into groups of components whose requirements are
+// all satisfied by earlier groups. Requirements on components missing from the graph are
+// ignored, but must be declared in one of the known graphs.
+func (g graph) layers() ([][]component, error) {
+	index := make(map[string]int, len(g))
+	for i, c := range g {
+		index[c.Name] = i
+	}
+
+	depth := make([]int, len(g))
+	state := make([]int, len(g)) // 0 unvisited, 1 visiting, 2 done
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case 1:
+			return fmt.Errorf("dependency cycle detected at %s", g[i].Name)
+		case 2:
+			return nil
+		}
+		state[i] = 1
+		for _, name := range g[i].Requires {
+			j, ok := index[name]
+			if !ok {
+				if !isKnownComponent(name) {
+					return fmt.Errorf("%s requires unknown component %s", g[i].Name, name)
+				}
+				continue
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+			if depth[j]+1 > depth[i] {
+				depth[i] = depth[j] + 1
+			}
+		}
+		state[i] = 2
+		return nil
+	}
+
+	var layers [][]component
+	for i := range g {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	for i, c := range g {
+		for len(layers) <= depth[i] {
+			layers = append(layers, nil)
+		}
+		layers[depth[i]] = append(layers[depth[i]], c)
+	}
+	return layers, nil
+}
+
+// knownGraphs returns every graph of components deisctl manages, including the custom
+// components registered with the platform.
+func knownGraphs() []graph {
+	return []graph{platformGraph, mesosGraph, swarmGraph, k8sGraph}
+}
+
+func isKnownComponent(name string) bool {
+	_, ok := lookupComponent(name)
+	return ok
+}
+
+// lookupComponent returns the declaration of a component from the known graphs.
+func lookupComponent(name string) (component, bool) {
+	for _, g := range knownGraphs() {
+		for _, c := range g {
+			if c.Name == name {
+				return c, true
+			}
+		}
+	}
+	return component{}, false
+}
+
+// unitNames returns the unit names for a group of components, addressing every instance
+// of scalable components.
+func unitNames(layer []component) []string {
+	var names []string
+	for _, c := range layer {
+		if c.Scalable {
+			names = append(names, c.Name+"@*")
+		} else {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// instanceUnitNames returns the unit names to install for a group of components.
+func instanceUnitNames(layer []component) []string {
+	var names []string
+	for _, c := range layer {
+		if !c.Scalable {
+			names = append(names, c.Name)
+			continue
+		}
+		n := 1
+		if c.Instances != nil {
+			n = c.Instances()
+		}
+		for i := 1; i <= n; i++ {
+			names = append(names, fmt.Sprintf("%s@%d", c.Name, i))
+		}
+	}
+	return names
+}
+
+// walk calls action on each layer of the graph in dependency order, or in reverse
+// dependency order if reverse is set, waiting for each layer before the next. Walking in
+// dependency order stops at the first layer which fails, so that nothing is acted on
+// before the components it requires. Walking in reverse carries on past failures, so that
+// as much as possible is stopped or removed, and returns the failures of every layer.
+func walk(g graph, reverse bool, targets func([]component) []string,
+	action func([]string, io.Writer) error, out io.Writer) error {
+
+	layers, err := g.layers()
+	if err != nil {
+		return err
+	}
+
+	errs := backend.NewErrors(nil)
+
+	announced := make(map[string]bool)
+	for i := range layers {
+		layer := layers[i]
+		if reverse {
+			layer = layers[len(layers)-1-i]
+		}
+		for _, c := range layer {
+			if !announced[c.Subsystem] {
+				announced[c.Subsystem] = true
+				fmt.Fprintf(out, "%s...\n", c.Subsystem)
+			}
+		}
+		if err := action(targets(layer), out); err != nil {
+			if !reverse {
+				return err
+			}
+			errs.Add(err)
+		}
+	}
+	return errs.Err()
+}
+
+func startComponents(b backend.Backend, g graph, out io.Writer) error {
+	return walk(g, false, unitNames, b.Start, out)
+}
+
+func stopComponents(b backend.Backend, g graph, out io.Writer) error {
+	return walk(g, true, unitNames, b.Stop, out)
+}
+
+func installComponents(b backend.Backend, g graph, out io.Writer) error {
+	return walk(g, false, instanceUnitNames, b.Create, out)
+}
+
+func uninstallComponents(b backend.Backend, g graph, out io.Writer) error {
+	return walk(g, true, unitNames, b.Destroy, out)
+}
+
+// RegisterComponents adds custom components to the platform, after the built in ones, so
+// that they are installed, started and stopped with it.
+func RegisterComponents(custom []units.Component) error {
+	g, err := platformGraph.with(custom)
+	if err != nil {
+		return err
+	}
+	platformGraph = g
+	return nil
+}
+
+// with returns the graph followed by custom components. A custom component may only
+// require components declared before it, so that the graph stays in declaration order.
+func (g graph) with(custom []units.Component) (graph, error) {
+	extended := append(graph(nil), g...)
+	for _, c := range custom {
+		if isKnownComponent(c.Name) || extended.contains(c.Name) {
+			return nil, fmt.Errorf("component %s is already declared", c.Name)
+		}
+		for _, name := range c.Requires {
+			if !extended.contains(name) && !isKnownComponent(name) {
+				return nil, fmt.Errorf("%s requires %s, which must be declared before it", c.Name, name)
+			}
+		}
+		comp := component{Name: c.Name, Subsystem: c.Subsystem, Requires: c.Requires,
+			Stateful: c.Stateful, Scalable: c.Scalable, Publishes: c.Publishes}
+		if instances := c.Instances; instances > 0 {
+			comp.Instances = func() int { return instances }
+		}
+		extended = append(extended, comp)
+	}
+	if _, err := extended.layers(); err != nil {
+		return nil, err
+	}
+	return extended, nil
+}
+
+// contains reports whether a component is part of the graph.
+func (g graph) contains(name string) bool {
+	for _, c := range g {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/deis/deis/deisctl/units"
+)
+
+func TestGraphsAreOrdered(t *testing.T) {
+	t.Parallel()
+
+	for _, g := range []graph{platformGraph, platform(true), mesosGraph, swarmGraph, k8sGraph} {
+		layers, err := g.layers()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		started := make(map[string]bool)
+		for _, layer := range layers {
+			for _, c := range layer {
+				for _, name := range c.Requires {
+					if g.contains(name) && !started[name] {
+						t.Error(fmt.Errorf("Expected %s to start before %s", name, c.Name))
+					}
+				}
+			}
+			for _, c := range layer {
+				started[c.Name] = true
+			}
+		}
+	}
+}
+
+func TestGraphLayers(t *testing.T) {
+	t.Parallel()
+
+	layers, err := k8sGraph.layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{{"kube-apiserver"}, {"kube-controller-manager", "kube-scheduler"},
+		{"kube-kubelet"}, {"kube-proxy"}}
+	var got [][]string
+	for _, layer := range layers {
+		got = append(got, unitNames(layer))
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, got))
+	}
+}
+
+func TestGraphCycle(t *testing.T) {
+	t.Parallel()
+
+	g := graph{
+		{Name: "controller", Requires: []string{"builder"}},
+		{Name: "builder", Requires: []string{"controller"}},
+	}
+
+	expected := "dependency cycle detected at controller"
+	if _, err := g.layers(); err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected '%v', Got '%v'", expected, err))
+	}
+}
+
+func TestGraphUnknownComponent(t *testing.T) {
+	t.Parallel()
+
+	g := graph{{Name: "controller", Requires: []string{"databse"}}}
+
+	expected := "controller requires unknown component databse"
+	if _, err := g.layers(); err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected '%v', Got '%v'", expected, err))
+	}
+}
+
+func TestGraphWithCustomComponents(t *testing.T) {
+	t.Parallel()
+
+	g, err := platformGraph.with([]units.Component{
+		{Name: "metrics", Subsystem: "Monitoring", Scalable: true, Instances: 2, Requires: []string{"logspout"}},
+		{Name: "metrics-alerts", Subsystem: "Monitoring", Requires: []string{"metrics", "controller"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g) != len(platformGraph)+2 {
+		t.Error(fmt.Errorf("Expected %d components, Got %d", len(platformGraph)+2, len(g)))
+	}
+
+	layers, err := g.layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the custom components start once the components they require are up
+	depth := make(map[string]int)
+	for i, layer := range layers {
+		for _, c := range layer {
+			depth[c.Name] = i
+		}
+	}
+	if depth["metrics"] <= depth["logspout"] || depth["metrics-alerts"] <= depth["controller"] {
+		t.Error(fmt.Errorf("Expected the custom components to start after their requirements, Got %v", depth))
+	}
+	expected := []string{"metrics@1", "metrics@2", "metrics-alerts"}
+	if names := instanceUnitNames(g[len(g)-2:]); !reflect.DeepEqual(names, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, names))
+	}
+
+	for _, test := range []struct {
+		custom   []units.Component
+		expected string
+	}{
+		{[]units.Component{{Name: "zookeeper"}}, "component zookeeper is already declared"},
+		{[]units.Component{{Name: "alerts", Requires: []string{"metrics"}}, {Name: "metrics"}},
+			"alerts requires metrics, which must be declared before it"},
+	} {
+		if _, err := platformGraph.with(test.custom); err == nil || err.Error() != test.expected {
+			t.Error(fmt.Errorf("Expected '%v', Got '%v'", test.expected, err))
+		}
+	}
+}
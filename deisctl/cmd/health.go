@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/config"
+)
+
+// unitNameRegexp extracts the component from a unit name such as deis-router@1.service.
+var unitNameRegexp = regexp.MustCompile(`^deis-([a-z-]+?)(?:@\d+)?(?:\.service)?$`)
+
+// Health prints the state of every installed component along with the keys it publishes
+// in etcd, and returns an error if any unit is not running or any key is missing or stale.
+func Health(b backend.Backend, cb config.Backend) error {
+	states, err := b.UnitStates()
+	if err != nil {
+		return err
+	}
+	if states, err = b.ActiveSince(states); err != nil {
+		return err
+	}
+
+	// group the units by component, in the order components are declared
+	byComponent := make(map[string][]backend.UnitState)
+	var names []string
+	for _, s := range states {
+		match := unitNameRegexp.FindStringSubmatch(s.Name)
+		if match == nil {
+			continue
+		}
+		if _, ok := byComponent[match[1]]; !ok {
+			names = append(names, match[1])
+		}
+		byComponent[match[1]] = append(byComponent[match[1]], s)
+	}
+	sort.Sort(byDeclaration(names))
+
+	out := new(tabwriter.Writer)
+	out.Init(Stdout, 0, 8, 1, '\t', 0)
+
+	degraded := make(map[string]bool)
+	fmt.Fprintln(out, "COMPONENT\tUNIT\tSTATE\tHOST\tUPTIME")
+	for _, name := range names {
+		for _, s := range byComponent[name] {
+			if s.Active != "active" {
+				degraded[name] = true
+			}
+			fmt.Fprintf(out, "%s\t%s\t%s/%s\t%s\t%s\n", name, s.Name, s.Active, s.Sub,
+				orDash(s.Host), uptime(s.Since))
+		}
+	}
+	out.Flush()
+
+	fmt.Fprintln(Stdout)
+	fmt.Fprintln(out, "COMPONENT\tKEY\tSTATUS")
+	for _, name := range names {
+		c, _ := lookupComponent(name)
+		for _, key := range c.Publishes {
+			status, ok := keyHealth(cb, key)
+			if !ok {
+				degraded[name] = true
+			}
+			fmt.Fprintf(out, "%s\t%s\t%s\n", name, key, status)
+		}
+	}
+	out.Flush()
+
+	if len(degraded) > 0 {
+		var components []string
+		for _, name := range names {
+			if degraded[name] {
+				components = append(components, name)
+			}
+		}
+		return fmt.Errorf("platform is degraded: %s", strings.Join(components, ", "))
+	}
+	fmt.Fprintln(Stdout, "\nAll components are healthy.")
+	return nil
+}
+
+// keyHealth reports whether a published key is present and fresh. Components publish their
+// keys with a TTL and refresh them while they run, so a key without one is not being kept
+// up to date. Directory keys must hold at least one fresh entry.
+func keyHealth(cb config.Backend, key string) (string, bool) {
+	nodes, err := cb.GetRecursive(strings.TrimSuffix(key, "/*"))
+	if err != nil || len(nodes) == 0 {
+		return "missing", false
+	}
+	for _, node := range nodes {
+		if node.TTL <= 0 {
+			return fmt.Sprintf("stale (%s has no TTL)", node.Key), false
+		}
+		if node.Expiration != nil && node.Expiration.Before(time.Now()) {
+			return fmt.Sprintf("stale (%s expired)", node.Key), false
+		}
+	}
+	if strings.HasSuffix(key, "/*") {
+		return fmt.Sprintf("ok (%d)", len(nodes)), true
+	}
+	return "ok", true
+}
+
+func uptime(since time.Time) string {
+	if since.IsZero() {
+		return "-"
+	}
+	d := time.Since(since)
+	return (d - d%time.Second).String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// byDeclaration sorts component names in the order they are declared in the known graphs,
+// followed by unknown components in alphabetical order.
+type byDeclaration []string
+
+func (s byDeclaration) Len() int      { return len(s) }
+func (s byDeclaration) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byDeclaration) Less(i, j int) bool {
+	a, b := declarationIndex(s[i]), declarationIndex(s[j])
+	if a != b {
+		return a < b
+	}
+	return s[i] < s[j]
+}
+
+func declarationIndex(name string) int {
+	i := 0
+	for _, g := range knownGraphs() {
+		for _, c := range g {
+			if c.Name == name {
+				return i
+			}
+			i++
+		}
+	}
+	return i
+}
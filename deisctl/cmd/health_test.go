@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deis/deis/deisctl/backend"
+	"github.com/deis/deis/deisctl/config/model"
+	"github.com/deis/deis/deisctl/test/mock"
+)
+
+// publishedKeys is a config backend whose GetRecursive returns the nodes under a key.
+type publishedKeys struct {
+	mock.ConfigBackend
+}
+
+func (p publishedKeys) GetRecursive(key string) ([]*model.ConfigNode, error) {
+	var nodes []*model.ConfigNode
+	for _, node := range p.Expected {
+		if node.Key == key || strings.HasPrefix(node.Key, key+"/") {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("Key not found")
+	}
+	return nodes, nil
+}
+
+// runHealth runs Health, capturing what it prints. Tests calling it must not run in
+// parallel, since they replace Stdout.
+func runHealth(b backend.Backend, keys []*model.ConfigNode) (string, error) {
+	var out bytes.Buffer
+	stdout := Stdout
+	Stdout = &out
+	defer func() { Stdout = stdout }()
+
+	err := Health(b, publishedKeys{mock.ConfigBackend{Expected: keys}})
+	return out.String(), err
+}
+
+func TestHealth(t *testing.T) {
+	b := backendStub{unitStates: []backend.UnitState{
+		{Name: "deis-router@1.service", Active: "active", Sub: "running", Host: "10.0.0.1"},
+		{Name: "deis-logger.service", Active: "active", Sub: "running", Host: "10.0.0.2"},
+		{Name: "deis-router@2.service", Active: "active", Sub: "running", Host: "10.0.0.3"},
+	}}
+	keys := []*model.ConfigNode{
+		{Key: "/deis/logs/host", Value: "10.0.0.2", TTL: 20},
+		{Key: "/deis/logs/port", Value: "514", TTL: 20},
+		{Key: "/deis/router/hosts/10.0.0.1", Value: "10.0.0.1:80", TTL: 20},
+		{Key: "/deis/router/hosts/10.0.0.3", Value: "10.0.0.3:80", TTL: 20},
+	}
+
+	out, err := runHealth(&b, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `COMPONENT UNIT                  STATE          HOST     UPTIME
+logger    deis-logger.service   active/running 10.0.0.2 -
+router    deis-router@1.service active/running 10.0.0.1 -
+router    deis-router@2.service active/running 10.0.0.3 -
+
+COMPONENT KEY                  STATUS
+logger    /deis/logs/host      ok
+logger    /deis/logs/port      ok
+router    /deis/router/hosts/* ok (2)
+
+All components are healthy.
+`
+	// the columns are padded with tabs, so only the words are compared
+	if squeeze(out) != squeeze(expected) {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, out))
+	}
+}
+
+func TestHealthDegraded(t *testing.T) {
+	b := backendStub{unitStates: []backend.UnitState{
+		{Name: "deis-builder.service", Active: "failed", Sub: "failed"},
+		{Name: "deis-logger.service", Active: "active", Sub: "running"},
+		{Name: "deis-controller.service", Active: "active", Sub: "running"},
+		{Name: "deis-publisher.service", Active: "active", Sub: "running"},
+	}}
+	keys := []*model.ConfigNode{
+		// set by hand rather than published by a running logger
+		{Key: "/deis/logs/host", Value: "10.0.0.2"},
+		{Key: "/deis/logs/port", Value: "514", TTL: 20},
+		{Key: "/deis/controller/host", Value: "10.0.0.1", TTL: 20},
+	}
+
+	out, err := runHealth(&b, keys)
+	expectedErr := "platform is degraded: logger, controller, builder"
+	if err == nil || err.Error() != expectedErr {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expectedErr, err))
+	}
+
+	for _, line := range []string{
+		"/deis/logs/host stale (/deis/logs/host has no TTL)",
+		"/deis/controller/port missing",
+		"/deis/builder/host missing",
+	} {
+		if !strings.Contains(squeeze(out), line) {
+			t.Error(fmt.Errorf("Expected output to contain %q, Got %q", line, out))
+		}
+	}
+}
+
+func squeeze(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
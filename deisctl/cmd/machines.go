@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/deis/deis/deisctl/backend"
+)
+
+// Machines prints the machines of the cluster with their metadata and the units running
+// on each, so that an uneven placement stands out.
+func Machines(b backend.Backend) error {
+	return printMachines(b, Stdout)
+}
+
+func printMachines(b backend.Backend, w io.Writer) error {
+	machines, err := b.Machines()
+	if err != nil {
+		return err
+	}
+	states, err := b.UnitStates()
+	if err != nil {
+		return err
+	}
+
+	onHost := make(map[string][]string)
+	var unscheduled []string
+	for _, s := range states {
+		name := strings.TrimSuffix(strings.TrimPrefix(s.Name, "deis-"), ".service")
+		if s.Host == "" {
+			unscheduled = append(unscheduled, name)
+			continue
+		}
+		onHost[s.Host] = append(onHost[s.Host], name)
+	}
+
+	out := new(tabwriter.Writer)
+	out.Init(w, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(out, "MACHINE\tIP\tMETADATA\tUNITS")
+	for _, m := range machines {
+		units := onHost[m.Host]
+		sort.Strings(units)
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", shortID(m.ID), m.Host, orDash(formatMetadata(m.Metadata)),
+			orDash(strings.Join(units, " ")))
+	}
+	out.Flush()
+
+	if len(unscheduled) > 0 {
+		sort.Strings(unscheduled)
+		fmt.Fprintf(w, "\nNot scheduled on any machine: %s\n", strings.Join(unscheduled, " "))
+	}
+	return nil
+}
+
+// shortID shortens a machine ID as fleetctl list-machines does.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8] + "..."
+	}
+	return id
+}
+
+func formatMetadata(metadata map[string]string) string {
+	var pairs []string
+	for k, v := range metadata {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Rebalance moves the instances of a component so that they are spread evenly across the
+// machines they may run on.
+func Rebalance(component string, b backend.Backend) error {
+	return b.Rebalance(component, Stdout)
+}
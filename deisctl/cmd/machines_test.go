@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/deis/deis/deisctl/backend"
+)
+
+func TestMachines(t *testing.T) {
+	t.Parallel()
+
+	b := backendStub{unitStates: []backend.UnitState{
+		{Name: "deis-router@2.service", Host: "10.0.0.2"},
+		{Name: "deis-router@1.service", Host: "10.0.0.2"},
+		{Name: "deis-controller.service", Host: "10.0.0.1"},
+		{Name: "deis-router@3.service"},
+	}}
+	var out bytes.Buffer
+	if err := printMachines(&b, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `MACHINE IP       METADATA       UNITS
+a       10.0.0.1 role=control   controller
+b       10.0.0.2 role=dataPlane router@1 router@2
+c       10.0.0.3 role=dataPlane -
+
+Not scheduled on any machine: router@3
+`
+	// the columns are padded with tabs, so only the words are compared
+	if squeeze(out.String()) != squeeze(expected) {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, out.String()))
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	t.Parallel()
+
+	b := backendStub{}
+	expected := []string{"router"}
+
+	Rebalance("router", &b)
+
+	if !reflect.DeepEqual(b.rebalancedUnits, expected) {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, b.rebalancedUnits))
+	}
+}
@@ -0,0 +1,551 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/deis/deis/deisctl/units"
+	"github.com/deis/deis/deisctl/utils"
+	"github.com/deis/deis/deisctl/utils/net"
+)
+
+// checksumsFile is the checksum manifest of a unit source, in the format written by
+// sha256sum and with paths relative to the unit files, as in decorators/<name>.
+const checksumsFile = "SHA256SUMS"
+
+// RefreshOptions choose where unit files are refreshed from and how they are verified.
+type RefreshOptions struct {
+	// Tag is the git tag, branch or SHA whose unit files are downloaded from RootURL,
+	// unless Source is set.
+	Tag     string
+	RootURL string
+	// Source is a directory, a tar archive or the URL of a mirror holding unit files laid
+	// out as in deisctl/units.
+	Source string
+	// Checksums is the path or URL of a checksum manifest. It defaults to the SHA256SUMS
+	// file of Source, which must exist unless NoVerify is set.
+	Checksums string
+	// Signature is the path or URL of a detached signature of the checksum manifest,
+	// verified by gpg with Keyring, or with the default keyring if Keyring is empty.
+	Signature string
+	Keyring   string
+	NoVerify  bool
+}
+
+// errNotFound is returned by unit sources for the files they do not hold.
+var errNotFound = errors.New("404 Not Found")
+
+// unitSource reads unit files by their path relative to deisctl/units.
+type unitSource interface {
+	read(name string) ([]byte, error)
+	String() string
+}
+
+// urlSource reads unit files below a URL, such as a mirror or GitHub.
+type urlSource string
+
+func (s urlSource) read(name string) ([]byte, error) {
+	data, err := net.Get(string(s) + name)
+	if e, ok := err.(*net.StatusError); ok && e.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	return data, err
+}
+
+func (s urlSource) String() string {
+	return string(s)
+}
+
+// dirSource reads unit files from a local directory.
+type dirSource string
+
+func (s dirSource) read(name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(string(s), filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, errNotFound
+	}
+	return data, err
+}
+
+func (s dirSource) String() string {
+	return string(s)
+}
+
+// archiveSource reads unit files from a tar archive, which may be gzipped and may nest
+// them in directories, as in a copy of the repository.
+type archiveSource struct {
+	path  string
+	files map[string][]byte
+}
+
+func openArchive(filename string) (*archiveSource, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if magic, err := r.(*bufio.Reader).Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		if r, err = gzip.NewReader(r); err != nil {
+			return nil, err
+		}
+	}
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %v", filename, err)
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+
+	// the unit files are in the shallowest directory holding one of them
+	root, found := "", false
+	for name := range files {
+		if path.Base(name) == units.Names[0]+".service" {
+			if dir := path.Dir(name); !found || len(dir) < len(root) {
+				root, found = dir, true
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s holds no unit files", filename)
+	}
+
+	s := &archiveSource{path: filename, files: make(map[string][]byte)}
+	for name, data := range files {
+		if rel := strings.TrimPrefix(name, root+"/"); root == "." || rel != name {
+			s.files[rel] = data
+		}
+	}
+	return s, nil
+}
+
+func (s *archiveSource) read(name string) ([]byte, error) {
+	data, ok := s.files[name]
+	if !ok {
+		return nil, errNotFound
+	}
+	return data, nil
+}
+
+func (s *archiveSource) String() string {
+	return s.path
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// newUnitSource returns the source of unit files chosen by opts.
+func newUnitSource(opts RefreshOptions) (unitSource, error) {
+	if opts.Source == "" {
+		return urlSource(opts.RootURL + opts.Tag + "/deisctl/units/"), nil
+	}
+	if isURL(opts.Source) {
+		return urlSource(strings.TrimSuffix(opts.Source, "/") + "/"), nil
+	}
+
+	source := utils.ResolvePath(opts.Source)
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return dirSource(source), nil
+	}
+	return openArchive(source)
+}
+
+// readLocation returns the contents of a local file or of a URL.
+func readLocation(location string) ([]byte, error) {
+	if isURL(location) {
+		return net.Get(location)
+	}
+	return ioutil.ReadFile(utils.ResolvePath(location))
+}
+
+// readChecksums returns the checksums which the refreshed files must match, keyed by
+// file, or nil if they are not verified. Files downloaded from GitHub are only verified
+// against an explicit manifest.
+func readChecksums(opts RefreshOptions, src unitSource) (map[string]string, error) {
+	if opts.NoVerify {
+		return nil, nil
+	}
+
+	var manifest []byte
+	var err error
+	switch {
+	case opts.Checksums != "":
+		manifest, err = readLocation(opts.Checksums)
+	case opts.Source != "":
+		manifest, err = src.read(checksumsFile)
+		if err == errNotFound {
+			return nil, fmt.Errorf("%s has no %s checksum manifest, use --checksums to provide one or --no-verify to skip verification",
+				src, checksumsFile)
+		}
+	case opts.Signature != "":
+		return nil, fmt.Errorf("a signature can only be verified with a checksum manifest, use --checksums to provide one")
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read the checksum manifest: %v", err)
+	}
+
+	if opts.Signature != "" {
+		if err := verifySignature(manifest, opts.Signature, opts.Keyring); err != nil {
+			return nil, err
+		}
+	}
+
+	sums := make(map[string]string)
+	for _, line := range strings.Split(string(manifest), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("could not parse the checksum manifest: %q", line)
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(fields[1], "*"), "./")
+		sums[name] = strings.ToLower(fields[0])
+	}
+	return sums, nil
+}
+
+// verifySignature checks a detached signature of the checksum manifest with gpg.
+func verifySignature(manifest []byte, signature, keyring string) error {
+	sig, err := readLocation(signature)
+	if err != nil {
+		return fmt.Errorf("could not read the signature: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "deisctl-verify")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	manifestFile, sigFile := filepath.Join(dir, checksumsFile), filepath.Join(dir, checksumsFile+".sig")
+	if err := ioutil.WriteFile(manifestFile, manifest, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(sigFile, sig, 0644); err != nil {
+		return err
+	}
+
+	args := []string{"--batch"}
+	if keyring != "" {
+		args = append(args, "--no-default-keyring", "--keyring", utils.ResolvePath(keyring))
+	}
+	args = append(args, "--verify", sigFile, manifestFile)
+	if out, err := exec.Command("gpg", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("the signature of the checksum manifest is not valid: %v\n%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// verify checks a file against the checksum manifest, if there is one.
+func verify(sums map[string]string, name string, data []byte) error {
+	if sums == nil {
+		return nil
+	}
+	expected, ok := sums[name]
+	if !ok {
+		return fmt.Errorf("%s is not listed in the checksum manifest", name)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+	}
+	return nil
+}
+
+// refreshUnits reads every unit file and decorator into a complete unit directory staged
+// next to unitDir and verifies them before switching unitDir over to it, so that a failed
+// refresh leaves the unit files as they were rather than mixing two releases, and files
+// the source no longer holds, such as a dropped decorator, do not outlive the refresh.
+// Files which belong to no component are carried over.
+func refreshUnits(unitDir string, opts RefreshOptions, out io.Writer) error {
+	src, err := newUnitSource(opts)
+	if err != nil {
+		return err
+	}
+	sums, err := readChecksums(opts, src)
+	if err != nil {
+		return err
+	}
+	from := opts.Tag
+	if opts.Source != "" {
+		from = opts.Source
+	}
+
+	unitDir = filepath.Clean(utils.ResolvePath(unitDir))
+	// create the parent dir if necessary
+	if err := os.MkdirAll(filepath.Dir(unitDir), 0755); err != nil {
+		return err
+	}
+	// stage next to the unit directory, so that it is switched to rather than copied
+	staging, err := ioutil.TempDir(filepath.Dir(unitDir), "."+filepath.Base(unitDir)+"-")
+	if err != nil {
+		return err
+	}
+	swapped := false
+	defer func() {
+		if !swapped {
+			os.RemoveAll(staging)
+		}
+	}()
+	if err := os.Chmod(staging, 0755); err != nil {
+		return err
+	}
+	if err := os.Mkdir(filepath.Join(staging, "decorators"), 0755); err != nil {
+		return err
+	}
+
+	copyFile := func(from unitSource, sums map[string]string, name string) error {
+		data, err := from.read(name)
+		if err != nil {
+			return err
+		}
+		if err := verify(sums, name, data); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(staging, filepath.FromSlash(name)), data, 0644)
+	}
+	var staged, messages []string
+	stage := func(name string) error {
+		if err := copyFile(src, sums, name); err != nil {
+			return err
+		}
+		staged = append(staged, name)
+		return nil
+	}
+
+	for _, unit := range units.Names {
+		// scalable components have a template unit instead
+		err := stage(unit + ".service")
+		if err == errNotFound {
+			err = stage(unit + "@.service")
+		}
+		if err == errNotFound && opts.Source != "" {
+			return fmt.Errorf("%s.service not found in %s", unit, src)
+		} else if err != nil {
+			return err
+		}
+		messages = append(messages, fmt.Sprintf("Refreshed %s unit from %s", unit, from))
+
+		decorator := "decorators/" + unit + ".service.decorator"
+		err = stage(decorator)
+		switch {
+		case err == errNotFound:
+			if _, listed := sums[decorator]; listed {
+				return fmt.Errorf("%s is listed in the checksum manifest but missing from %s", decorator, src)
+			}
+			messages = append(messages, fmt.Sprintf("Decorator for %s not found in %s", unit, from))
+		case err != nil:
+			return err
+		default:
+			messages = append(messages, fmt.Sprintf("Refreshed %s decorator from %s", unit, from))
+		}
+	}
+
+	// custom components are refreshed along with their manifest from sources which hold
+	// one, and are otherwise carried over as they are
+	refreshed := false
+	data, err := []byte(nil), errNotFound
+	if opts.Source != "" {
+		data, err = src.read(units.ManifestFile)
+		refreshed = err == nil
+	}
+	current := dirSource(unitDir)
+	// files which belong to a component are left out when the source no longer holds them;
+	// any other file in the unit directory, such as one the user added, is carried over
+	owned := map[string]bool{units.ManifestFile: true}
+	own := func(unit string) {
+		owned[unit+".service"] = true
+		owned[unit+"@.service"] = true
+		owned["decorators/"+unit+".service.decorator"] = true
+	}
+	for _, unit := range units.Names {
+		own(unit)
+	}
+	if refreshed {
+		// components the current manifest declares are dropped with it
+		previous, err := current.read(units.ManifestFile)
+		if err != nil && err != errNotFound {
+			return err
+		}
+		if err == nil {
+			custom, err := units.ParseManifest(previous)
+			if err != nil {
+				return err
+			}
+			for _, c := range custom {
+				own("deis-" + c.Name)
+			}
+		}
+	}
+	if err == errNotFound {
+		data, err = current.read(units.ManifestFile)
+	}
+	if err != nil && err != errNotFound {
+		return err
+	}
+	if err == nil {
+		if refreshed {
+			if err := verify(sums, units.ManifestFile, data); err != nil {
+				return err
+			}
+			staged = append(staged, units.ManifestFile)
+		}
+		custom, err := units.ParseManifest(data)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(staging, units.ManifestFile), data, 0644); err != nil {
+			return err
+		}
+		copyTemplate := func(name string) error {
+			if refreshed {
+				return stage(name)
+			}
+			return copyFile(current, nil, name)
+		}
+		for _, c := range custom {
+			own("deis-" + c.Name)
+			err := copyTemplate("deis-" + c.Name + ".service")
+			if err == errNotFound {
+				err = copyTemplate("deis-" + c.Name + "@.service")
+			}
+			switch {
+			case err == errNotFound && refreshed:
+				return fmt.Errorf("%s declares %s, but %s has no unit template for it", units.ManifestFile, c.Name, src)
+			case err != nil && err != errNotFound:
+				return err
+			case refreshed:
+				messages = append(messages, fmt.Sprintf("Refreshed custom component %s from %s", c.Name, from))
+			}
+		}
+	}
+
+	kept, err := carryOver(unitDir, staging, owned)
+	if err != nil {
+		return err
+	}
+	for _, name := range kept {
+		messages = append(messages, fmt.Sprintf("Kept %s, which belongs to no component", name))
+	}
+
+	if err := switchUnitDir(unitDir, staging); err != nil {
+		return err
+	}
+	swapped = true
+	for _, msg := range messages {
+		fmt.Fprintln(out, msg)
+	}
+	if sums != nil {
+		fmt.Fprintf(out, "Verified %d files against the checksum manifest\n", len(staged))
+	}
+	return nil
+}
+
+// carryOver copies the files of the current unit directory and its decorators which are
+// not owned by a component into the staged unit directory, and returns their names.
+func carryOver(unitDir, staging string, owned map[string]bool) ([]string, error) {
+	var kept []string
+	for _, dir := range []string{"", "decorators"} {
+		files, err := ioutil.ReadDir(filepath.Join(unitDir, dir))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			name := path.Join(dir, f.Name())
+			if !f.Mode().IsRegular() || owned[name] {
+				continue
+			}
+			data, err := ioutil.ReadFile(filepath.Join(unitDir, filepath.FromSlash(name)))
+			if err != nil {
+				return nil, err
+			}
+			if err := ioutil.WriteFile(filepath.Join(staging, filepath.FromSlash(name)), data, f.Mode().Perm()); err != nil {
+				return nil, err
+			}
+			kept = append(kept, name)
+		}
+	}
+	return kept, nil
+}
+
+// switchUnitDir points unitDir at a staged unit directory with a single rename of a
+// symlink, and removes the directory of the previous refresh. A unitDir which is still a
+// plain directory, as before its first refresh, is moved aside first.
+func switchUnitDir(unitDir, staging string) error {
+	var previous string
+	target, err := os.Readlink(unitDir)
+	switch {
+	case err == nil:
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(unitDir), target)
+		}
+		// only directories staged by a refresh are removed, not those the user links to
+		if filepath.Dir(target) == filepath.Dir(unitDir) &&
+			strings.HasPrefix(filepath.Base(target), "."+filepath.Base(unitDir)+"-") {
+			previous = target
+		}
+	case os.IsNotExist(err):
+	default:
+		if info, statErr := os.Lstat(unitDir); statErr != nil || !info.IsDir() {
+			return err
+		}
+		previous = staging + ".old"
+		if err := os.Rename(unitDir, previous); err != nil {
+			return err
+		}
+	}
+
+	link := staging + ".link"
+	err = os.Symlink(filepath.Base(staging), link)
+	if err == nil {
+		err = os.Rename(link, unitDir)
+	}
+	if err != nil {
+		os.Remove(link)
+		if previous == staging+".old" {
+			os.Rename(previous, unitDir)
+		}
+		return err
+	}
+	if previous != "" {
+		os.RemoveAll(previous)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deis/deis/deisctl/units"
+)
+
+// unitFiles returns a unit file for every component, with the decorator of the first,
+// keyed by their path relative to deisctl/units.
+func unitFiles() map[string]string {
+	files := make(map[string]string)
+	for _, unit := range units.Names {
+		files[unit+".service"] = "[Unit]\nDescription=" + unit + "\n"
+	}
+	files["decorators/"+units.Names[0]+".service.decorator"] = "[X-Fleet]\n"
+	return files
+}
+
+func checksums(files map[string]string) string {
+	var b bytes.Buffer
+	for name, data := range files {
+		sum := sha256.Sum256([]byte(data))
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+	return b.String()
+}
+
+func writeSource(t *testing.T, files map[string]string, manifest string) string {
+	dir, err := ioutil.TempDir("", "deisctl-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Mkdir(filepath.Join(dir, "decorators"), 0755)
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if manifest != "" {
+		ioutil.WriteFile(filepath.Join(dir, checksumsFile), []byte(manifest), 0644)
+	}
+	return dir
+}
+
+// tempUnitDir returns a unit directory which does not exist yet, in a temporary directory
+// which also holds the directories staged by refreshes.
+func tempUnitDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "deisctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "units"), func() { os.RemoveAll(dir) }
+}
+
+func TestRefreshUnitsFromDir(t *testing.T) {
+	t.Parallel()
+
+	files := unitFiles()
+	source := writeSource(t, files, checksums(files))
+	defer os.RemoveAll(source)
+	name, cleanup := tempUnitDir(t)
+	defer cleanup()
+
+	var b bytes.Buffer
+	if err := refreshUnits(name, RefreshOptions{Source: source}, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range files {
+		data, err := ioutil.ReadFile(filepath.Join(name, filepath.FromSlash(file)))
+		if err != nil || string(data) != expected {
+			t.Error(fmt.Errorf("Expected %q in %s, Got %q (%v)", expected, file, data, err))
+		}
+	}
+	expected := fmt.Sprintf("Verified %d files against the checksum manifest", len(files))
+	if !strings.Contains(b.String(), expected) {
+		t.Error(fmt.Errorf("Expected %s, Got %s", expected, b.String()))
+	}
+}
+
+func TestRefreshUnitsChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	files := unitFiles()
+	manifest := checksums(files)
+	last := units.Names[len(units.Names)-1] + ".service"
+	files[last] = "[Unit]\nDescription=tampered\n"
+	source := writeSource(t, files, manifest)
+	defer os.RemoveAll(source)
+	name, cleanup := tempUnitDir(t)
+	defer cleanup()
+	os.Mkdir(name, 0755)
+	original := filepath.Join(name, units.Names[0]+".service")
+	ioutil.WriteFile(original, []byte("original"), 0644)
+
+	err := refreshUnits(name, RefreshOptions{Source: source}, ioutil.Discard)
+	expected := "checksum mismatch for " + last
+	if err == nil || !strings.HasPrefix(err.Error(), expected) {
+		t.Error(fmt.Errorf("Expected %s, Got %v", expected, err))
+	}
+
+	// a failed refresh leaves the unit files as they were
+	if data, _ := ioutil.ReadFile(original); string(data) != "original" {
+		t.Error(fmt.Errorf("Expected original, Got %s", data))
+	}
+	if entries, _ := ioutil.ReadDir(name); len(entries) != 1 {
+		t.Error(fmt.Errorf("Expected only the original unit, Got %d files", len(entries)))
+	}
+	if entries, _ := ioutil.ReadDir(filepath.Dir(name)); len(entries) != 1 {
+		t.Error(fmt.Errorf("Expected the staged files to be removed, Got %d files", len(entries)))
+	}
+}
+
+func TestRefreshUnitsNoManifest(t *testing.T) {
+	t.Parallel()
+
+	source := writeSource(t, unitFiles(), "")
+	defer os.RemoveAll(source)
+	name, cleanup := tempUnitDir(t)
+	defer cleanup()
+
+	err := refreshUnits(name, RefreshOptions{Source: source}, ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), "has no SHA256SUMS checksum manifest") {
+		t.Error(fmt.Errorf("Expected a missing manifest error, Got %v", err))
+	}
+
+	var b bytes.Buffer
+	if err := refreshUnits(name, RefreshOptions{Source: source, NoVerify: true}, &b); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "Verified") {
+		t.Error(fmt.Errorf("Expected no verification, Got %s", b.String()))
+	}
+}
+
+func TestRefreshUnitsFromArchive(t *testing.T) {
+	t.Parallel()
+
+	files := unitFiles()
+	files[checksumsFile] = checksums(files)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for file, data := range files {
+		// nested as in a copy of the repository
+		tw.WriteHeader(&tar.Header{Name: "deis-1.13.0/deisctl/units/" + file, Mode: 0644, Size: int64(len(data))})
+		tw.Write([]byte(data))
+	}
+	tw.Close()
+	gz.Close()
+
+	dir, err := ioutil.TempDir("", "deisctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "units.tar.gz")
+	ioutil.WriteFile(archive, buf.Bytes(), 0644)
+	name := filepath.Join(dir, "units")
+
+	if err := refreshUnits(name, RefreshOptions{Source: archive}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	decorator := filepath.Join(name, "decorators", units.Names[0]+".service.decorator")
+	if data, err := ioutil.ReadFile(decorator); err != nil || string(data) != "[X-Fleet]\n" {
+		t.Error(fmt.Errorf("Expected the decorator, Got %q (%v)", data, err))
+	}
+}
+
+func TestRefreshUnitsCustomComponents(t *testing.T) {
+	t.Parallel()
+
+	files := unitFiles()
+	files[units.ManifestFile] = "components:\n- name: metrics\n  scalable: true\n"
+	files["deis-metrics@.service"] = "[Unit]\nDescription=deis-metrics %i\n"
+	source := writeSource(t, files, checksums(files))
+	defer os.RemoveAll(source)
+	name, cleanup := tempUnitDir(t)
+	defer cleanup()
+
+	var b bytes.Buffer
+	if err := refreshUnits(name, RefreshOptions{Source: source}, &b); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{units.ManifestFile, "deis-metrics@.service"} {
+		if data, err := ioutil.ReadFile(filepath.Join(name, file)); err != nil || string(data) != files[file] {
+			t.Error(fmt.Errorf("Expected %q in %s, Got %q (%v)", files[file], file, data, err))
+		}
+	}
+	if !strings.Contains(b.String(), "Refreshed custom component metrics from "+source) {
+		t.Error(fmt.Errorf("Expected the custom component to be refreshed, Got %s", b.String()))
+	}
+
+	// a manifest declaring a component without a template is refused
+	delete(files, "deis-metrics@.service")
+	os.Remove(filepath.Join(source, "deis-metrics@.service"))
+	err := refreshUnits(name, RefreshOptions{Source: source, NoVerify: true}, ioutil.Discard)
+	expected := fmt.Sprintf("components.yml declares metrics, but %s has no unit template for it", source)
+	if err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, err))
+	}
+}
+
+func TestRefreshUnitsReplacesDirectory(t *testing.T) {
+	t.Parallel()
+
+	files := unitFiles()
+	source := writeSource(t, files, "")
+	defer os.RemoveAll(source)
+	name, cleanup := tempUnitDir(t)
+	defer cleanup()
+
+	// unit files from before the first refresh, with custom components
+	os.MkdirAll(filepath.Join(name, "decorators"), 0755)
+	ioutil.WriteFile(filepath.Join(name, units.ManifestFile), []byte("components:\n- name: metrics\n"), 0644)
+	ioutil.WriteFile(filepath.Join(name, "deis-metrics.service"), []byte("[Unit]\n"), 0644)
+	// and files which belong to no component
+	ioutil.WriteFile(filepath.Join(name, "deis-mine.service"), []byte("[Unit]\n"), 0644)
+	ioutil.WriteFile(filepath.Join(name, "decorators", "deis-mine.service.decorator"), []byte("[X-Fleet]\n"), 0644)
+
+	for i := 0; i < 2; i++ {
+		if err := refreshUnits(name, RefreshOptions{Source: source, NoVerify: true}, ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		// drop the decorator from the source for the second refresh
+		os.RemoveAll(filepath.Join(source, "decorators"))
+		os.Mkdir(filepath.Join(source, "decorators"), 0755)
+	}
+
+	// the decorator the source no longer holds is gone
+	decorator := filepath.Join(name, "decorators", units.Names[0]+".service.decorator")
+	if _, err := os.Stat(decorator); !os.IsNotExist(err) {
+		t.Error(fmt.Errorf("Expected %s to be removed, Got %v", decorator, err))
+	}
+	// custom components the source does not declare are carried over
+	for _, file := range []string{units.ManifestFile, "deis-metrics.service"} {
+		if _, err := os.Stat(filepath.Join(name, file)); err != nil {
+			t.Error(err)
+		}
+	}
+	// as are files which belong to no component
+	for _, file := range []string{"deis-mine.service", "decorators/deis-mine.service.decorator"} {
+		if _, err := os.Stat(filepath.Join(name, file)); err != nil {
+			t.Error(err)
+		}
+	}
+	// only the unit directory and the directory it links to are left
+	if entries, _ := ioutil.ReadDir(filepath.Dir(name)); len(entries) != 2 {
+		t.Error(fmt.Errorf("Expected 2 files, Got %d", len(entries)))
+	}
+}
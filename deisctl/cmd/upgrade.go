@@ -1,42 +1,59 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
-	"sync"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/deis/deis/deisctl/backend"
 	"github.com/deis/deis/deisctl/config"
 	"github.com/deis/deis/deisctl/config/model"
+	"github.com/deis/deis/deisctl/utils"
 )
 
-// UpgradePrep stops and uninstalls all components except router and publisher
-func UpgradePrep(b backend.Backend) error {
-	var wg sync.WaitGroup
+// upgradeKey is where `deisctl upgrade` records its progress, so that an interrupted
+// upgrade can be resumed.
+const upgradeKey = "/deis/upgrade"
 
-	b.Stop([]string{"database", "registry@*", "controller", "builder", "logger", "logspout"}, &wg, Stdout, Stderr)
-	wg.Wait()
-	b.Destroy([]string{"database", "registry@*", "controller", "builder", "logger", "logspout"}, &wg, Stdout, Stderr)
-	wg.Wait()
-
-	b.Stop([]string{"store-volume", "store-gateway@*"}, &wg, Stdout, Stderr)
-	wg.Wait()
-	b.Destroy([]string{"store-volume", "store-gateway@*"}, &wg, Stdout, Stderr)
-	wg.Wait()
+// upgradeStep is one resumable step of an upgrade.
+type upgradeStep struct {
+	Name        string
+	Description string
+	Run         func(u *upgrade) error
+}
 
-	b.Stop([]string{"store-metadata"}, &wg, Stdout, Stderr)
-	wg.Wait()
-	b.Destroy([]string{"store-metadata"}, &wg, Stdout, Stderr)
-	wg.Wait()
+// upgradeSteps are run in order. Once a step succeeds it is recorded in etcd, and it is
+// skipped when an interrupted upgrade is resumed.
+var upgradeSteps = []upgradeStep{
+	{"units", "Installing unit files", (*upgrade).installUnits},
+	{"prep", "Stopping the control plane", (*upgrade).prep},
+	{"version", "Pinning the platform version", (*upgrade).pinVersion},
+	{"republish", "Handing services over from the publisher", (*upgrade).republish},
+	{"routers", "Restarting the routers", (*upgrade).restartRouters},
+	{"platform", "Starting the platform", (*upgrade).startPlatform},
+}
 
-	b.Stop([]string{"store-daemon"}, &wg, Stdout, Stderr)
-	wg.Wait()
-	b.Destroy([]string{"store-daemon"}, &wg, Stdout, Stderr)
-	wg.Wait()
+// upgrade holds the state of a running `deisctl upgrade`.
+type upgrade struct {
+	b       backend.Backend
+	cb      config.Backend
+	version string
+	unitDir string
+	staging string
+	// completed is the last step of an earlier run which the upgrade resumes after
+	completed string
+}
 
-	b.Stop([]string{"store-monitor"}, &wg, Stdout, Stderr)
-	wg.Wait()
-	b.Destroy([]string{"store-monitor"}, &wg, Stdout, Stderr)
-	wg.Wait()
+// UpgradePrep stops and uninstalls all components except router and publisher
+func UpgradePrep(b backend.Backend) error {
+	if err := stopControlPlane(b, Stdout); err != nil {
+		return err
+	}
 
 	fmt.Fprintln(Stdout, "The platform has been stopped, but applications are still serving traffic as normal.")
 	fmt.Fprintln(Stdout, "Your cluster is now ready for upgrade. Install a new deisctl version and run `deisctl upgrade-takeover`.")
@@ -44,6 +61,22 @@ func UpgradePrep(b backend.Backend) error {
 	return nil
 }
 
+// stopControlPlane stops and uninstalls every component except the routers and the
+// publisher, which keep serving applications during the upgrade. It carries on past
+// failures, so that as much as possible is removed, and returns all of them.
+func stopControlPlane(b backend.Backend, out io.Writer) error {
+	g := platformGraph.without(func(c component) bool {
+		return c.Name == "router" || c.Name == "publisher"
+	})
+
+	return walk(g, true, unitNames, func(targets []string, out io.Writer) error {
+		if err := b.Stop(targets, out); err != nil {
+			return err
+		}
+		return b.Destroy(targets, out)
+	}, out)
+}
+
 func listPublishedServices(cb config.Backend) ([]*model.ConfigNode, error) {
 	nodes, err := cb.GetRecursive("deis/services")
 	if err != nil {
@@ -75,33 +108,232 @@ func UpgradeTakeover(b backend.Backend, cb config.Backend) error {
 }
 
 func doUpgradeTakeOver(b backend.Backend, cb config.Backend) error {
-	var wg sync.WaitGroup
-
 	nodes, err := listPublishedServices(cb)
 	if err != nil {
 		return err
 	}
+	if err := handOverServices(b, cb, nodes, Stdout); err != nil {
+		return err
+	}
+
+	if err := restartRouters(b, Stdout); err != nil {
+		return err
+	}
+
+	return startUpgradedPlatform(b, Stdout)
+}
+
+// handOverServices removes the publisher after republishing the services it published
+// with a TTL long enough for the routers to keep serving them during the upgrade.
+func handOverServices(b backend.Backend, cb config.Backend, nodes []*model.ConfigNode, out io.Writer) error {
+	if err := b.Stop([]string{"publisher"}, out); err != nil {
+		return err
+	}
+	if err := b.Destroy([]string{"publisher"}, out); err != nil {
+		return err
+	}
+
+	return republishServices(1800, nodes, cb)
+}
 
-	b.Stop([]string{"publisher"}, &wg, Stdout, Stderr)
-	wg.Wait()
-	b.Destroy([]string{"publisher"}, &wg, Stdout, Stderr)
-	wg.Wait()
+// restartRouters replaces the routers one at a time, so that applications keep being
+// served.
+func restartRouters(b backend.Backend, out io.Writer) error {
+	return b.RollingRestart("router", backend.RollingRestartOptions{MaxUnavailable: 1}, out)
+}
+
+// startUpgradedPlatform starts the publisher, so that the republished services are kept
+// up to date again, then installs and starts the rest of the platform.
+func startUpgradedPlatform(b backend.Backend, out io.Writer) error {
+	if err := b.Create([]string{"publisher"}, out); err != nil {
+		return err
+	}
+	if err := b.Start([]string{"publisher"}, out); err != nil {
+		return err
+	}
 
-	if err := republishServices(1800, nodes, cb); err != nil {
+	if err := installComponents(b, platformGraph, out); err != nil {
 		return err
 	}
 
-	b.RollingRestart("router", &wg, Stdout, Stderr)
-	wg.Wait()
-	b.Create([]string{"publisher"}, &wg, Stdout, Stderr)
-	wg.Wait()
-	b.Start([]string{"publisher"}, &wg, Stdout, Stderr)
-	wg.Wait()
+	return startComponents(b, platformGraph, out)
+}
 
-	installDefaultServices(b, false, &wg, Stdout, Stderr) // @fixme: hax?
-	wg.Wait()
+// Upgrade gracefully upgrades the platform to a release, downloading its unit files from
+// rootURL into unitDir. Progress is recorded in etcd, so running Upgrade again with the
+// same release resumes an upgrade which was interrupted.
+func Upgrade(to, unitDir, rootURL string, b backend.Backend, cb config.Backend, checkKeys func(config.Backend) error) error {
+	version := "v" + strings.TrimPrefix(to, "v")
 
-	startDefaultServices(b, false, &wg, Stdout, Stderr) // @fixme: hax?
-	wg.Wait()
+	// preflight checks, which leave the cluster untouched
+	fmt.Fprintln(Stdout, "Running preflight checks...")
+	if err := checkEtcdHealth(cb); err != nil {
+		return fmt.Errorf("preflight: %v", err)
+	}
+	inProgress, err := cb.GetWithDefault(upgradeKey+"/version", "")
+	if err != nil {
+		return fmt.Errorf("preflight: could not read the upgrade progress: %v", err)
+	}
+	if inProgress != "" && inProgress != version {
+		return fmt.Errorf("preflight: an upgrade to %s is in progress, resume it with --to=%s", inProgress, inProgress)
+	}
+	current, err := cb.GetWithDefault("/deis/platform/version", "")
+	if err != nil {
+		return fmt.Errorf("preflight: could not read the platform version: %v", err)
+	}
+	if inProgress == "" && current == version {
+		fmt.Fprintf(Stdout, "The platform already runs %s.\n", version)
+		return nil
+	}
+	if err := checkKeys(cb); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempDir("", "deisctl-upgrade")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	staging := filepath.Join(tmp, "units")
+	if err := refreshUnits(staging, RefreshOptions{Tag: version, RootURL: rootURL}, ioutil.Discard); err != nil {
+		return fmt.Errorf("preflight: unit files for %s are not available: %v", version, err)
+	}
+
+	// start or resume the upgrade
+	completed := ""
+	if inProgress == "" {
+		if _, err := cb.Set(upgradeKey+"/version", version); err != nil {
+			return err
+		}
+		if _, err := cb.Set(upgradeKey+"/from", current); err != nil {
+			return err
+		}
+	} else {
+		completed, _ = cb.GetWithDefault(upgradeKey+"/step", "")
+		current, _ = cb.GetWithDefault(upgradeKey+"/from", "")
+		fmt.Fprintf(Stdout, "Resuming the upgrade to %s after step %q.\n", version, orDash(completed))
+	}
+
+	u := &upgrade{
+		b:         b,
+		cb:        cb,
+		version:   version,
+		unitDir:   utils.ResolvePath(unitDir),
+		staging:   staging,
+		completed: completed,
+	}
+
+	results := make([]string, len(upgradeSteps))
+	skipping := completed != ""
+	for i, step := range upgradeSteps {
+		if skipping {
+			results[i] = "skipped (completed earlier)"
+			skipping = step.Name != completed
+			continue
+		}
+
+		fmt.Fprintf(Stdout, "%s...\n", step.Description)
+		if err := step.Run(u); err != nil {
+			results[i] = "failed"
+			printUpgradeSummary(current, version, results)
+			return fmt.Errorf("the upgrade stopped at step %q: %v; once the problem is fixed, run `deisctl upgrade --to=%s` to resume it",
+				step.Name, err, version)
+		}
+		if _, err := cb.Set(upgradeKey+"/step", step.Name); err != nil {
+			return err
+		}
+		results[i] = "done"
+	}
+
+	for _, key := range []string{"version", "from", "step", "services"} {
+		cb.Delete(upgradeKey + "/" + key)
+	}
+	printUpgradeSummary(current, version, results)
 	return nil
 }
+
+// checkEtcdHealth returns an error unless every member of the etcd cluster is healthy.
+// Config backends which cannot report on the cluster are not checked.
+func checkEtcdHealth(cb config.Backend) error {
+	cluster, ok := cb.(etcdCluster)
+	if !ok {
+		return nil
+	}
+	members, err := cluster.Members()
+	if err != nil {
+		return fmt.Errorf("etcd is not healthy: %v", err)
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("etcd is not healthy: no members reported")
+	}
+	for _, m := range members {
+		if m.Error != "" {
+			return fmt.Errorf("etcd is not healthy: %s: %s", m.Endpoint, m.Error)
+		}
+	}
+	return nil
+}
+
+func (u *upgrade) installUnits() error {
+	return refreshUnits(u.unitDir, RefreshOptions{Source: u.staging, NoVerify: true}, ioutil.Discard)
+}
+
+func (u *upgrade) pinVersion() error {
+	_, err := u.cb.Set("/deis/platform/version", u.version)
+	return err
+}
+
+func (u *upgrade) prep() error {
+	return stopControlPlane(u.b, Stdout)
+}
+
+// republish hands the services over from the publisher, and records them so that a
+// resumed upgrade can republish them again.
+func (u *upgrade) republish() error {
+	nodes, err := listPublishedServices(u.cb)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(nodes)
+	if err != nil {
+		return err
+	}
+	if _, err := u.cb.Set(upgradeKey+"/services", string(data)); err != nil {
+		return err
+	}
+	return handOverServices(u.b, u.cb, nodes, Stdout)
+}
+
+func (u *upgrade) restartRouters() error {
+	// the republished services may have expired while the upgrade was interrupted
+	if u.completed == "republish" {
+		data, err := u.cb.GetWithDefault(upgradeKey+"/services", "")
+		if err != nil {
+			return err
+		}
+		var nodes []*model.ConfigNode
+		if data != "" {
+			if err := json.Unmarshal([]byte(data), &nodes); err != nil {
+				return fmt.Errorf("could not read the services handed over from the publisher: %v", err)
+			}
+		}
+		if err := republishServices(1800, nodes, u.cb); err != nil {
+			return err
+		}
+	}
+	return restartRouters(u.b, Stdout)
+}
+
+func (u *upgrade) startPlatform() error {
+	return startUpgradedPlatform(u.b, Stdout)
+}
+
+func printUpgradeSummary(from, to string, results []string) {
+	fmt.Fprintf(Stdout, "\nUpgrade from %s to %s:\n", orDash(from), to)
+	out := new(tabwriter.Writer)
+	out.Init(Stdout, 0, 8, 1, '\t', 0)
+	for i, step := range upgradeSteps {
+		fmt.Fprintf(out, "%s\t%s\t%s\n", step.Name, step.Description, orDash(results[i]))
+	}
+	out.Flush()
+}
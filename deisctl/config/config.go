@@ -7,8 +7,10 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/deis/deis/deisctl/utils"
+	"github.com/deis/deis/pkg/whitelist"
 )
 
 // fileKeys define config keys to be read from local files
@@ -21,6 +23,10 @@ var fileKeys = []string{
 // b64Keys define config keys to be base64 encoded before stored
 var b64Keys = []string{"/deis/platform/sshPrivateKey"}
 
+// Stderr is where warnings about the values being set are written. By default, this is
+// os.Stderr.
+var Stderr io.Writer = os.Stderr
+
 // Config runs the config subcommand
 func Config(target string, action string, key []string, cb Backend) error {
 	return doConfig(target, action, key, cb, os.Stdout)
@@ -48,7 +54,13 @@ func doConfig(target string, action string, key []string, cb Backend, w io.Write
 	case "rm":
 		vals, err = doConfigRm(cb, rootPath, key)
 	case "set":
-		vals, err = doConfigSet(cb, rootPath, key)
+		var warnings []string
+		vals, warnings, err = doConfigSet(cb, target, key)
+		for _, warning := range warnings {
+			fmt.Fprintf(Stderr, "Warning: %s\n", warning)
+		}
+	case "list":
+		return doConfigList(cb, target, w)
 	default:
 		vals, err = doConfigGet(cb, rootPath, key)
 	}
@@ -63,31 +75,110 @@ func doConfig(target string, action string, key []string, cb Backend, w io.Write
 	return nil
 }
 
-func doConfigSet(cb Backend, root string, kvs []string) ([]string, error) {
-	var result []string
+// doConfigSet validates every key and value against the target's schema before setting
+// any of them, returning warnings for keys the schema does not know.
+func doConfigSet(cb Backend, target string, kvs []string) ([]string, []string, error) {
+	var result, warnings []string
+	root := "/deis/" + target + "/"
 
-	for _, kv := range kvs {
+	paths := make([]string, len(kvs))
+	vals := make([]string, len(kvs))
+	for i, kv := range kvs {
 
 		// split k/v from args
 		split := strings.SplitN(kv, "=", 2)
+		if len(split) != 2 {
+			return result, warnings, fmt.Errorf("%s is not in the form key=value", kv)
+		}
 		k, v := split[0], split[1]
 
+		warning, err := validate(target, k, v)
+		if err != nil {
+			return result, warnings, err
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+
 		// prepare path and value
-		path := root + k
-		val, err := valueForPath(path, v)
+		paths[i] = root + k
+		vals[i], err = valueForPath(paths[i], v)
 		if err != nil {
-			return result, err
+			return result, warnings, err
 		}
+	}
 
+	for i := range paths {
 		// set key/value in config backend
-		ret, err := cb.Set(path, val)
+		ret, err := cb.Set(paths[i], vals[i])
 		if err != nil {
-			return result, err
+			return result, warnings, err
 		}
 		result = append(result, ret)
+	}
+	return result, warnings, nil
+}
 
+// doConfigList prints every known setting of a target with its current and default
+// values, followed by any keys set under the target that the schema does not know.
+func doConfigList(cb Backend, target string, w io.Writer) error {
+	if _, ok := schema[target]; !ok {
+		return fmt.Errorf("no settings are known for %s", target)
 	}
-	return result, nil
+	root := "/deis/" + target + "/"
+
+	out := new(tabwriter.Writer)
+	out.Init(w, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(out, "KEY\tCURRENT\tDEFAULT\tDESCRIPTION")
+	for _, s := range sortedSettings(target) {
+		current := "-"
+		if v, err := cb.Get(root + s.Key); err == nil {
+			current = v
+			if s.Secret || s.Type == fileType {
+				current = "(set)"
+			}
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", s.Key, abbreviate(current), abbreviate(orDash(s.Default)),
+			s.Description)
+	}
+
+	// keys published by the components themselves are not settings, but typos are
+	nodes, _ := cb.GetRecursive(root)
+	for _, node := range nodes {
+		k := strings.TrimPrefix(node.Key, root)
+		if node.TTL > 0 || !strings.HasPrefix(node.Key, root) {
+			continue
+		}
+		s, _ := lookupSetting(target, k)
+		switch {
+		case s == nil:
+			fmt.Fprintf(out, "%s\t%s\t-\t(unknown setting)\n", k, abbreviate(node.Value))
+		case s.Key != k:
+			// keys matching a pattern, such as router/memoryLimit, are listed as they are set
+			current := node.Value
+			if s.Secret || s.Type == fileType {
+				current = "(set)"
+			}
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", k, abbreviate(current), abbreviate(orDash(s.Default)), s.Description)
+		}
+	}
+	return out.Flush()
+}
+
+// abbreviate shortens long values so that the list stays readable.
+func abbreviate(v string) string {
+	v = strings.Replace(v, "\n", " ", -1)
+	if len(v) > 40 {
+		return v[:37] + "..."
+	}
+	return v
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
 }
 
 func doConfigGet(cb Backend, root string, keys []string) ([]string, error) {
@@ -117,6 +208,15 @@ func doConfigRm(cb Backend, root string, keys []string) ([]string, error) {
 // valueForPath returns the canonical value for a user-defined path and value
 func valueForPath(path string, v string) (string, error) {
 
+	// reject malformed whitelists, which would lock everyone out of the controller
+	if path == controllerWhitelistKey {
+		entries, err := whitelist.Parse(v)
+		if err != nil {
+			return "", err
+		}
+		return whitelist.Format(entries), nil
+	}
+
 	// check if path is part of fileKeys
 	for _, p := range fileKeys {
 
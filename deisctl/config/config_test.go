@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/deis/deis/deisctl/config/model"
@@ -152,3 +153,125 @@ func writeTempFile(data string) (*os.File, error) {
 
 	return f, nil
 }
+
+func TestWhitelist(t *testing.T) {
+	t.Parallel()
+
+	testMock := mock.ConfigBackend{Expected: []*model.ConfigNode{{Key: "/deis/router/controller/whitelist", Value: "10.0.0.0/8:vpn"}}}
+	testWriter := bytes.Buffer{}
+
+	err := doWhitelist("add", []string{"192.168.1.1", "10.0.0.0/8"}, testMock, &testWriter)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "10.0.0.0/8:vpn\n192.168.1.1\n"
+	output := testWriter.String()
+	if output != expected {
+		t.Error(fmt.Errorf("Expected: '%s', Got:'%s'", expected, output))
+	}
+
+	if err = doWhitelist("add", []string{"10.0.0.0/33"}, testMock, &testWriter); err == nil {
+		t.Error("Expected an invalid CIDR block to be rejected")
+	}
+
+	if err = doWhitelist("remove", []string{"172.16.0.1"}, testMock, &testWriter); err == nil {
+		t.Error("Expected removing a missing address to fail")
+	}
+}
+
+func TestSetWhitelistConfig(t *testing.T) {
+	t.Parallel()
+
+	testMock := mock.ConfigBackend{Expected: []*model.ConfigNode{{Key: "/deis/router/controller/whitelist", Value: ""}}}
+	testWriter := bytes.Buffer{}
+
+	if err := doConfig("router", "set", []string{"controller/whitelist=10.0.0.1,10.0.0.300"}, testMock, &testWriter); err == nil {
+		t.Error("Expected an invalid whitelist to be rejected")
+	}
+}
+
+func TestSetConfigValidation(t *testing.T) {
+	t.Parallel()
+
+	testMock := mock.ConfigBackend{Expected: []*model.ConfigNode{
+		{Key: "/deis/router/gzipCompLevel", Value: "5"},
+		{Key: "/deis/router/enforceHTTPS", Value: "false"},
+		{Key: "/deis/router/gzipCompLevl", Value: ""},
+	}}
+	testWriter := bytes.Buffer{}
+
+	for _, kv := range []string{"gzipCompLevel=ten", "gzipCompLevel=10", "enforceHTTPS=yes", "proxyRealIpCidr=10.0.0.0", "bodySize"} {
+		if err := doConfig("router", "set", []string{kv}, testMock, &testWriter); err == nil {
+			t.Error(fmt.Errorf("Expected %s to be rejected", kv))
+		}
+	}
+
+	// nothing is set unless every value is valid
+	if err := doConfig("router", "set", []string{"enforceHTTPS=true", "gzipCompLevel=0"}, testMock, &testWriter); err == nil {
+		t.Error("Expected gzipCompLevel=0 to be rejected")
+	}
+	if v, _ := testMock.Get("/deis/router/enforceHTTPS"); v != "false" {
+		t.Error(fmt.Errorf("Expected false, Got %v", v))
+	}
+
+	_, warnings, err := doConfigSet(testMock, "router", []string{"gzipCompLevl=9", "gzipCompLevel=9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "gzipCompLevl is not a known router setting, did you mean gzipCompLevel?"
+	if len(warnings) != 1 || warnings[0] != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, warnings))
+	}
+}
+
+func TestSetConfigPattern(t *testing.T) {
+	t.Parallel()
+
+	testMock := mock.ConfigBackend{Expected: []*model.ConfigNode{
+		{Key: "/deis/units/router/memoryLimit", Value: ""},
+		{Key: "/deis/units/router/memoryLimt", Value: ""},
+	}}
+
+	_, warnings, err := doConfigSet(testMock, "units", []string{"router/memoryLimit=512m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Error(fmt.Errorf("Expected router/memoryLimit to be known, Got %v", warnings))
+	}
+
+	_, warnings, err = doConfigSet(testMock, "units", []string{"router/memoryLimt=512m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 {
+		t.Error(fmt.Errorf("Expected a warning for router/memoryLimt, Got %v", warnings))
+	}
+}
+
+func TestListConfig(t *testing.T) {
+	t.Parallel()
+
+	testMock := mock.ConfigBackend{Expected: []*model.ConfigNode{
+		{Key: "/deis/cache/port", Value: "6380"},
+	}}
+	testWriter := bytes.Buffer{}
+
+	if err := doConfig("cache", "list", nil, testMock, &testWriter); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `KEY  CURRENT DEFAULT DESCRIPTION
+host -       -       host of a Redis cache used by the registry
+port 6380    -       port of a Redis cache used by the registry
+`
+	if strings.Join(strings.Fields(testWriter.String()), " ") != strings.Join(strings.Fields(expected), " ") {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, testWriter.String()))
+	}
+
+	if err := doConfig("builder", "list", nil, testMock, &testWriter); err == nil {
+		t.Error("Expected listing a target without settings to fail")
+	}
+}
@@ -1,13 +1,14 @@
 package etcd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/coreos/fleet/pkg"
 	"github.com/coreos/fleet/ssh"
 	etcdlib "github.com/coreos/go-etcd/etcd"
 	"github.com/deis/deis/deisctl/backend/fleet"
@@ -17,6 +18,8 @@ import (
 // ConfigBackend is an etcd-based implementation of the config.Backend interface
 type ConfigBackend struct {
 	etcdlib *etcdlib.Client
+	// http shares the transport of etcdlib, to query the members directly
+	http *http.Client
 }
 
 func getTunnelFlag() string {
@@ -64,6 +67,7 @@ func singleNodeToConfigNode(node *etcdlib.Node) *model.ConfigNode {
 	key := model.ConfigNode{
 		Key:        node.Key,
 		Expiration: node.Expiration,
+		TTL:        node.TTL,
 	}
 
 	if node.Dir != true && node.Key != "" {
@@ -147,8 +151,11 @@ func NewConfigBackend() (*ConfigBackend, error) {
 		}
 	}
 
-	tlsConfig, err := pkg.ReadTLSConfigFiles(fleet.Flags.EtcdCAFile,
-		fleet.Flags.EtcdCertFile, fleet.Flags.EtcdKeyFile)
+	machines, err := fleet.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := fleet.TLSConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -159,13 +166,59 @@ func NewConfigBackend() (*ConfigBackend, error) {
 	}
 
 	timeout := time.Duration(fleet.Flags.RequestTimeout*1000) * time.Millisecond
-	machines := []string{fleet.Flags.Endpoint}
-
 	c := etcdlib.NewClient(machines)
 	c.SetDialTimeout(timeout)
 
 	// use custom transport with SSH tunnel capability
 	c.SetTransport(&trans)
 
-	return &ConfigBackend{etcdlib: c}, nil
+	return &ConfigBackend{etcdlib: c, http: &http.Client{Transport: &trans, Timeout: timeout}}, nil
+}
+
+// Members returns the version and health of every member of the etcd cluster
+func (cb *ConfigBackend) Members() ([]*model.EtcdMember, error) {
+	if !cb.etcdlib.SyncCluster() {
+		return nil, fmt.Errorf("could not reach any etcd member at %s", strings.Join(cb.etcdlib.GetCluster(), ", "))
+	}
+
+	var members []*model.EtcdMember
+	for _, endpoint := range cb.etcdlib.GetCluster() {
+		m := &model.EtcdMember{Endpoint: endpoint}
+		members = append(members, m)
+
+		body, status, err := cb.get(endpoint + "/version")
+		if err != nil {
+			m.Error = err.Error()
+			continue
+		}
+		// etcd 2.0 answers with plain text, later releases with JSON
+		version := struct {
+			Server string `json:"etcdserver"`
+		}{}
+		if json.Unmarshal(body, &version) == nil {
+			m.Version = version.Server
+		} else if status == http.StatusOK {
+			m.Version = strings.TrimPrefix(strings.TrimSpace(string(body)), "etcd ")
+		}
+
+		// etcd 2.0 has no health endpoint
+		body, status, err = cb.get(endpoint + "/health")
+		health := struct {
+			Health string `json:"health"`
+		}{}
+		if err == nil && status == http.StatusOK && json.Unmarshal(body, &health) == nil && health.Health != "true" {
+			m.Error = "reports itself unhealthy"
+		}
+	}
+	return members, nil
+}
+
+func (cb *ConfigBackend) get(url string) ([]byte, int, error) {
+	resp, err := cb.http.Get(url)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
 }
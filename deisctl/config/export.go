@@ -0,0 +1,187 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// unexportedKeys are trees of keys that components or the controller manage themselves,
+// and which are republished from their own state rather than restored from an export.
+var unexportedKeys = []string{
+	"/deis/backups",
+	"/deis/builder/users",
+	"/deis/certs",
+	"/deis/config",
+	"/deis/database/restore",
+	"/deis/domains",
+	"/deis/services",
+	"/deis/upgrade",
+}
+
+// snapshot holds configuration values keyed by component, then by key relative to
+// /deis/<component>/. Values are stored as they are in etcd, so base64 keys such as
+// sshPrivateKey stay encoded and file keys hold the contents of the file.
+type snapshot map[string]map[string]string
+
+// Export writes the configuration of the given components, or of every component when
+// none are given, as YAML.
+func Export(components []string, cb Backend) error {
+	return doExport(components, cb, os.Stdout)
+}
+
+// Import sets the configuration values read from a YAML export. With dryRun, the
+// differences with the current configuration are printed but nothing is changed.
+func Import(path string, dryRun bool, cb Backend) error {
+	var r io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return doImport(r, dryRun, cb, os.Stdout)
+}
+
+// ExportTo writes the configuration of the given components, or of every component when
+// none are given, as YAML to w.
+func ExportTo(components []string, cb Backend, w io.Writer) error {
+	return doExport(components, cb, w)
+}
+
+// ImportFrom sets the configuration values of a YAML export read from r, printing the
+// changes to w.
+func ImportFrom(r io.Reader, cb Backend, w io.Writer) error {
+	return doImport(r, false, cb, w)
+}
+
+func doExport(components []string, cb Backend, w io.Writer) error {
+	roots := []string{"/deis"}
+	if len(components) > 0 {
+		roots = nil
+		for _, c := range components {
+			roots = append(roots, "/deis/"+c)
+		}
+	}
+
+	snap := make(snapshot)
+	for _, root := range roots {
+		nodes, err := cb.GetRecursive(root)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %v", root, err)
+		}
+		for _, node := range nodes {
+			// keys with a TTL are published by running components
+			if node.TTL > 0 || node.Expiration != nil || unexported(node.Key) {
+				continue
+			}
+			component, key, ok := splitKey(node.Key)
+			if !ok {
+				continue
+			}
+			if snap[component] == nil {
+				snap[component] = make(map[string]string)
+			}
+			snap[component][key] = node.Value
+		}
+	}
+
+	out, err := yaml.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func doImport(r io.Reader, dryRun bool, cb Backend, w io.Writer) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	snap := make(snapshot)
+	if err := yaml.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("could not parse the configuration: %v", err)
+	}
+
+	changed := 0
+	for _, component := range sortedComponents(snap) {
+		keys := make([]string, 0, len(snap[component]))
+		for k := range snap[component] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			path := "/deis/" + component + "/" + k
+			v := snap[component][k]
+			current, err := cb.Get(path)
+			exists := err == nil
+			if exists && current == v {
+				continue
+			}
+			changed++
+
+			shown, was := v, current
+			if s, _ := lookupSetting(component, k); s != nil && (s.Secret || s.Type == fileType) {
+				shown, was = "(set)", "(set)"
+			}
+			if exists {
+				fmt.Fprintf(w, "~ %s: %s -> %s\n", path, abbreviate(was), abbreviate(shown))
+			} else {
+				fmt.Fprintf(w, "+ %s: %s\n", path, abbreviate(shown))
+			}
+
+			// values are set as exported, without reading file keys from disk again
+			if !dryRun {
+				if _, err := cb.Set(path, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	switch {
+	case changed == 0:
+		fmt.Fprintln(w, "The configuration is up to date.")
+	case dryRun:
+		fmt.Fprintf(w, "%d keys would be changed.\n", changed)
+	default:
+		fmt.Fprintf(w, "%d keys changed.\n", changed)
+	}
+	return nil
+}
+
+// splitKey splits /deis/<component>/<key> into its component and key.
+func splitKey(path string) (component, key string, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/deis/"), "/", 2)
+	if !strings.HasPrefix(path, "/deis/") || len(parts) != 2 || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func unexported(key string) bool {
+	for _, prefix := range unexportedKeys {
+		if key == prefix || strings.HasPrefix(key, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedComponents(snap snapshot) []string {
+	components := make([]string, 0, len(snap))
+	for c := range snap {
+		components = append(components, c)
+	}
+	sort.Strings(components)
+	return components
+}
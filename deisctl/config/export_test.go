@@ -0,0 +1,138 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deis/deis/deisctl/config/model"
+	"github.com/deis/deis/deisctl/test/mock"
+)
+
+// memoryBackend is a config backend which records the values set and returns
+// every node under a key from GetRecursive.
+type memoryBackend struct {
+	mock.ConfigBackend
+	set map[string]string
+}
+
+func (m *memoryBackend) Get(key string) (string, error) {
+	if v, ok := m.set[key]; ok {
+		return v, nil
+	}
+	return m.ConfigBackend.Get(key)
+}
+
+func (m *memoryBackend) Set(key, value string) (string, error) {
+	m.set[key] = value
+	return value, nil
+}
+
+func (m *memoryBackend) GetRecursive(key string) ([]*model.ConfigNode, error) {
+	var nodes []*model.ConfigNode
+	for _, node := range m.Expected {
+		if strings.HasPrefix(node.Key, key+"/") {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+func newMemoryBackend(nodes ...*model.ConfigNode) *memoryBackend {
+	return &memoryBackend{ConfigBackend: mock.ConfigBackend{Expected: nodes}, set: make(map[string]string)}
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	cb := newMemoryBackend(
+		&model.ConfigNode{Key: "/deis/platform/domain", Value: "example.com"},
+		&model.ConfigNode{Key: "/deis/platform/sshPrivateKey", Value: "cHJpdmF0ZS1rZXk="},
+		&model.ConfigNode{Key: "/deis/router/hsts/enabled", Value: "true"},
+		&model.ConfigNode{Key: "/deis/router/hosts/10.0.0.1", Value: "10.0.0.1:80", TTL: 20},
+		&model.ConfigNode{Key: "/deis/services/app/app_v2.web.1", Value: "10.0.0.1:49153"},
+		&model.ConfigNode{Key: "/deis/config/app/deis_maintenance", Value: "true"},
+	)
+	testWriter := bytes.Buffer{}
+
+	if err := doExport(nil, cb, &testWriter); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `platform:
+  domain: example.com
+  sshPrivateKey: cHJpdmF0ZS1rZXk=
+router:
+  hsts/enabled: "true"
+`
+	if testWriter.String() != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, testWriter.String()))
+	}
+
+	testWriter.Reset()
+	if err := doExport([]string{"router"}, cb, &testWriter); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = "router:\n  hsts/enabled: \"true\"\n"
+	if testWriter.String() != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, testWriter.String()))
+	}
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	cb := newMemoryBackend(
+		&model.ConfigNode{Key: "/deis/platform/domain", Value: "staging.example.com"},
+		&model.ConfigNode{Key: "/deis/router/hsts/enabled", Value: "true"},
+	)
+	export := `platform:
+  domain: example.com
+  sshPrivateKey: cHJpdmF0ZS1rZXk=
+router:
+  hsts/enabled: "true"
+`
+	testWriter := bytes.Buffer{}
+
+	if err := doImport(strings.NewReader(export), true, cb, &testWriter); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `~ /deis/platform/domain: staging.example.com -> example.com
++ /deis/platform/sshPrivateKey: (set)
+2 keys would be changed.
+`
+	if testWriter.String() != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, testWriter.String()))
+	}
+	if len(cb.set) != 0 {
+		t.Error(fmt.Errorf("Expected a dry run to change nothing, Got %v", cb.set))
+	}
+
+	testWriter.Reset()
+	if err := doImport(strings.NewReader(export), false, cb, &testWriter); err != nil {
+		t.Fatal(err)
+	}
+
+	// base64 values are restored as exported rather than read from a file
+	if v := cb.set["/deis/platform/sshPrivateKey"]; v != "cHJpdmF0ZS1rZXk=" {
+		t.Error(fmt.Errorf("Expected cHJpdmF0ZS1rZXk=, Got %v", v))
+	}
+	if v := cb.set["/deis/platform/domain"]; v != "example.com" {
+		t.Error(fmt.Errorf("Expected example.com, Got %v", v))
+	}
+
+	testWriter.Reset()
+	if err := doImport(strings.NewReader(export), false, cb, &testWriter); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "The configuration is up to date.\n"; testWriter.String() != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, testWriter.String()))
+	}
+
+	if err := doImport(strings.NewReader("- not a snapshot"), false, cb, &testWriter); err == nil {
+		t.Error("Expected an invalid export to be rejected")
+	}
+}
@@ -9,3 +9,11 @@ type ConfigNode struct {
 	Expiration *time.Time `json:"expiration,omitempty"`
 	TTL        int64      `json:"ttl,omitempty"`
 }
+
+// EtcdMember reports the version and health of a member of the etcd cluster
+type EtcdMember struct {
+	Endpoint string `json:"endpoint"`
+	Version  string `json:"version,omitempty"`
+	// Error explains why the member is unhealthy, or is empty if it is healthy
+	Error string `json:"error,omitempty"`
+}
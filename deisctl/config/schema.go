@@ -0,0 +1,236 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// settingType describes the values a setting accepts.
+type settingType string
+
+const (
+	stringType settingType = "string"
+	intType    settingType = "int"
+	boolType   settingType = "bool"
+	cidrType   settingType = "cidr"
+	// fileType values are paths to local files whose contents are stored, see fileKeys.
+	fileType settingType = "file"
+)
+
+// setting describes a key under /deis/<component>/ that is read by a component.
+type setting struct {
+	Key  string
+	Type settingType
+	// Allowed restricts the setting to a set of values, when not empty.
+	Allowed []string
+	// Default is the value the component uses when the key is not set.
+	Default     string
+	Description string
+	// Secret settings are never printed.
+	Secret bool
+}
+
+// schema registers the settings known for each configuration target, as documented
+// in docs/customizing_deis and read by the components' templates.
+var schema = map[string][]setting{
+	"platform": {
+		{Key: "domain", Type: stringType, Description: "base domain for the controller and applications"},
+		{Key: "sshPrivateKey", Type: fileType, Secret: true, Description: "SSH private key used by deisctl to reach the cluster"},
+		{Key: "enablePlacementOptions", Type: boolType, Default: "false", Description: "isolate the control plane, data plane and router mesh"},
+		{Key: "version", Type: stringType, Description: "platform release used to tag component images"},
+	},
+	"controller": {
+		{Key: "registrationMode", Type: stringType, Allowed: []string{"enabled", "disabled", "admin_only"}, Default: "enabled", Description: "who may register new users"},
+		{Key: "schedulerModule", Type: stringType, Allowed: []string{"fleet", "swarm", "mesos_marathon", "k8s", "chaos", "mock"}, Default: "fleet", Description: "scheduler backend"},
+		{Key: "subdomain", Type: stringType, Default: "deis", Description: "subdomain used by the router for API requests"},
+		{Key: "webEnabled", Type: intType, Allowed: []string{"0", "1"}, Default: "0", Description: "enable the controller web UI"},
+		{Key: "workers", Type: intType, Description: "number of web worker processes (default: CPU cores * 2 + 1)"},
+		{Key: "auth/ldap/endpoint", Type: stringType, Description: "full LDAP endpoint, such as ldap://ldap.company.com"},
+		{Key: "auth/ldap/bind/dn", Type: stringType, Description: "user to bind as, empty for an anonymous bind"},
+		{Key: "auth/ldap/bind/password", Type: stringType, Secret: true, Description: "password of the bind user"},
+		{Key: "auth/ldap/user/basedn", Type: stringType, Description: "base DN of LDAP users"},
+		{Key: "auth/ldap/user/filter", Type: stringType, Description: "field matched against Deis usernames"},
+		{Key: "auth/ldap/group/basedn", Type: stringType, Description: "base DN of LDAP groups"},
+		{Key: "auth/ldap/group/filter", Type: stringType, Description: "field used to find LDAP groups"},
+		{Key: "auth/ldap/group/type", Type: stringType, Description: "type of LDAP groups, such as groupOfNames"},
+	},
+	"units": {
+		{Key: "imageRegistry", Type: stringType, Description: "registry prefixed to the images of the platform, such as a private mirror"},
+		{Key: "imageTag", Type: stringType, Description: "tag of the images of the platform (default: the platform version)"},
+		{Key: "memoryLimit", Type: stringType, Description: "memory limit of each component container, such as 512m"},
+		{Key: "dockerArgs", Type: stringType, Description: "extra arguments to docker run for each component"},
+		{Key: "machineMetadata", Type: stringType, Description: "fleet metadata required of the machines running each component, such as role=control"},
+		{Key: "*/imageRegistry", Type: stringType, Description: "imageRegistry for one component, such as router/imageRegistry"},
+		{Key: "*/imageTag", Type: stringType, Description: "imageTag for one component"},
+		{Key: "*/memoryLimit", Type: stringType, Description: "memoryLimit for one component"},
+		{Key: "*/dockerArgs", Type: stringType, Description: "dockerArgs for one component"},
+		{Key: "*/machineMetadata", Type: stringType, Description: "machineMetadata for one component"},
+	},
+	"cache": {
+		{Key: "host", Type: stringType, Description: "host of a Redis cache used by the registry"},
+		{Key: "port", Type: intType, Description: "port of a Redis cache used by the registry"},
+	},
+	"router": {
+		{Key: "affinityArg", Type: stringType, Description: "query string variable hashed for session affinity"},
+		{Key: "bodySize", Type: stringType, Default: "1m", Description: "nginx client_max_body_size"},
+		{Key: "builder/timeout/connect", Type: intType, Default: "10000", Description: "proxy_connect_timeout for the builder, in milliseconds"},
+		{Key: "builder/timeout/tcp", Type: intType, Default: "1200000", Description: "proxy_timeout for the builder, in milliseconds"},
+		{Key: "controller/timeout/connect", Type: stringType, Default: "10s", Description: "proxy_connect_timeout for the controller"},
+		{Key: "controller/timeout/read", Type: stringType, Default: "20m", Description: "proxy_read_timeout for the controller"},
+		{Key: "controller/timeout/send", Type: stringType, Default: "20m", Description: "proxy_send_timeout for the controller"},
+		{Key: "controller/whitelist", Type: stringType, Description: "addresses allowed to reach the controller, see \"deisctl config router whitelist\""},
+		{Key: "defaultTimeout", Type: intType, Default: "1300", Description: "default timeout in seconds"},
+		{Key: "enforceHTTPS", Type: boolType, Default: "false", Description: "redirect all HTTP traffic to HTTPS"},
+		{Key: "enforceWhitelist", Type: boolType, Default: "false", Description: "deny all connections unless whitelisted"},
+		{Key: "errorLogLevel", Type: stringType, Allowed: []string{"debug", "info", "notice", "warn", "error", "crit", "alert", "emerg"}, Default: "error", Description: "nginx error_log level"},
+		{Key: "firewall/enabled", Type: boolType, Default: "false", Description: "enable the nginx naxsi firewall"},
+		{Key: "firewall/errorCode", Type: intType, Default: "400", Description: "status code returned by the firewall"},
+		{Key: "gzip", Type: stringType, Allowed: []string{"on", "off"}, Default: "on", Description: "nginx gzip"},
+		{Key: "gzipCompLevel", Type: intType, Allowed: []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}, Default: "5", Description: "nginx gzip_comp_level"},
+		{Key: "gzipDisable", Type: stringType, Default: "msie6", Description: "nginx gzip_disable"},
+		{Key: "gzipHttpVersion", Type: stringType, Allowed: []string{"1.0", "1.1"}, Default: "1.1", Description: "nginx gzip_http_version"},
+		{Key: "gzipMinLength", Type: intType, Default: "256", Description: "nginx gzip_min_length"},
+		{Key: "gzipProxied", Type: stringType, Default: "any", Description: "nginx gzip_proxied"},
+		{Key: "gzipTypes", Type: stringType, Default: "application/atom+xml application/javascript application/json application/rss+xml application/vnd.ms-fontobject application/x-font-ttf application/x-web-app-manifest+json application/xhtml+xml application/xml font/opentype image/svg+xml image/x-icon text/css text/plain text/x-component", Description: "nginx gzip_types"},
+		{Key: "gzipVary", Type: stringType, Allowed: []string{"on", "off"}, Default: "on", Description: "nginx gzip_vary"},
+		{Key: "hsts/enabled", Type: boolType, Default: "false", Description: "send HTTP Strict Transport Security headers"},
+		{Key: "hsts/includeSubDomains", Type: boolType, Default: "false", Description: "enforce HSTS on all subdomains"},
+		{Key: "hsts/maxAge", Type: intType, Default: "10886400", Description: "seconds user agents observe HSTS"},
+		{Key: "hsts/preload", Type: boolType, Default: "false", Description: "allow the domain in the HSTS preload list"},
+		{Key: "maintenancePage", Type: stringType, Description: "HTML served to applications in maintenance mode"},
+		{Key: "maxWorkerConnections", Type: intType, Default: "768", Description: "nginx worker_connections"},
+		{Key: "proxyProtocol", Type: boolType, Default: "false", Description: "accept the PROXY protocol"},
+		{Key: "proxyRealIpCidr", Type: cidrType, Default: "10.0.0.0/8", Description: "CIDR of the load balancer in front of the routers"},
+		{Key: "serverNameHashBucketSize", Type: intType, Default: "64", Description: "nginx server_names_hash_bucket_size"},
+		{Key: "serverNameHashMaxSize", Type: intType, Default: "512", Description: "nginx server_names_hash_max_size"},
+		{Key: "sslBufferSize", Type: stringType, Default: "4k", Description: "nginx ssl_buffer_size"},
+		{Key: "sslCert", Type: fileType, Description: "cluster-wide SSL certificate"},
+		{Key: "sslCiphers", Type: stringType, Description: "cluster-wide enabled SSL ciphers"},
+		{Key: "sslDhparam", Type: fileType, Description: "cluster-wide SSL dhparam"},
+		{Key: "sslKey", Type: fileType, Secret: true, Description: "cluster-wide SSL private key"},
+		{Key: "sslProtocols", Type: stringType, Default: "TLSv1 TLSv1.1 TLSv1.2", Description: "nginx ssl_protocols"},
+		{Key: "sslSessionCache", Type: stringType, Description: "nginx ssl_session_cache"},
+		{Key: "sslSessionTickets", Type: stringType, Allowed: []string{"on", "off"}, Default: "on", Description: "nginx ssl_session_tickets"},
+		{Key: "sslSessionTimeout", Type: stringType, Default: "10m", Description: "nginx ssl_session_timeout"},
+		{Key: "workerProcesses", Type: stringType, Default: "auto", Description: "number of nginx worker processes, or auto for one per CPU core"},
+	},
+}
+
+// lookupSetting returns the setting registered for a key of a target. ok is false if
+// the target has no schema at all, in which case any key is accepted. Registered keys may
+// be patterns, such as */memoryLimit for the memoryLimit of any component.
+func lookupSetting(target, key string) (s *setting, ok bool) {
+	settings, ok := schema[target]
+	if !ok {
+		return nil, false
+	}
+	for i := range settings {
+		if matched, _ := path.Match(settings[i].Key, key); matched {
+			return &settings[i], true
+		}
+	}
+	return nil, true
+}
+
+// validate checks a value against the setting registered for its key. Unknown keys of
+// a target with a schema are accepted with a warning, which suggests the closest known key.
+func validate(target, key, value string) (warning string, err error) {
+	s, ok := lookupSetting(target, key)
+	if !ok {
+		return "", nil
+	}
+	if s == nil {
+		warning = fmt.Sprintf("%s is not a known %s setting", key, target)
+		if suggestion := closestKey(target, key); suggestion != "" {
+			warning += fmt.Sprintf(", did you mean %s?", suggestion)
+		}
+		return warning, nil
+	}
+
+	switch s.Type {
+	case intType:
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("%s must be an integer, got %q", key, value)
+		}
+	case boolType:
+		if value != "true" && value != "false" {
+			return "", fmt.Errorf("%s must be true or false, got %q", key, value)
+		}
+	case cidrType:
+		if _, _, err := net.ParseCIDR(value); err != nil {
+			return "", fmt.Errorf("%s must be a CIDR block such as 10.0.0.0/8, got %q", key, value)
+		}
+	}
+	if len(s.Allowed) > 0 && !contains(s.Allowed, value) {
+		return "", fmt.Errorf("%s must be one of %s, got %q", key, strings.Join(s.Allowed, ", "), value)
+	}
+	return "", nil
+}
+
+// closestKey returns the known key of a target nearest to key, if it is a likely typo.
+func closestKey(target, key string) string {
+	best, bestDistance := "", 4
+	for _, s := range schema[target] {
+		if d := distance(strings.ToLower(key), strings.ToLower(s.Key)); d < bestDistance {
+			best, bestDistance = s.Key, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minimum(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minimum(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedSettings returns the settings of a target ordered by key.
+func sortedSettings(target string) []setting {
+	settings := append([]setting(nil), schema[target]...)
+	sort.Sort(byKey(settings))
+	return settings
+}
+
+type byKey []setting
+
+func (s byKey) Len() int           { return len(s) }
+func (s byKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byKey) Less(i, j int) bool { return s[i].Key < s[j].Key }
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/deis/deis/pkg/whitelist"
+)
+
+// controllerWhitelistKey lists the addresses the router allows to reach the controller.
+const controllerWhitelistKey = "/deis/router/controller/whitelist"
+
+// Whitelist lists, adds or removes addresses allowed to connect to the controller.
+func Whitelist(action string, addresses []string, cb Backend) error {
+	return doWhitelist(action, addresses, cb, os.Stdout)
+}
+
+func doWhitelist(action string, addresses []string, cb Backend, w io.Writer) error {
+	// validate everything locally before touching the current whitelist
+	changes := make([]whitelist.Entry, len(addresses))
+	for i, address := range addresses {
+		entry, err := whitelist.ParseEntry(address)
+		if err != nil {
+			return err
+		}
+		changes[i] = entry
+	}
+
+	current, err := cb.GetWithDefault(controllerWhitelistKey, "")
+	if err != nil {
+		return err
+	}
+
+	entries, err := whitelist.Parse(current)
+	if err != nil {
+		return fmt.Errorf("the existing whitelist is invalid: %v", err)
+	}
+
+	switch action {
+	case "add":
+		entries = whitelist.Add(entries, changes...)
+	case "remove":
+		if entries, err = whitelist.Remove(entries, changes...); err != nil {
+			return err
+		}
+	}
+
+	if action == "add" || action == "remove" {
+		// an empty whitelist must be removed, otherwise the router denies everyone
+		if len(entries) == 0 {
+			err = cb.Delete(controllerWhitelistKey)
+		} else {
+			_, err = cb.Set(controllerWhitelistKey, whitelist.Format(entries))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		fmt.Fprintln(w, entry)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/deis/deis/deisctl/backend/dryrun"
 	"github.com/deis/deis/deisctl/backend/fleet"
 	"github.com/deis/deis/deisctl/client"
 	"github.com/deis/deis/pkg/prettyprint"
@@ -36,20 +37,32 @@ Commands, use "deisctl help <command>" to learn more:
   stop              stop components
   restart           stop, then start components
   status            view status of components
-  scale             grow or shrink the number of routers, registries or store gateways
-  journal           print the log output of a component
+  health            check the health of every installed component
+  doctor            check that the cluster is ready for the platform to be installed
+  scale             grow or shrink the number of instances of a component with an @ template
+  journal           print the log output of components, interleaved by time
   config            set platform or component values
-  refresh-units     refresh unit files from GitHub
+  backup            back up or restore the database and the platform configuration
+  refresh-units     refresh unit files from GitHub, a directory, an archive or a mirror
+  render            print the unit files of components with their variables filled in
   ssh               open an interactive shell on a machine in the cluster
+  machines          list the machines of the cluster with their metadata and units
+  rebalance         spread the instances of a component evenly across machines
+  exec              run a command on every machine in the cluster, or on those matching metadata
   dock              open an interactive shell on a container in the cluster
   help              show the help screen for a command
+  upgrade           gracefully upgrade the platform to another release
   upgrade-prep      prepare a running cluster for upgrade
   upgrade-takeover  allow an upgrade to gracefully takeover a running cluster
-  rolling-restart   perform a rolling restart of a Deis component (currently only router is supported)
+  rolling-restart   perform a health-gated rolling restart of a Deis component
 
 Options:
   -h --help                   show this help screen
-  --endpoint=<url>            etcd endpoint for fleet [default: http://127.0.0.1:4001]
+  --backend=<backend>         manage components with fleet, kubernetes or local [default: fleet]
+  --dry-run                   print the unit operations of install, uninstall, start, stop,
+                              restart, scale, rolling-restart or upgrade-prep without performing them
+  --discovery-srv=<domain>    discover etcd members from the SRV records of a domain [default: ]
+  --endpoint=<urls>           comma-separated etcd members for fleet [default: http://127.0.0.1:4001]
   --etcd-cafile=<path>        etcd CA file authentication [default: ]
   --etcd-certfile=<path>      etcd cert file authentication [default: ]
   --etcd-key-prefix=<path>    keyspace for fleet data in etcd [default: /_coreos.com/fleet/]
@@ -59,6 +72,7 @@ Options:
   --ssh-timeout=<secs>        seconds before SSH connection is considered failed [default: 10.0]
   --strict-host-key-checking  verify SSH host keys [default: true]
   --tunnel=<host>             SSH tunnel for communication with fleet and etcd [default: ]
+  --unit-timeout=<secs>       seconds to wait for a unit to load, start or stop [default: 1200.0]
   --version                   print the version of deisctl
 `
 	// pre-parse command-line arguments
@@ -85,11 +99,19 @@ Options:
 	// clean up the args so subcommands don't need to reparse them
 	argv = removeGlobalArgs(argv)
 	// construct a client
-	c, err := client.NewClient("fleet")
+	c, err := client.NewClient(args["--backend"].(string))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return 1
 	}
+	if args["--dry-run"] == true {
+		if !dryRunCommands[command.(string)] {
+			fmt.Printf("Error: --dry-run is not supported by %s\n", command)
+			return 1
+		}
+		c.Backend = dryrun.NewClient(c.Backend)
+		fmt.Println("Dry run: the following unit operations would be performed, but nothing is changed.")
+	}
 	// Dispatch the command, passing the argv through so subcommands can
 	// re-parse it according to their usage strings.
 	switch command {
@@ -105,20 +127,36 @@ Options:
 		err = c.Stop(argv)
 	case "status":
 		err = c.Status(argv)
+	case "health":
+		err = c.Health(argv)
+	case "doctor":
+		err = c.Doctor(argv)
 	case "journal":
 		err = c.Journal(argv)
+	case "exec":
+		err = c.Exec(argv)
+	case "machines":
+		err = c.Machines(argv)
+	case "rebalance":
+		err = c.Rebalance(argv)
 	case "install":
 		err = c.Install(argv)
 	case "uninstall":
 		err = c.Uninstall(argv)
 	case "config":
 		err = c.Config(argv)
+	case "backup":
+		err = c.Backup(argv)
 	case "refresh-units":
 		err = c.RefreshUnits(argv)
+	case "render":
+		err = c.Render(argv)
 	case "ssh":
 		err = c.SSH(argv)
 	case "dock":
 		err = c.Dock(argv)
+	case "upgrade":
+		err = c.Upgrade(argv)
 	case "upgrade-prep":
 		err = c.UpgradePrep(argv)
 	case "upgrade-takeover":
@@ -140,10 +178,25 @@ Usage: deisctl <command> [<args>...] [options]`)
 	return 0
 }
 
+// dryRunCommands only change the cluster through the backend, so --dry-run can print
+// what they would do.
+var dryRunCommands = map[string]bool{
+	"install":         true,
+	"uninstall":       true,
+	"start":           true,
+	"stop":            true,
+	"restart":         true,
+	"scale":           true,
+	"rolling-restart": true,
+	"upgrade-prep":    true,
+}
+
 // isGlobalArg returns true if a string looks like it is a global deisctl option flag,
 // such as "--tunnel".
 func isGlobalArg(arg string) bool {
 	prefixes := []string{
+		"--backend=",
+		"--discovery-srv=",
 		"--endpoint=",
 		"--etcd-key-prefix=",
 		"--etcd-keyfile=",
@@ -155,6 +208,10 @@ func isGlobalArg(arg string) bool {
 		"--ssh-timeout=",
 		"--strict-host-key-checking=",
 		"--tunnel=",
+		"--unit-timeout=",
+	}
+	if arg == "--dry-run" {
+		return true
 	}
 	for _, p := range prefixes {
 		if strings.HasPrefix(arg, p) {
@@ -211,6 +268,7 @@ func removeGlobalArgs(argv []string) []string {
 // setGlobalFlags sets fleet provider options based on deisctl global flags.
 func setGlobalFlags(args map[string]interface{}, setTunnel bool) {
 	fleet.Flags.Endpoint = args["--endpoint"].(string)
+	fleet.Flags.DiscoverySRV = args["--discovery-srv"].(string)
 	fleet.Flags.EtcdKeyPrefix = args["--etcd-key-prefix"].(string)
 	fleet.Flags.EtcdKeyFile = args["--etcd-keyfile"].(string)
 	fleet.Flags.EtcdCertFile = args["--etcd-certfile"].(string)
@@ -222,6 +280,8 @@ func setGlobalFlags(args map[string]interface{}, setTunnel bool) {
 	fleet.Flags.RequestTimeout = timeout
 	sshTimeout, _ := strconv.ParseFloat(args["--ssh-timeout"].(string), 64)
 	fleet.Flags.SSHTimeout = sshTimeout
+	unitTimeout, _ := strconv.ParseFloat(args["--unit-timeout"].(string), 64)
+	fleet.Flags.UnitTimeout = unitTimeout
 	if setTunnel == true {
 		tunnel := args["--tunnel"].(string)
 		if tunnel != "" {
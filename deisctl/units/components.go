@@ -0,0 +1,118 @@
+package units
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ManifestFile declares custom components in a unit directory, next to their unit templates.
+const ManifestFile = "components.yml"
+
+// Component is a custom component declared in a manifest, such as:
+//
+//	components:
+//	- name: metrics
+//	  subsystem: Monitoring
+//	  scalable: true
+//	  instances: 2
+//	  requires: [logspout]
+//	  decorator: MachineMetadata="role=monitoring"
+//
+// Its unit template is deis-<name>.service or deis-<name>@.service, in any unit directory.
+type Component struct {
+	Name string `yaml:"name"`
+	// Subsystem groups the component in the output of platform commands. It defaults to
+	// "Custom components".
+	Subsystem string `yaml:"subsystem"`
+	// Requires are the components which must be started before this one, built in or custom.
+	Requires []string `yaml:"requires"`
+	// Stateful components are left out of the stateless platform.
+	Stateful bool `yaml:"stateful"`
+	// Scalable components run as numbered instances, of which Instances are installed.
+	Scalable  bool `yaml:"scalable"`
+	Instances int  `yaml:"instances"`
+	// Decorator is appended to the unit when placement options are enabled, as the files
+	// of the decorators directory are for built in components.
+	Decorator string `yaml:"decorator"`
+	// Publishes lists the keys the component keeps refreshed in etcd, checked by
+	// `deisctl health`.
+	Publishes []string `yaml:"publishes"`
+}
+
+type manifest struct {
+	Components []Component `yaml:"components"`
+}
+
+var componentNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
+// ParseManifest reads the components declared in a manifest.
+func ParseManifest(data []byte) ([]Component, error) {
+	var m manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", ManifestFile, err)
+	}
+	for i, c := range m.Components {
+		if !componentNameRegexp.MatchString(c.Name) {
+			return nil, fmt.Errorf("%s: invalid component name %q", ManifestFile, c.Name)
+		}
+		for _, name := range Names {
+			if "deis-"+c.Name == name {
+				return nil, fmt.Errorf("%s: %s is a built in component", ManifestFile, c.Name)
+			}
+		}
+		if c.Instances < 0 || c.Instances > 0 && !c.Scalable {
+			return nil, fmt.Errorf("%s: %s declares %d instances but is not scalable", ManifestFile, c.Name, c.Instances)
+		}
+		if c.Subsystem == "" {
+			m.Components[i].Subsystem = "Custom components"
+		}
+	}
+	return m.Components, nil
+}
+
+// Custom returns the custom components declared in the manifests of the unit directories.
+// A component declared in more than one is taken from the first, as templates are.
+func Custom(paths []string) ([]Component, error) {
+	var components []Component
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(path.Join(p, ManifestFile))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		declared, err := ParseManifest(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p, err)
+		}
+		for _, c := range declared {
+			if !seen[c.Name] {
+				seen[c.Name] = true
+				components = append(components, c)
+			}
+		}
+	}
+	return components, nil
+}
+
+// AllNames returns the base names of the Deis units and of the custom components.
+func AllNames(paths []string) ([]string, error) {
+	custom, err := Custom(paths)
+	if err != nil {
+		return nil, err
+	}
+	names := append([]string(nil), Names...)
+	for _, c := range custom {
+		names = append(names, "deis-"+c.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,91 @@
+package units
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestCustom(t *testing.T) {
+	t.Parallel()
+
+	user, err := ioutil.TempDir("", "deisctl-units")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(user)
+	system, err := ioutil.TempDir("", "deisctl-units")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(system)
+
+	files := map[string]string{
+		path.Join(user, ManifestFile): `components:
+- name: metrics
+  scalable: true
+  instances: 2
+  requires: [logspout]
+  decorator: MachineMetadata="role=monitoring"
+`,
+		path.Join(user, "deis-metrics@.service"): "[X-Fleet]\n",
+		path.Join(system, ManifestFile): `components:
+- name: metrics
+- name: backup-agent
+  subsystem: Backups
+  stateful: true
+`,
+	}
+	for file, contents := range files {
+		if err := ioutil.WriteFile(file, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	custom, err := Custom([]string{"", user, system})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first directory declaring a component wins
+	expected := []Component{
+		{Name: "metrics", Subsystem: "Custom components", Scalable: true, Instances: 2,
+			Requires: []string{"logspout"}, Decorator: `MachineMetadata="role=monitoring"`},
+		{Name: "backup-agent", Subsystem: "Backups", Stateful: true},
+	}
+	if !reflect.DeepEqual(custom, expected) {
+		t.Error(fmt.Errorf("Expected %+v, Got %+v", expected, custom))
+	}
+
+	names, err := AllNames([]string{user, system})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != len(Names)+2 || names[len(names)-2] != "deis-metrics" {
+		t.Error(fmt.Errorf("Expected the custom components after %v, Got %v", Names, names))
+	}
+
+	output, err := Render("metrics", []string{user}, true, Variables{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[X-Fleet]\n\nMachineMetadata=\"role=monitoring\""; output != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, output))
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	t.Parallel()
+
+	for manifest, expected := range map[string]string{
+		"components:\n- name: Metrics\n":                 `components.yml: invalid component name "Metrics"`,
+		"components:\n- name: router\n":                  "components.yml: router is a built in component",
+		"components:\n- name: metrics\n  instances: 2\n": "components.yml: metrics declares 2 instances but is not scalable",
+	} {
+		if _, err := ParseManifest([]byte(manifest)); err == nil || err.Error() != expected {
+			t.Error(fmt.Errorf("Expected %v, Got %v", expected, err))
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package units
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/deis/deis/deisctl/config"
+)
+
+// settingsKey is where the values of unit template variables are set, either for every
+// component or for one, as in /deis/units/router/memoryLimit.
+const settingsKey = "/deis/units"
+
+// Variables are the values a unit template can refer to, as in {{.DockerArgs}}.
+type Variables struct {
+	// Component is the name of the component, such as "router".
+	Component string
+	// ImageRegistry prefixes the images of the platform, such as "mirror.local:5000/".
+	ImageRegistry string
+	// ImageTag pins the tag of the images of the platform, instead of the release set
+	// in /deis/platform/version.
+	ImageTag string
+	// MemoryLimit is passed to docker run as --memory, such as "512m".
+	MemoryLimit string
+	// DockerArgs are extra arguments for docker run, preceded by the memory limit.
+	DockerArgs string
+	// MachineMetadata restricts the machines fleet may schedule the component on, such
+	// as "role=control".
+	MachineMetadata string
+}
+
+// LoadVariables reads the variables of a component's unit template from etcd. Values set
+// for the component itself take precedence over those set for every component.
+func LoadVariables(component string, cb config.Backend) (Variables, error) {
+	component = strings.TrimPrefix(component, "deis-")
+	get := func(key string) (string, error) {
+		v, err := cb.GetWithDefault(settingsKey+"/"+key, "")
+		if err != nil {
+			return "", err
+		}
+		return cb.GetWithDefault(settingsKey+"/"+component+"/"+key, v)
+	}
+
+	vars := Variables{Component: component}
+	for key, value := range map[string]*string{
+		"imageRegistry":   &vars.ImageRegistry,
+		"imageTag":        &vars.ImageTag,
+		"memoryLimit":     &vars.MemoryLimit,
+		"dockerArgs":      &vars.DockerArgs,
+		"machineMetadata": &vars.MachineMetadata,
+	} {
+		v, err := get(key)
+		if err != nil {
+			return Variables{}, err
+		}
+		*value = v
+	}
+
+	if vars.ImageRegistry != "" && !strings.HasSuffix(vars.ImageRegistry, "/") {
+		vars.ImageRegistry += "/"
+	}
+	if vars.MemoryLimit != "" {
+		vars.DockerArgs = strings.TrimSpace("--memory=" + vars.MemoryLimit + " " + vars.DockerArgs)
+	}
+	return vars, nil
+}
+
+// Render returns the unit template of a component with its variables filled in. When
+// decorate is set, the component's decorator is appended to the template first, so that
+// it may use the variables too.
+func Render(component string, paths []string, decorate bool, vars Variables) (string, error) {
+	component = strings.TrimPrefix(component, "deis-")
+	filename, err := Template(component, paths)
+	if err != nil {
+		return "", err
+	}
+	text, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	if decorate {
+		decorator, err := readDecorator(component, paths)
+		if err != nil {
+			return "", err
+		}
+		text = append(append(text, '\n'), decorator...)
+	}
+
+	tmpl, err := template.New(path.Base(filename)).Funcs(template.FuncMap{
+		"image": func(name string) string { return image(name, vars) },
+	}).Parse(string(text))
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// image returns a shell word which expands to the image of a component, as used by
+// {{image "router"}}. Without a pinned tag, the image is resolved on the machine by
+// get_image, which honors /deis/<component>/image and the platform version.
+func image(name string, vars Variables) string {
+	if vars.ImageTag != "" {
+		return vars.ImageRegistry + "deis/" + name + ":" + vars.ImageTag
+	}
+	return vars.ImageRegistry + "`/run/deis/bin/get_image /deis/" + name + "`"
+}
+
+// readDecorator returns the contents of a file containing a snippet that can
+// optionally be grafted on to the end of a corresponding systemd unit to
+// achieve isolation of the control plane, data plane, and router mesh. Custom components
+// declare theirs in their manifest instead.
+func readDecorator(component string, paths []string) ([]byte, error) {
+	decoratorName := "deis-" + component + ".service.decorator"
+
+	// look in the decorators directory of each template path
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		filename := path.Join(p, "decorators", decoratorName)
+		if _, err := os.Stat(filename); err == nil {
+			return ioutil.ReadFile(filename)
+		}
+	}
+
+	custom, err := Custom(paths)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range custom {
+		if c.Name == component {
+			return []byte(c.Decorator), nil
+		}
+	}
+	return nil, nil
+}
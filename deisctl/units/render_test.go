@@ -0,0 +1,115 @@
+package units
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/deis/deis/deisctl/config/model"
+	"github.com/deis/deis/deisctl/test/mock"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	name, err := ioutil.TempDir("", "deisctl-units")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(name)
+	expected := "test"
+
+	for _, unit := range Names {
+		ioutil.WriteFile(path.Join(name, unit+".service"), []byte(expected), 0644)
+		output, err := Render(unit[5:], []string{name}, false, Variables{})
+
+		if err != nil {
+			t.Error(err)
+		}
+
+		if output != expected {
+			t.Errorf("Unit %s: Expected %s, Got %s", unit, expected, output)
+		}
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	t.Parallel()
+
+	name, err := ioutil.TempDir("", "deisctl-units")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(name)
+
+	_, err = Render("foo", []string{name}, false, Variables{})
+	expected := "Could not find unit template for foo"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %s, Got %v", expected, err)
+	}
+}
+
+func TestRenderVariables(t *testing.T) {
+	t.Parallel()
+
+	name, err := ioutil.TempDir("", "deisctl-units")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(name)
+	os.Mkdir(path.Join(name, "decorators"), 0755)
+
+	template := `[Service]
+ExecStart=/bin/sh -c "IMAGE={{image "router"}} && docker run --name deis-router {{.DockerArgs}} $IMAGE"
+`
+	decorator := `{{with .MachineMetadata}}MachineMetadata="{{.}}"{{end}}`
+	ioutil.WriteFile(path.Join(name, "deis-router.service"), []byte(template), 0644)
+	ioutil.WriteFile(path.Join(name, "decorators", "deis-router.service.decorator"), []byte(decorator), 0644)
+
+	cb := mock.ConfigBackend{Expected: []*model.ConfigNode{
+		{Key: "/deis/units/imageRegistry", Value: "mirror.local:5000"},
+		{Key: "/deis/units/memoryLimit", Value: "1g"},
+		{Key: "/deis/units/router/memoryLimit", Value: "512m"},
+		{Key: "/deis/units/router/dockerArgs", Value: "--dns=10.0.0.2"},
+		{Key: "/deis/units/machineMetadata", Value: "role=router"},
+	}}
+	vars, err := LoadVariables("deis-router", cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := Render("router", []string{name}, true, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[Service]\n" +
+		"ExecStart=/bin/sh -c \"IMAGE=mirror.local:5000/`/run/deis/bin/get_image /deis/router` && " +
+		"docker run --name deis-router --memory=512m --dns=10.0.0.2 $IMAGE\"\n" +
+		"\nMachineMetadata=\"role=router\""
+	if output != expected {
+		t.Error(fmt.Errorf("Expected %q, Got %q", expected, output))
+	}
+
+	vars.ImageTag = "v1.12.2"
+	if image := image("router", vars); image != "mirror.local:5000/deis/router:v1.12.2" {
+		t.Error(fmt.Errorf("Expected a pinned image, Got %s", image))
+	}
+}
+
+// The templates shipped with deisctl render as they did before they used variables.
+func TestRenderShippedTemplates(t *testing.T) {
+	t.Parallel()
+
+	for _, unit := range Names {
+		output, err := Render(unit, []string{"."}, true, Variables{Component: unit[5:]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(output, "{{") || strings.Contains(output, "<no value>") {
+			t.Errorf("Unit %s was not fully rendered: %s", unit, output)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package units
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// TemplatePaths returns the directories searched for unit templates, in order: the
+// $DEISCTL_UNITS environment variable, $HOME/.deis/units and /var/lib/deis/units.
+func TemplatePaths() []string {
+	return []string{
+		os.Getenv("DEISCTL_UNITS"),
+		path.Join(os.Getenv("HOME"), ".deis", "units"),
+		"/var/lib/deis/units",
+	}
+}
+
+// Template returns the path of the unit template of a component, which is named either
+// deis-<component>@.service for scalable components or deis-<component>.service. The
+// numbered template is preferred where a directory holds both.
+func Template(component string, paths []string) (string, error) {
+	component = strings.TrimPrefix(component, "deis-")
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		for _, name := range []string{"deis-" + component + "@.service", "deis-" + component + ".service"} {
+			filename := path.Join(p, name)
+			if _, err := os.Stat(filename); err == nil {
+				return filename, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Could not find unit template for %v", component)
+}
+
+// Scalable reports whether a component runs as numbered instances, which is the case for
+// any component with a deis-<component>@.service template. The unit files of earlier
+// releases, which upstream still publishes, name the template deis-<component>.service
+// and keep its instances apart with Conflicts=deis-<component>@*.service instead.
+func Scalable(component string, paths []string) (bool, error) {
+	component = strings.TrimPrefix(component, "deis-")
+	filename, err := Template(component, paths)
+	if err != nil {
+		return false, err
+	}
+	if strings.HasSuffix(filename, "@.service") {
+		return true, nil
+	}
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(contents), "Conflicts=deis-"+component+"@*.service"), nil
+}
@@ -0,0 +1,53 @@
+package units
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestScalable(t *testing.T) {
+	t.Parallel()
+
+	name, err := ioutil.TempDir("", "deisctl-units")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(name)
+
+	templates := map[string]string{
+		"deis-controller.service": "[Unit]\nDescription=deis-controller\n",
+		"deis-logspout@.service":  "[Unit]\nDescription=deis-logspout %i\n",
+		// the unit files of earlier releases keep the instances apart instead
+		"deis-router.service": "[X-Fleet]\nConflicts=deis-router@*.service\n",
+		// the numbered template is preferred over one left by an earlier release
+		"deis-registry.service":  "[Unit]\nDescription=deis-registry\n",
+		"deis-registry@.service": "[Unit]\nDescription=deis-registry %i\n",
+	}
+	for file, contents := range templates {
+		if err := ioutil.WriteFile(path.Join(name, file), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for component, expected := range map[string]bool{"controller": false, "logspout": true, "deis-router": true, "registry": true} {
+		scalable, err := Scalable(component, []string{"", name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scalable != expected {
+			t.Error(fmt.Errorf("Expected %s to be scalable: %v, Got %v", component, expected, scalable))
+		}
+	}
+
+	if filename, _ := Template("registry", []string{name}); filename != path.Join(name, "deis-registry@.service") {
+		t.Error(fmt.Errorf("Expected %v, Got %v", path.Join(name, "deis-registry@.service"), filename))
+	}
+
+	expected := "Could not find unit template for builder"
+	if _, err := Scalable("builder", []string{name}); err == nil || err.Error() != expected {
+		t.Error(fmt.Errorf("Expected %v, Got %v", expected, err))
+	}
+}
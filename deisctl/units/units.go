@@ -1,6 +1,7 @@
 package units
 
 // Names are the base names of Deis units. Update this list when adding a new Deis unit file.
+// Components of other projects are declared in a ManifestFile instead, see AllNames.
 var Names = []string{
 	"deis-builder",
 	"deis-controller",
@@ -2,7 +2,6 @@
 package net
 
 import (
-	"errors"
 	"io/ioutil"
 	"net/http"
 )
@@ -10,15 +9,7 @@ import (
 // Download downloads a resource from a specified
 // source (URL) to the specified destination
 func Download(src string, dest string) error {
-	res, err := http.Get(src)
-	if err != nil {
-		return err
-	}
-	if res.StatusCode != 200 {
-		return errors.New(res.Status)
-	}
-	defer res.Body.Close()
-	data, err := ioutil.ReadAll(res.Body)
+	data, err := Get(src)
 	if err != nil {
 		return err
 	}
@@ -27,3 +18,27 @@ func Download(src string, dest string) error {
 	}
 	return nil
 }
+
+// StatusError is returned by Get when a resource is not served with 200 OK
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return e.Status
+}
+
+// Get returns the contents of a resource at a specified source (URL)
+func Get(src string) ([]byte, error) {
+	res, err := http.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: src, StatusCode: res.StatusCode, Status: res.Status}
+	}
+	return ioutil.ReadAll(res.Body)
+}
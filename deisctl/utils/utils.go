@@ -13,3 +13,11 @@ func ResolvePath(path string) string {
 	path = strings.Replace(path, "$HOME", os.Getenv("HOME"), -1)
 	return path
 }
+
+// ShellQuote quotes an argument of a command run by a shell, unless it needs no quotes
+func ShellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.@:/=") == "" {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"os"
 	"testing"
 )
@@ -18,3 +19,21 @@ func TestResolvePath(t *testing.T) {
 		}
 	}
 }
+
+func TestShellQuote(t *testing.T) {
+	t.Parallel()
+
+	for arg, expected := range map[string]string{
+		"journalctl":       "journalctl",
+		"deis-router@1":    "deis-router@1",
+		"":                 "''",
+		"{{.Names}}":       "'{{.Names}}'",
+		"a b; rm -rf /":    "'a b; rm -rf /'",
+		"it's":             `'it'\''s'`,
+		"$(cat /etc/motd)": "'$(cat /etc/motd)'",
+	} {
+		if quoted := ShellQuote(arg); quoted != expected {
+			t.Error(fmt.Errorf("Expected %s, Got %s", expected, quoted))
+		}
+	}
+}
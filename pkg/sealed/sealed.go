@@ -0,0 +1,198 @@
+// Package sealed encrypts config values with an app's public key so that only the app,
+// holding the private key, can read them.
+//
+// A sealed value is a random AES-256-GCM key wrapped with RSA-OAEP (SHA-256), followed by
+// the GCM nonce and ciphertext, base64 encoded and prefixed with Prefix. Apps call
+// OpenEnviron at boot to replace sealed environment variables with their plaintext.
+package sealed
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// Prefix marks a config value as sealed.
+const Prefix = "sealed:v1:"
+
+const keySize = 32
+
+// ErrNotSealed is returned when opening a value which does not carry Prefix.
+var ErrNotSealed = errors.New("value is not sealed")
+
+// IsSealed reports whether a config value is sealed.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Seal encrypts plaintext so that it can only be opened with the private half of pub.
+func Seal(pub *rsa.PublicKey, plaintext []byte) (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	out := append(wrapped, nonce...)
+	out = gcm.Seal(out, nonce, plaintext, nil)
+
+	return Prefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Open decrypts a value produced by Seal.
+func Open(priv *rsa.PrivateKey, value string) ([]byte, error) {
+	if !IsSealed(value) {
+		return nil, ErrNotSealed
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the wrapped key is as long as the modulus, rounded up to whole bytes
+	wrappedSize := (priv.PublicKey.N.BitLen() + 7) / 8
+	if len(data) < wrappedSize {
+		return nil, errors.New("sealed value is truncated")
+	}
+
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, data[:wrappedSize], nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[wrappedSize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("sealed value is truncated")
+	}
+
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// OpenEnviron replaces every sealed environment variable of the current process with
+// its plaintext.
+func OpenEnviron(priv *rsa.PrivateKey) error {
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+
+		if len(parts) != 2 || !IsSealed(parts[1]) {
+			continue
+		}
+
+		plaintext, err := Open(priv, parts[1])
+
+		if err != nil {
+			return fmt.Errorf("opening %s: %v", parts[0], err)
+		}
+
+		if err = os.Setenv(parts[0], string(plaintext)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParsePublicKey parses a PEM encoded RSA public key in either PKIX or PKCS#1 form.
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return nil, errors.New("no PEM data found in public key")
+	}
+
+	// x509 only parses PKCS#1 public keys from Go 1.10
+	var pkcs1 struct {
+		N *big.Int
+		E int
+	}
+	if rest, err := asn1.Unmarshal(block.Bytes, &pkcs1); err == nil && len(rest) == 0 {
+		return &rsa.PublicKey{N: pkcs1.N, E: pkcs1.E}, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// ParsePrivateKey parses a PEM encoded RSA private key in either PKCS#1 or PKCS#8 form.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return nil, errors.New("no PEM data found in private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
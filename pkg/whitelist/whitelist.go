@@ -0,0 +1,135 @@
+// Package whitelist parses and edits the IP whitelists read by the router, such as an
+// app's DEIS_WHITELIST config and /deis/router/controller/whitelist.
+//
+// A whitelist is a comma separated list of IPv4 addresses or CIDR blocks, each with an
+// optional label after a colon, e.g. "10.0.0.0/8:office,192.168.1.1".
+package whitelist
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Entry is a single address or CIDR block in a whitelist.
+type Entry struct {
+	Address string
+	Label   string
+}
+
+func (e Entry) String() string {
+	if e.Label == "" {
+		return e.Address
+	}
+
+	return e.Address + ":" + e.Label
+}
+
+// ParseEntry validates a single "address[:label]" whitelist entry. Addresses are
+// normalized, so that "10.1.2.3/8" becomes "10.0.0.0/8".
+func ParseEntry(s string) (Entry, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 2)
+	entry := Entry{Address: parts[0]}
+
+	if len(parts) == 2 {
+		entry.Label = parts[1]
+
+		if entry.Label == "" || strings.ContainsAny(entry.Label, ":,; {}\"'") {
+			return Entry{}, fmt.Errorf("%s has an invalid label, labels cannot be empty or contain spaces or any of :,;{}\"'", s)
+		}
+	}
+
+	if strings.Contains(entry.Address, "/") {
+		ip, ipNet, err := net.ParseCIDR(entry.Address)
+
+		if err != nil || ip.To4() == nil {
+			return Entry{}, fmt.Errorf("%s is not a valid IPv4 CIDR block", entry.Address)
+		}
+
+		entry.Address = ipNet.String()
+		return entry, nil
+	}
+
+	ip := net.ParseIP(entry.Address)
+
+	if ip == nil || ip.To4() == nil {
+		return Entry{}, fmt.Errorf("%s is not a valid IPv4 address", entry.Address)
+	}
+
+	entry.Address = ip.String()
+	return entry, nil
+}
+
+// Parse validates a comma separated whitelist.
+func Parse(s string) ([]Entry, error) {
+	entries := []Entry{}
+
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		entry, err := ParseEntry(item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Format renders entries as the comma separated value the router expects.
+func Format(entries []Entry) string {
+	items := make([]string, len(entries))
+
+	for i, entry := range entries {
+		items[i] = entry.String()
+	}
+
+	return strings.Join(items, ",")
+}
+
+// Add adds entries to a whitelist. An address which is already present keeps its
+// position and takes the new label, if one is given.
+func Add(entries []Entry, additions ...Entry) []Entry {
+	out := append([]Entry{}, entries...)
+
+outer:
+	for _, addition := range additions {
+		for i := range out {
+			if out[i].Address == addition.Address {
+				if addition.Label != "" {
+					out[i].Label = addition.Label
+				}
+				continue outer
+			}
+		}
+
+		out = append(out, addition)
+	}
+
+	return out
+}
+
+// Remove removes addresses from a whitelist, returning an error if any is absent.
+// Labels are ignored when matching.
+func Remove(entries []Entry, removals ...Entry) ([]Entry, error) {
+	out := append([]Entry{}, entries...)
+
+outer:
+	for _, removal := range removals {
+		for i := range out {
+			if out[i].Address == removal.Address {
+				out = append(out[:i], out[i+1:]...)
+				continue outer
+			}
+		}
+
+		return nil, fmt.Errorf("%s is not whitelisted", removal.Address)
+	}
+
+	return out, nil
+}
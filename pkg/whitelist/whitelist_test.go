@@ -0,0 +1,77 @@
+package whitelist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEntry(t *testing.T) {
+	valid := map[string]Entry{
+		"10.0.0.1":                {Address: "10.0.0.1"},
+		"10.1.2.3/8":              {Address: "10.0.0.0/8"},
+		" 192.168.0.0/16:office ": {Address: "192.168.0.0/16", Label: "office"},
+	}
+
+	for input, expected := range valid {
+		entry, err := ParseEntry(input)
+
+		if err != nil {
+			t.Errorf("Expected '%s' to be valid, got %v", input, err)
+		}
+
+		if entry != expected {
+			t.Errorf("Expected %v, got %v", expected, entry)
+		}
+	}
+
+	invalid := []string{"10.0.0", "10.0.0.256", "10.0.0.0/33", "::1", "example.com", "10.0.0.1:", "10.0.0.1:a;b"}
+
+	for _, input := range invalid {
+		if _, err := ParseEntry(input); err == nil {
+			t.Errorf("Expected '%s' to be invalid", input)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	entries, err := Parse("10.0.0.0/8:vpn,,192.168.1.1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "10.0.0.0/8:vpn,192.168.1.1"
+	if out := Format(entries); out != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, out)
+	}
+
+	if _, err = Parse("10.0.0.0/8,10.0.0.300"); err == nil {
+		t.Error("Expected an invalid whitelist to fail parsing")
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	entries := []Entry{{Address: "10.0.0.0/8", Label: "vpn"}}
+
+	entries = Add(entries, Entry{Address: "192.168.1.1"}, Entry{Address: "10.0.0.0/8"}, Entry{Address: "192.168.1.1", Label: "home"})
+
+	expected := []Entry{{Address: "10.0.0.0/8", Label: "vpn"}, {Address: "192.168.1.1", Label: "home"}}
+	if !reflect.DeepEqual(expected, entries) {
+		t.Errorf("Expected %v, got %v", expected, entries)
+	}
+
+	entries, err := Remove(entries, Entry{Address: "10.0.0.0/8"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []Entry{{Address: "192.168.1.1", Label: "home"}}
+	if !reflect.DeepEqual(expected, entries) {
+		t.Errorf("Expected %v, got %v", expected, entries)
+	}
+
+	if _, err = Remove(entries, Entry{Address: "10.0.0.0/8"}); err == nil {
+		t.Error("Expected removing a missing address to fail")
+	}
+}
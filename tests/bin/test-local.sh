@@ -0,0 +1,71 @@
+#!/usr/bin/env bash
+#
+# Runs `make test-smoke` against a platform installed on this machine with
+# `deisctl --backend=local`, without provisioning a Vagrant cluster. Requires
+# systemd, docker and an etcd listening on 127.0.0.1:4001, and must be run as
+# root so deisctl can install units into /etc/systemd/system.
+#
+
+# fail on any command exiting non-zero
+set -eo pipefail
+
+# absolute path to current directory
+export THIS_DIR=$(cd $(dirname $0); pwd)
+
+# setup the test environment
+source $THIS_DIR/test-setup.sh
+
+DEISCTL_BACKEND_FLAG=--backend=local
+export DEIS_TEST_HOSTS=${DEIS_TEST_HOSTS:-$HOST_IPADDR}
+
+function cleanup_local {
+    if [ "$SKIP_CLEANUP" != true ]; then
+        log_phase "Cleaning up"
+        set +e
+        deisctl $DEISCTL_BACKEND_FLAG uninstall platform
+        log_phase "Test run complete"
+    fi
+}
+
+trap cleanup_local EXIT
+
+log_phase "Building from current source tree"
+
+make build
+
+# use the built client binaries
+export PATH=$DEIS_ROOT/deisctl:$DEIS_ROOT/client/dist:$PATH
+
+log_phase "Preparing this machine"
+
+# the unit templates expect the helpers CoreOS machines are provisioned with
+mkdir -p /run/deis/bin
+cat > /run/deis/bin/get_image <<'SCRIPT'
+#!/usr/bin/env bash
+# usage: get_image <component_path>
+IMAGE=`etcdctl get $1/image 2>/dev/null`
+if [ $? -ne 0 ]; then
+  RELEASE=`etcdctl get /deis/platform/version 2>/dev/null`
+  IMAGE=$1:$RELEASE
+fi
+echo ${IMAGE#/}
+SCRIPT
+chmod 0755 /run/deis/bin/get_image
+grep -q COREOS_PRIVATE_IPV4 /etc/environment 2>/dev/null || \
+    echo "COREOS_PRIVATE_IPV4=$HOST_IPADDR" >> /etc/environment
+
+log_phase "Publishing release from source tree"
+
+make dev-release
+
+log_phase "Provisioning Deis"
+
+deisctl config platform set domain=$DEIS_TEST_DOMAIN
+deisctl config platform set sshPrivateKey=$DEIS_TEST_SSH_KEY
+
+time deisctl $DEISCTL_BACKEND_FLAG install platform
+time deisctl $DEISCTL_BACKEND_FLAG start platform
+
+log_phase "Starting smoke tests"
+
+time make test-smoke